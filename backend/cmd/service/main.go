@@ -2,19 +2,69 @@ package main
 
 import (
 	"context"
-	"log"
+	"errors"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
 
 	// 作成したパッケージをインポート
 	"catchrobo_app/internal/api"
+	"catchrobo_app/internal/audit"
+	"catchrobo_app/internal/auth"
+	"catchrobo_app/internal/config"
+	"catchrobo_app/internal/logging"
 	"catchrobo_app/internal/robot"
 
 	"github.com/tiiuae/rclgo/pkg/rclgo"
 )
 
 func main() {
-	// rclgoを初期化
-	if err := rclgo.Init(nil); err != nil {
-		log.Fatalf("Failed to init rclgo: %v", err)
+	// 設定を読み込む（ファイルが無ければ既定値）
+	cfgPath := os.Getenv("CATCHROBO_CONFIG")
+	if cfgPath == "" {
+		cfgPath = config.DefaultPath
+	}
+	cfg, err := config.Load(cfgPath)
+	if err != nil {
+		fatal("Failed to load config", err)
+	}
+	// 以降のログは JSON（log.Printf も component=main で出る）
+	if err := logging.Setup(cfg.Log.Config, os.Stdout); err != nil {
+		fatal("Failed to set up logging", err)
+	}
+
+	// 認証を有効にしている場合はユーザーファイルが必須（cmd/useradd で作る）
+	var users *auth.Store
+	if cfg.Auth.Enabled {
+		users, err = auth.LoadStore(cfg.Auth.UsersFile, cfg.Auth.SessionTTL())
+		if err != nil {
+			fatal("Failed to load users (create one with `go run ./cmd/useradd -name <user> -role admin`)", err)
+		}
+	} else {
+		slog.Warn("Authentication is disabled; every request is treated as admin")
+	}
+
+	var auditLog *audit.Log
+	if cfg.Audit.File != "" {
+		auditLog, err = audit.Open(cfg.Audit.File)
+		if err != nil {
+			fatal("Failed to open audit log", err)
+		}
+		defer auditLog.Close()
+	}
+
+	// rclgoを初期化（ros_stdout が false なら rcl のロガーは /rosout にだけ出す）
+	var rosArgs *rclgo.Args
+	if !cfg.Log.ROSStdout {
+		rosArgs, _, err = rclgo.ParseArgs([]string{"--ros-args", "--disable-stdout-logs"})
+		if err != nil {
+			fatal("Failed to parse ros args", err)
+		}
+	}
+	if err := rclgo.Init(rosArgs); err != nil {
+		fatal("Failed to init rclgo", err)
 	}
 	defer rclgo.Uninit()
 
@@ -23,18 +73,58 @@ func main() {
 	defer cancel()
 
 	// RobotControllerを初期化
-	robotController, err := robot.NewController(ctx)
+	robotController, err := robot.NewController(ctx, cfg)
 	if err != nil {
-		log.Fatalf("Failed to create robot controller: %v", err)
+		fatal("Failed to create robot controller", err)
 	}
 	defer robotController.Close()
 
-	// ルーターをセットアップ（RobotControllerを渡す）
-	router := api.SetupRouter(robotController)
+	// ルーターをセットアップ（RobotController・設定・ユーザー・監査ログを渡す）
+	router := api.SetupRouter(robotController, cfg, users, auditLog)
 
 	// Webサーバーをポート8080で起動
-	log.Println("Starting server on port 8080...")
-	if err := router.Run(":8080"); err != nil {
-		log.Fatalf("Failed to run server: %v", err)
+	srv := &http.Server{Addr: ":8080", Handler: router}
+	serveErr := make(chan error, 1)
+	go func() {
+		slog.Info("Starting server", "addr", srv.Addr)
+		serveErr <- srv.ListenAndServe()
+	}()
+
+	// SIGINT / SIGTERM を待つ
+	sigCtx, stop := signal.NotifyContext(ctx, syscall.SIGINT, syscall.SIGTERM)
+	select {
+	case <-sigCtx.Done():
+		slog.Info("Signal received, shutting down")
+	case err := <-serveErr:
+		if !errors.Is(err, http.ErrServerClosed) {
+			slog.Error("Server stopped", "error", err)
+		}
+	}
+	// 2回目のシグナルでは即座に終了できるようにする
+	stop()
+
+	// 1. 新しい指令を拒否し、MJPEG・WebSocket・速度ジョグを止める
+	robotController.BeginShutdown()
+
+	// 2. 処理中のリクエストを待つ
+	drainCtx, cancelDrain := context.WithTimeout(context.Background(), cfg.Shutdown.DrainTimeout())
+	if err := srv.Shutdown(drainCtx); err != nil {
+		slog.Warn("Failed to drain requests", "error", err)
+		_ = srv.Close()
 	}
-}
\ No newline at end of file
+	cancelDrain()
+
+	// 3. 駐機姿勢を送る（設定がある場合）
+	if err := robotController.Park(); err != nil {
+		slog.Error("Failed to park", "error", err)
+	}
+
+	// 4. spin を止めて Publisher などを閉じる（defer: RobotController → 監査ログ → rclgo の順）
+	slog.Info("Server stopped")
+}
+
+// fatal はエラーを出力して終了します（log.Fatal と同じく defer は実行されない）
+func fatal(msg string, err error) {
+	slog.Error(msg, "error", err)
+	os.Exit(1)
+}
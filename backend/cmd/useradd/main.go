@@ -0,0 +1,81 @@
+// cmd/useradd/main.go
+package main
+
+// ユーザーファイル（config.yaml の auth.users_file）にユーザーを追加・更新します
+//
+//	go run ./cmd/useradd -name alice -role operator          # パスワードを標準入力から読む
+//	go run ./cmd/useradd -name scoreboard -role viewer -token display  # API トークンを発行して表示する
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"catchrobo_app/internal/auth"
+)
+
+func main() {
+	file := flag.String("file", "data/users.yaml", "users file")
+	name := flag.String("name", "", "user name")
+	role := flag.String("role", string(auth.RoleViewer), "viewer / operator / admin")
+	token := flag.String("token", "", "issue an API token with this name instead of setting a password")
+	flag.Parse()
+
+	if *name == "" {
+		log.Fatal("-name is required")
+	}
+	if !auth.Role(*role).Valid() {
+		log.Fatalf("%v: %q", auth.ErrUnknownRole, *role)
+	}
+
+	users, err := auth.ReadUsers(*file)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		log.Fatal(err)
+	}
+	idx := -1
+	for i, u := range users {
+		if u.Name == *name {
+			idx = i
+		}
+	}
+	if idx < 0 {
+		users = append(users, auth.User{Name: *name})
+		idx = len(users) - 1
+	}
+	u := &users[idx]
+	u.Role = auth.Role(*role)
+
+	var issued string
+	if *token != "" {
+		issued, err = auth.NewToken()
+		if err != nil {
+			log.Fatal(err)
+		}
+		u.Tokens = append(u.Tokens, auth.APIToken{Name: *token, Hash: auth.HashToken(issued)})
+	} else {
+		fmt.Fprint(os.Stderr, "password: ")
+		line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+		if err != nil && line == "" {
+			log.Fatal(err)
+		}
+		password := strings.TrimRight(line, "\r\n")
+		if password == "" {
+			log.Fatal("empty password")
+		}
+		if u.PasswordHash, err = auth.HashPassword(password); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	if err := auth.WriteUsers(*file, users); err != nil {
+		log.Fatal(err)
+	}
+	fmt.Fprintf(os.Stderr, "saved %s (%s) to %s\n", u.Name, u.Role, *file)
+	if issued != "" {
+		// トークンはここでしか表示しない（ファイルにはハッシュだけが残る）
+		fmt.Println(issued)
+	}
+}
@@ -0,0 +1,396 @@
+// internal/analytics/analytics.go
+package analytics
+
+// analyticsはROSにもginにも依存しないように書く（記録は score、HTTP は api パッケージ側）
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"time"
+
+	"catchrobo_app/internal/score"
+)
+
+// ErrInvalidFilter は絞り込みの日付・サイド・種類が正しくない場合に返ります
+var ErrInvalidFilter = errors.New("invalid analytics filter")
+
+// dateLayout は日付だけで絞り込むときの書式です
+const dateLayout = "2006-01-02"
+
+// Filter は集計する記録の絞り込みです（ゼロ値なら絞り込まない）
+type Filter struct {
+	From time.Time `json:"from,omitempty"` // これ以降に始めた記録
+	To   time.Time `json:"to,omitempty"`   // これより前に始めた記録
+	Side string    `json:"side,omitempty"`
+	Kind string    `json:"kind,omitempty"`
+}
+
+// ParseFilter はクエリの値から Filter を作ります
+// from / to は "2006-01-02"（loc の日付。to はその日の終わりまで含む）か RFC3339
+func ParseFilter(from, to, side, kind string, loc *time.Location) (Filter, error) {
+	var f Filter
+	var err error
+	if from != "" {
+		if f.From, err = parseTime(from, loc, false); err != nil {
+			return Filter{}, err
+		}
+	}
+	if to != "" {
+		if f.To, err = parseTime(to, loc, true); err != nil {
+			return Filter{}, err
+		}
+	}
+	if side != "" && side != score.SideBlue && side != score.SideRed {
+		return Filter{}, fmt.Errorf("%w: side must be %s or %s", ErrInvalidFilter, score.SideBlue, score.SideRed)
+	}
+	if kind != "" && kind != score.KindMatch && kind != score.KindPractice {
+		return Filter{}, fmt.Errorf("%w: kind must be %s or %s", ErrInvalidFilter, score.KindMatch, score.KindPractice)
+	}
+	f.Side, f.Kind = side, kind
+	return f, nil
+}
+
+func parseTime(s string, loc *time.Location, endOfDay bool) (time.Time, error) {
+	if t, err := time.ParseInLocation(dateLayout, s, loc); err == nil {
+		if endOfDay {
+			t = t.AddDate(0, 0, 1)
+		}
+		return t, nil
+	}
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%w: %q is not a date (2006-01-02) or RFC3339 time", ErrInvalidFilter, s)
+	}
+	return t, nil
+}
+
+// Match は記録が絞り込みに当てはまるかを返します
+func (f Filter) Match(rec score.Record) bool {
+	switch {
+	case !f.From.IsZero() && rec.Start.Before(f.From):
+		return false
+	case !f.To.IsZero() && !rec.Start.Before(f.To):
+		return false
+	case f.Side != "" && rec.Side != f.Side:
+		return false
+	case f.Kind != "" && rec.Kind != f.Kind:
+		return false
+	}
+	return true
+}
+
+// Apply は絞り込みに当てはまる記録を返します
+func (f Filter) Apply(recs []score.Record) []score.Record {
+	out := make([]score.Record, 0, len(recs))
+	for _, rec := range recs {
+		if f.Match(rec) {
+			out = append(out, rec)
+		}
+	}
+	return out
+}
+
+// Stats は時間の集計です（秒）
+type Stats struct {
+	Count int     `json:"count"`
+	Mean  float64 `json:"mean_s"`
+	Min   float64 `json:"min_s"`
+	P50   float64 `json:"p50_s"`
+	P90   float64 `json:"p90_s"`
+	P95   float64 `json:"p95_s"`
+	Max   float64 `json:"max_s"`
+}
+
+// NewStats は秒の並びを集計します（並びは書き換えない）
+func NewStats(values []float64) Stats {
+	if len(values) == 0 {
+		return Stats{}
+	}
+	v := slices.Clone(values)
+	slices.Sort(v)
+	sum := 0.0
+	for _, x := range v {
+		sum += x
+	}
+	return Stats{
+		Count: len(v),
+		Mean:  sum / float64(len(v)),
+		Min:   v[0],
+		P50:   percentile(v, 50),
+		P90:   percentile(v, 90),
+		P95:   percentile(v, 95),
+		Max:   v[len(v)-1],
+	}
+}
+
+// percentile は並べ替え済みの v の p パーセンタイルを線形補間で返します
+func percentile(sorted []float64, p float64) float64 {
+	if len(sorted) == 1 {
+		return sorted[0]
+	}
+	rank := p / 100 * float64(len(sorted)-1)
+	lo := int(math.Floor(rank))
+	hi := int(math.Ceil(rank))
+	return sorted[lo] + (sorted[hi]-sorted[lo])*(rank-float64(lo))
+}
+
+// Retries はやり直しの集計です
+// やり直しは同じ記録の中で失敗したセルをもう一度取りに行った試技。失った時間はやり直す前の失敗した試技の時間
+type Retries struct {
+	Count    int     `json:"count"`
+	TimeLost float64 `json:"time_lost_s"`
+}
+
+// Rate は成功率の集計です
+type Rate struct {
+	Attempts    int     `json:"attempts"` // 成否の付いた試技
+	Successes   int     `json:"successes"`
+	SuccessRate float64 `json:"success_rate"` // 0〜1（試技が無ければ0）
+}
+
+func (r *Rate) add(a score.Attempt) {
+	r.Attempts++
+	if a.Succeeded() {
+		r.Successes++
+	}
+	r.SuccessRate = float64(r.Successes) / float64(r.Attempts)
+}
+
+// Summary は絞り込んだ記録全体の集計です
+type Summary struct {
+	Filter      Filter          `json:"filter"`
+	Sessions    int             `json:"sessions"`
+	Rate                        // 全体の成功率
+	CycleTime   Stats           `json:"cycle_time"`   // 成功した試技（1個取って置くまで）の時間
+	AttemptTime Stats           `json:"attempt_time"` // 成否の付いた全ての試技の時間（時間が分からないものは除く）
+	Retries     Retries         `json:"retries"`
+	Sides       map[string]Rate `json:"sides"`
+	Slots       map[string]Rate `json:"slots"` // 置き場所ごと（空は "-"）
+	Workpieces  map[string]Rate `json:"workpieces,omitempty"`
+	ScoreTotal  int             `json:"score_total"`
+}
+
+// Cell はフィールドのセル・サイドごとの集計です
+type Cell struct {
+	Side      string  `json:"side"`
+	Cell      int     `json:"cell"`
+	Rate              // このセルの成功率
+	CycleTime Stats   `json:"cycle_time"`
+	Retries   Retries `json:"retries"`
+}
+
+// Session は記録1つごとの集計です（練習を重ねた変化を見るため、古い順に並べる）
+type Session struct {
+	ID        string    `json:"id"`
+	Kind      string    `json:"kind"`
+	Side      string    `json:"side"`
+	Start     time.Time `json:"start"`
+	Score     int       `json:"score"`
+	Rate                // この記録の成功率
+	CycleTime Stats     `json:"cycle_time"`
+	Retries   Retries   `json:"retries"`
+}
+
+// finished は成否の付いた試技を順に渡します
+// retry はそのセルの前の試技が失敗していた（やり直しの）試技、retried は後で同じセルをやり直した失敗の試技のときに true
+func finished(rec score.Record, fn func(a score.Attempt, retry, retried bool)) {
+	// 先に、後でやり直した失敗を調べる
+	lastFailed := map[int]int{} // セル → そのセルを最後に失敗した試技の添字
+	retried := map[int]bool{}
+	for i, a := range rec.Attempts {
+		if a.Success == nil {
+			continue
+		}
+		if j, ok := lastFailed[a.Cell]; ok {
+			retried[j] = true
+		}
+		if a.Succeeded() {
+			delete(lastFailed, a.Cell)
+		} else {
+			lastFailed[a.Cell] = i
+		}
+	}
+	failed := map[int]bool{}
+	for i, a := range rec.Attempts {
+		if a.Success == nil {
+			continue
+		}
+		fn(a, failed[a.Cell], retried[i])
+		failed[a.Cell] = !a.Succeeded()
+	}
+}
+
+// Summarize は記録全体を集計します（recs は絞り込み済み）
+func Summarize(f Filter, recs []score.Record) Summary {
+	s := Summary{
+		Filter:     f,
+		Sessions:   len(recs),
+		Sides:      map[string]Rate{},
+		Slots:      map[string]Rate{},
+		Workpieces: map[string]Rate{},
+	}
+	var cycles, attempts []float64
+	for _, rec := range recs {
+		s.ScoreTotal += rec.Score
+		finished(rec, func(a score.Attempt, retry, retried bool) {
+			s.Rate.add(a)
+			addRate(s.Sides, rec.Side, a)
+			slot := a.Slot
+			if slot == "" {
+				slot = "-"
+			}
+			addRate(s.Slots, slot, a)
+			if a.Workpiece != "" {
+				addRate(s.Workpieces, a.Workpiece, a)
+			}
+			if retry {
+				s.Retries.Count++
+			}
+			d, ok := measured(a)
+			if !ok {
+				return
+			}
+			attempts = append(attempts, d)
+			if a.Succeeded() {
+				cycles = append(cycles, d)
+			}
+			if retried {
+				s.Retries.TimeLost += d
+			}
+		})
+	}
+	s.CycleTime = NewStats(cycles)
+	s.AttemptTime = NewStats(attempts)
+	return s
+}
+
+// measured は試技にかかった時間 [s] を返します
+// 終わった時刻が分からない試技（成否だけ後から記録したもの。開始と同じ時刻のものも）は ok が false で、時間の集計に入れない
+func measured(a score.Attempt) (float64, bool) {
+	if a.End == nil || !a.End.After(a.Start) {
+		return 0, false
+	}
+	return a.Duration().Seconds(), true
+}
+
+func addRate(m map[string]Rate, key string, a score.Attempt) {
+	r := m[key]
+	r.add(a)
+	m[key] = r
+}
+
+// Cells はフィールドのセル・サイドごとに集計します（サイド・セル番号の順）
+func Cells(recs []score.Record) []Cell {
+	type key struct {
+		side string
+		cell int
+	}
+	cells := map[key]*Cell{}
+	cycles := map[key][]float64{}
+	for _, rec := range recs {
+		finished(rec, func(a score.Attempt, retry, retried bool) {
+			k := key{rec.Side, a.Cell}
+			c := cells[k]
+			if c == nil {
+				c = &Cell{Side: rec.Side, Cell: a.Cell}
+				cells[k] = c
+			}
+			c.Rate.add(a)
+			if retry {
+				c.Retries.Count++
+			}
+			d, ok := measured(a)
+			if !ok {
+				return
+			}
+			if a.Succeeded() {
+				cycles[k] = append(cycles[k], d)
+			}
+			if retried {
+				c.Retries.TimeLost += d
+			}
+		})
+	}
+	out := make([]Cell, 0, len(cells))
+	for k, c := range cells {
+		c.CycleTime = NewStats(cycles[k])
+		out = append(out, *c)
+	}
+	slices.SortFunc(out, func(a, b Cell) int {
+		if a.Side != b.Side {
+			return cmp.Compare(a.Side, b.Side)
+		}
+		return a.Cell - b.Cell
+	})
+	return out
+}
+
+// Sessions は記録1つごとに集計します（古い順）
+func Sessions(recs []score.Record) []Session {
+	out := make([]Session, 0, len(recs))
+	for _, rec := range recs {
+		s := Session{ID: rec.ID, Kind: rec.Kind, Side: rec.Side, Start: rec.Start, Score: rec.Score}
+		var cycles []float64
+		finished(rec, func(a score.Attempt, retry, retried bool) {
+			s.Rate.add(a)
+			if retry {
+				s.Retries.Count++
+			}
+			d, ok := measured(a)
+			if !ok {
+				return
+			}
+			if a.Succeeded() {
+				cycles = append(cycles, d)
+			}
+			if retried {
+				s.Retries.TimeLost += d
+			}
+		})
+		s.CycleTime = NewStats(cycles)
+		out = append(out, s)
+	}
+	slices.SortFunc(out, func(a, b Session) int { return a.Start.Compare(b.Start) })
+	return out
+}
+
+// Trend は記録を重ねるごとの変化です（記録1つあたりの傾き。試技の無い記録は除く）
+type Trend struct {
+	Sessions         int     `json:"sessions"`
+	SuccessRateSlope float64 `json:"success_rate_slope"` // 正なら成功率が上がっている
+	CycleTimeSlope   float64 `json:"cycle_time_slope_s"` // 負なら速くなっている（成功の無い記録は除く）
+}
+
+// NewTrend は古い順の sessions から変化の傾きを最小二乗法で求めます
+func NewTrend(sessions []Session) Trend {
+	var rates, cycles []float64
+	for _, s := range sessions {
+		if s.Attempts == 0 {
+			continue
+		}
+		rates = append(rates, s.SuccessRate)
+		if s.CycleTime.Count > 0 {
+			cycles = append(cycles, s.CycleTime.Mean)
+		}
+	}
+	return Trend{Sessions: len(rates), SuccessRateSlope: slope(rates), CycleTimeSlope: slope(cycles)}
+}
+
+// slope は添字を x とした y の回帰直線の傾きです（2点未満なら0）
+func slope(y []float64) float64 {
+	n := float64(len(y))
+	if n < 2 {
+		return 0
+	}
+	var sx, sy, sxx, sxy float64
+	for i, v := range y {
+		x := float64(i)
+		sx += x
+		sy += v
+		sxx += x * x
+		sxy += x * v
+	}
+	return (n*sxy - sx*sy) / (n*sxx - sx*sx)
+}
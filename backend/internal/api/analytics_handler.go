@@ -0,0 +1,51 @@
+// internal/api/analytics_handler.go
+package api
+
+import (
+	"net/http"
+	"time"
+
+	"catchrobo_app/internal/analytics"
+	"catchrobo_app/internal/score"
+
+	"github.com/gin-gonic/gin"
+)
+
+// filteredRecords は ?from=&to=&side=&kind= で絞り込んだ記録を返します。絞り込みが正しくなければ400を返して false
+// from / to は日付（2006-01-02、to はその日を含む）か RFC3339
+func (h *RobotHandler) filteredRecords(c *gin.Context) (analytics.Filter, []score.Record, bool) {
+	f, err := analytics.ParseFilter(c.Query("from"), c.Query("to"), c.Query("side"), c.Query("kind"), time.Local)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid filter", "detail": err.Error()})
+		return analytics.Filter{}, nil, false
+	}
+	return f, f.Apply(h.controller.Scores().Records()), true
+}
+
+// GetAnalytics は試合・練習の記録全体の成功率・1個あたりの時間（平均とパーセンタイル）・やり直しで失った時間を返します
+func (h *RobotHandler) GetAnalytics(c *gin.Context) {
+	f, recs, ok := h.filteredRecords(c)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, analytics.Summarize(f, recs))
+}
+
+// GetCellAnalytics はフィールドのセル・サイドごとの成功率・時間・やり直しを返します（手直しの要るセルを探すため）
+func (h *RobotHandler) GetCellAnalytics(c *gin.Context) {
+	f, recs, ok := h.filteredRecords(c)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"filter": f, "cells": analytics.Cells(recs)})
+}
+
+// GetSessionAnalytics は記録1つごとの集計（古い順）と、記録を重ねるごとの変化を返します
+func (h *RobotHandler) GetSessionAnalytics(c *gin.Context) {
+	f, recs, ok := h.filteredRecords(c)
+	if !ok {
+		return
+	}
+	sessions := analytics.Sessions(recs)
+	c.JSON(http.StatusOK, gin.H{"filter": f, "sessions": sessions, "trend": analytics.NewTrend(sessions)})
+}
@@ -0,0 +1,213 @@
+// internal/api/auth_handler.go
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"catchrobo_app/internal/audit"
+	"catchrobo_app/internal/auth"
+	"catchrobo_app/internal/logging"
+
+	"github.com/gin-gonic/gin"
+)
+
+// identityKey は認証されたリクエスト元を gin.Context に入れるキーです
+const identityKey = "identity"
+
+// anonymousAdmin は認証を無効にしている場合のリクエスト元です（従来どおり誰でも全て使える）
+var anonymousAdmin = auth.Identity{User: "anonymous", Role: auth.RoleAdmin, Method: auth.MethodAnonymous}
+
+// bearerToken は Authorization: Bearer ヘッダ、無ければ ?access_token= からトークンを取り出します
+// <img> の MJPEG や WebSocket はヘッダを付けられないのでクエリでも受け付ける
+func bearerToken(c *gin.Context) string {
+	if h := c.GetHeader("Authorization"); strings.HasPrefix(h, "Bearer ") {
+		return strings.TrimSpace(strings.TrimPrefix(h, "Bearer "))
+	}
+	return c.Query("access_token")
+}
+
+// identityOf は authenticate が入れたリクエスト元を返します
+func identityOf(c *gin.Context) (auth.Identity, bool) {
+	v, ok := c.Get(identityKey)
+	if !ok {
+		return auth.Identity{}, false
+	}
+	id, ok := v.(auth.Identity)
+	return id, ok
+}
+
+// authenticate はトークンからリクエスト元を特定します。users が nil なら認証を無効にする
+func authenticate(users *auth.Store) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if users == nil {
+			c.Set(identityKey, anonymousAdmin)
+			c.Next()
+			return
+		}
+		id, err := users.Authenticate(bearerToken(c))
+		if err != nil {
+			c.Header("WWW-Authenticate", `Bearer realm="catchrobo"`)
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "authentication required", "detail": err.Error()})
+			return
+		}
+		c.Set(identityKey, id)
+		c.Next()
+	}
+}
+
+// requireRole は min 未満のロールを 403 で拒否します
+func requireRole(min auth.Role) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, _ := identityOf(c)
+		if !id.Role.Allows(min) {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+				"error":  "insufficient role",
+				"detail": fmt.Sprintf("%s requires %s (you are %s)", c.FullPath(), min, id.Role),
+			})
+			return
+		}
+		c.Next()
+	}
+}
+
+// auditMiddleware は状態を変えるリクエスト（GET / HEAD 以外と WebSocket の接続）を監査ログに残します
+func auditMiddleware(log *audit.Log) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+		if log == nil {
+			return
+		}
+		m := c.Request.Method
+		if (m == http.MethodGet || m == http.MethodHead) && !strings.HasSuffix(c.FullPath(), "/ws") {
+			return
+		}
+		action := c.GetString(auditActionKey)
+		if action == "" {
+			action = "request"
+		}
+		e := requestEntry(c, action)
+		e.Status = c.Writer.Status()
+		e.Detail = c.GetString(auditDetailKey)
+		if len(c.Errors) > 0 {
+			e.Detail = strings.TrimSpace(e.Detail + " " + c.Errors.String())
+		}
+		_ = log.Record(e)
+	}
+}
+
+// 監査ログの action と detail をハンドラから指定するときの gin.Context のキー
+const (
+	auditActionKey = "audit_action"
+	auditDetailKey = "audit_detail"
+)
+
+// setAudit はこのリクエストの監査ログの action と detail を指定します（何を変えたかを残したいハンドラから呼ぶ）
+func setAudit(c *gin.Context, action, detail string) {
+	c.Set(auditActionKey, action)
+	c.Set(auditDetailKey, detail)
+}
+
+// requestEntry はリクエスト元の情報を入れた監査ログの1行を作ります
+func requestEntry(c *gin.Context, action string) audit.Entry {
+	id, _ := identityOf(c)
+	return audit.Entry{
+		User:      id.User,
+		Role:      string(id.Role),
+		Client:    clientID(c),
+		RemoteIP:  c.ClientIP(),
+		Method:    c.Request.Method,
+		Path:      c.Request.URL.Path,
+		Action:    action,
+		RequestID: logging.RequestID(c.Request.Context()),
+	}
+}
+
+// AuthHandler はログイン・ログアウトを扱います
+type AuthHandler struct {
+	users *auth.Store
+	audit *audit.Log
+}
+
+func NewAuthHandler(users *auth.Store, log *audit.Log) *AuthHandler {
+	return &AuthHandler{users: users, audit: log}
+}
+
+type LoginReq struct {
+	Username string `json:"username" binding:"required"`
+	Password string `json:"password" binding:"required"`
+}
+
+// Login はユーザー名とパスワードを確認してセッショントークンを返します
+func (h *AuthHandler) Login(c *gin.Context) {
+	if h.users == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "authentication is disabled"})
+		return
+	}
+	var req LoginReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid login json", "detail": err.Error()})
+		return
+	}
+	token, id, expires, err := h.users.Login(req.Username, req.Password)
+	e := requestEntry(c, "login")
+	e.User = req.Username
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, auth.ErrInvalidCredentials) {
+			status = http.StatusUnauthorized
+		}
+		e.Status, e.Detail = status, err.Error()
+		_ = h.audit.Record(e)
+		c.JSON(status, gin.H{"error": "login failed", "detail": err.Error()})
+		return
+	}
+	e.Role, e.Status = string(id.Role), http.StatusOK
+	_ = h.audit.Record(e)
+	c.JSON(http.StatusOK, gin.H{"token": token, "expires_at": expires, "identity": id})
+}
+
+// Logout はセッションを無効にします（API トークンは無効にならない）
+func (h *AuthHandler) Logout(c *gin.Context) {
+	if h.users != nil {
+		h.users.Logout(bearerToken(c))
+	}
+	c.JSON(http.StatusOK, gin.H{"ok": true})
+}
+
+// Me は自分のユーザー名とロールを返します
+func (h *AuthHandler) Me(c *gin.Context) {
+	id, _ := identityOf(c)
+	c.JSON(http.StatusOK, gin.H{"identity": id, "auth_enabled": h.users != nil})
+}
+
+// GetUsers はユーザー一覧（ハッシュを除く）を返します
+func (h *AuthHandler) GetUsers(c *gin.Context) {
+	if h.users == nil {
+		c.JSON(http.StatusOK, gin.H{"auth_enabled": false, "users": []auth.User{}})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"auth_enabled": true, "users": h.users.Users()})
+}
+
+// GetAudit は監査ログを新しい順に返します（?limit= 既定100）
+func (h *AuthHandler) GetAudit(c *gin.Context) {
+	limit := 100
+	if s := c.Query("limit"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid limit", "detail": s})
+			return
+		}
+		limit = n
+	}
+	entries, err := h.audit.Tail(limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "read audit log failed", "detail": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"entries": entries})
+}
@@ -0,0 +1,149 @@
+// internal/api/control_handler.go
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	"catchrobo_app/internal/auth"
+	"catchrobo_app/internal/control"
+	"catchrobo_app/internal/robot"
+
+	"github.com/gin-gonic/gin"
+)
+
+// clientIDHeader はブラウザのタブごとに UI が付けるクライアントIDです（認証を無効にしている場合だけ使う）
+// WebSocket のようにヘッダを付けられない場合は ?client_id= で渡す。どちらも無ければIPアドレスで区別する
+const clientIDHeader = "X-Client-Id"
+
+// clientID はリクエスト元のクライアントを識別します
+// 認証を有効にしていればログインユーザーに結び付け、クライアントが付けるヘッダやクエリは見ない
+// （他のオペレーターが持ち主のIDを真似て指令を送れないように）
+func clientID(c *gin.Context) string {
+	if id, ok := identityOf(c); ok && id.Method != auth.MethodAnonymous {
+		return "user:" + id.User
+	}
+	if id := c.GetHeader(clientIDHeader); id != "" {
+		return id
+	}
+	if id := c.Query("client_id"); id != "" {
+		return id
+	}
+	return "ip:" + c.ClientIP()
+}
+
+// controlTokenHeader は POST /api/control/take で受け取ったトークンを指令に付けるヘッダです
+// WebSocket のようにヘッダを付けられない場合は ?control_token= で渡す
+const controlTokenHeader = "X-Control-Token"
+
+// controlToken はリクエストに付いている操作権のトークンを返します
+func controlToken(c *gin.Context) string {
+	if t := c.GetHeader(controlTokenHeader); t != "" {
+		return t
+	}
+	return c.Query("control_token")
+}
+
+// forbidForceUnlessAdmin は force 指定を admin だけに許します
+func forbidForceUnlessAdmin(c *gin.Context, req ControlReq) bool {
+	if !req.Force {
+		return false
+	}
+	if id, _ := identityOf(c); id.Role.Allows(auth.RoleAdmin) {
+		return false
+	}
+	c.JSON(http.StatusForbidden, gin.H{"error": "insufficient role", "detail": "force requires admin"})
+	return true
+}
+
+// rejectWhenShuttingDown は停止処理に入った後の指令を 503 で拒否します
+func rejectWhenShuttingDown(rc *robot.RobotController) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		select {
+		case <-rc.ShuttingDown():
+			c.AbortWithStatusJSON(http.StatusServiceUnavailable, gin.H{"error": "server is shutting down", "detail": robot.ErrShuttingDown.Error()})
+			return
+		default:
+		}
+		c.Next()
+	}
+}
+
+// requireControl は操作権を持つクライアント（take で受け取ったトークン付き）以外からの指令を 423 で拒否します
+func requireControl(lock *control.Lock) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if st, err := lock.Use(clientID(c), controlToken(c)); err != nil {
+			c.AbortWithStatusJSON(http.StatusLocked, gin.H{"error": "control required", "detail": err.Error(), "control": st})
+			return
+		}
+		c.Next()
+	}
+}
+
+// ControlHandler は操作権の取得・解放を扱います
+type ControlHandler struct {
+	lock *control.Lock
+}
+
+func NewControlHandler(lock *control.Lock) *ControlHandler {
+	return &ControlHandler{lock: lock}
+}
+
+// ControlReq は take / release の入力です（どちらも省略可）
+type ControlReq struct {
+	Name  string `json:"name"`  // 表示用の名前
+	Force bool   `json:"force"` // 他のクライアントから奪う / 持ち主でなくても解放する（admin のみ）
+}
+
+// bindControlReq はボディが空でも受け付けます
+func bindControlReq(c *gin.Context) (ControlReq, bool) {
+	var req ControlReq
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid control json", "detail": err.Error()})
+			return req, false
+		}
+	}
+	return req, true
+}
+
+// GetControl は操作権の持ち主と自動解放の時刻を返します（トークンは返さない）
+func (h *ControlHandler) GetControl(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"control": h.lock.Status(), "client_id": clientID(c), "you": h.lock.Holds(clientID(c), controlToken(c))})
+}
+
+// TakeControl は操作権を取り、指令に付けるトークンを返します。他のクライアントが持っていれば force の場合のみ奪います
+func (h *ControlHandler) TakeControl(c *gin.Context) {
+	req, ok := bindControlReq(c)
+	if !ok || forbidForceUnlessAdmin(c, req) {
+		return
+	}
+	st, token, err := h.lock.Take(clientID(c), req.Name, req.Force)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, control.ErrHeld) {
+			status = http.StatusConflict
+		}
+		c.JSON(status, gin.H{"error": "take control failed", "detail": err.Error(), "control": st})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"ok": true, "control": st, "token": token})
+}
+
+// ReleaseControl は操作権を手放します
+func (h *ControlHandler) ReleaseControl(c *gin.Context) {
+	req, ok := bindControlReq(c)
+	if !ok || forbidForceUnlessAdmin(c, req) {
+		return
+	}
+	st, err := h.lock.Release(clientID(c), controlToken(c), req.Force)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, control.ErrNotHolder) {
+			status = http.StatusConflict
+		}
+		c.JSON(status, gin.H{"error": "release control failed", "detail": err.Error(), "control": st})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"ok": true, "control": st})
+}
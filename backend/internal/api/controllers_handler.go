@@ -0,0 +1,116 @@
+// internal/api/controllers_handler.go
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"catchrobo_app/internal/controllers"
+
+	"github.com/gin-gonic/gin"
+)
+
+// LoadControllerReq は POST /api/controllers/load の本文です
+type LoadControllerReq struct {
+	Name      string `json:"name" binding:"required"`
+	Configure bool   `json:"configure"` // 読み込んだ後に configure して inactive にする
+}
+
+// respondControllerError は controller_manager まわりのエラーを返します
+func respondControllerError(c *gin.Context, msg string, err error) {
+	switch {
+	case errors.Is(err, controllers.ErrInvalidSwitch):
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg, "detail": err.Error()})
+	case errors.Is(err, controllers.ErrRejected):
+		c.JSON(http.StatusConflict, gin.H{"error": msg, "detail": err.Error()})
+	default:
+		respondServiceError(c, msg, err)
+	}
+}
+
+// respondControllers は変更後のコントローラー一覧を返します（取得に失敗しても変更は成功として返す）
+func (h *RobotHandler) respondControllers(c *gin.Context) {
+	res := gin.H{"controller_manager": h.controller.ControllerManager()}
+	if list, err := h.controller.ListControllers(c.Request.Context()); err == nil {
+		res["controllers"] = list
+	} else {
+		res["detail"] = err.Error()
+	}
+	c.JSON(http.StatusOK, res)
+}
+
+// GetControllers はコントローラーと状態・確保しているインターフェースを返します
+func (h *RobotHandler) GetControllers(c *gin.Context) {
+	list, err := h.controller.ListControllers(c.Request.Context())
+	if err != nil {
+		respondControllerError(c, "list controllers failed", err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"controller_manager": h.controller.ControllerManager(), "controllers": list})
+}
+
+// GetHardwareComponents はハードウェアコンポーネントと状態・インターフェースを返します
+func (h *RobotHandler) GetHardwareComponents(c *gin.Context) {
+	comps, err := h.controller.ListHardwareComponents(c.Request.Context())
+	if err != nil {
+		respondControllerError(c, "list hardware components failed", err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"controller_manager": h.controller.ControllerManager(), "components": comps})
+}
+
+// LoadController はコントローラーを読み込みます（configure: true なら inactive まで進める）
+func (h *RobotHandler) LoadController(c *gin.Context) {
+	var req LoadControllerReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid load json", "detail": err.Error()})
+		return
+	}
+	setAudit(c, "load_controller", fmt.Sprintf("controller=%s configure=%t", req.Name, req.Configure))
+	ctx := c.Request.Context()
+	if err := h.controller.LoadController(ctx, req.Name); err != nil {
+		respondControllerError(c, "load controller failed", err)
+		return
+	}
+	if req.Configure {
+		if err := h.controller.ConfigureController(ctx, req.Name); err != nil {
+			respondControllerError(c, "configure controller failed", err)
+			return
+		}
+	}
+	h.respondControllers(c)
+}
+
+// ConfigureController は読み込んだコントローラーを configure します
+func (h *RobotHandler) ConfigureController(c *gin.Context) {
+	name := c.Param("name")
+	setAudit(c, "configure_controller", "controller="+name)
+	if err := h.controller.ConfigureController(c.Request.Context(), name); err != nil {
+		respondControllerError(c, "configure controller failed", err)
+		return
+	}
+	h.respondControllers(c)
+}
+
+// SwitchControllers はコントローラーを切り替えます（位置制御と軌道制御の切り替えなど）
+// strictness が strict なら1つでも切り替えられなければ何も変えずに409
+func (h *RobotHandler) SwitchControllers(c *gin.Context) {
+	var sw controllers.Switch
+	if err := c.ShouldBindJSON(&sw); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid switch json", "detail": err.Error()})
+		return
+	}
+	if err := sw.Normalize(); err != nil {
+		respondControllerError(c, "invalid switch", err)
+		return
+	}
+	setAudit(c, "switch_controllers", fmt.Sprintf("activate=%s deactivate=%s strictness=%s",
+		strings.Join(sw.Activate, ","), strings.Join(sw.Deactivate, ","), sw.Strictness))
+	if err := h.controller.SwitchControllers(c.Request.Context(), sw); err != nil {
+		respondControllerError(c, "switch controllers failed", err)
+		return
+	}
+	h.respondControllers(c)
+}
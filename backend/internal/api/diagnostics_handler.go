@@ -0,0 +1,77 @@
+// internal/api/diagnostics_handler.go
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetDiagnostics は hardware_id ごとにまとめた診断と最も悪いレベルを返します
+// ?since= を付けるとその番号より後のレベル変化（changes）も返す
+func (h *RobotHandler) GetDiagnostics(c *gin.Context) {
+	diag := h.controller.Diagnostics()
+	res := gin.H{"tree": diag.Tree()}
+	if s := c.Query("since"); s != "" {
+		since, err := strconv.ParseUint(s, 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "since must be a sequence number", "detail": err.Error()})
+			return
+		}
+		res["changes"] = diag.Changes(since)
+	}
+	c.JSON(http.StatusOK, res)
+}
+
+// StreamDiagnostics は接続時に全体（event: snapshot）を送り、以降はレベルの変化（event: change、id: seq）を送り続けます
+// 再接続で Last-Event-ID が付いていれば、その間の変化も送る
+func (h *RobotHandler) StreamDiagnostics(c *gin.Context) {
+	diag := h.controller.Diagnostics()
+	var since uint64
+	if s := c.GetHeader("Last-Event-ID"); s != "" {
+		since, _ = strconv.ParseUint(s, 10, 64)
+	}
+
+	live, cancel := diag.Subscribe()
+	defer cancel()
+
+	flusher, ok := startSSE(c)
+	if !ok {
+		return
+	}
+	if writeSSE(c, flusher, "snapshot", "", diag.Tree()) != nil {
+		return
+	}
+	if since > 0 {
+		for _, ch := range diag.Changes(since) {
+			if writeSSE(c, flusher, "change", strconv.FormatUint(ch.Seq, 10), ch) != nil {
+				return
+			}
+			since = ch.Seq
+		}
+	}
+
+	heartbeat := time.NewTicker(sseHeartbeat)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-c.Request.Context().Done():
+			return
+		case <-h.controller.ShuttingDown():
+			return
+		case <-heartbeat.C:
+			if writeSSEHeartbeat(c, flusher) != nil {
+				return
+			}
+		case ch := <-live:
+			if ch.Seq <= since {
+				continue
+			}
+			if writeSSE(c, flusher, "change", strconv.FormatUint(ch.Seq, 10), ch) != nil {
+				return
+			}
+		}
+	}
+}
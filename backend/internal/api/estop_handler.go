@@ -0,0 +1,44 @@
+// internal/api/estop_handler.go
+package api
+
+import (
+	"errors"
+	"io"
+	"net/http"
+
+	"catchrobo_app/internal/robot"
+
+	"github.com/gin-gonic/gin"
+)
+
+// EStopReq は POST /api/estop の本文です（省略可）
+type EStopReq struct {
+	Reason string `json:"reason"`
+}
+
+// GetEStop は非常停止の状態を返します
+func (h *RobotHandler) GetEStop(c *gin.Context) {
+	c.JSON(http.StatusOK, h.controller.EStopStatus())
+}
+
+// EngageEStop は非常停止を掛けます。解除するまでゲームパッド・HTTP・WebSocket の指令を全て拒否する
+// 止めるのは安全側なので操作権が無くても受け付ける
+func (h *RobotHandler) EngageEStop(c *gin.Context) {
+	var req EStopReq
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid estop json", "detail": err.Error()})
+		return
+	}
+	if req.Reason == "" {
+		req.Reason = "estop requested via api"
+	}
+	setAudit(c, "estop", req.Reason)
+	c.JSON(http.StatusOK, h.controller.EStop(c.Request.Context(), robot.EStopSourceAPI, req.Reason))
+}
+
+// ClearEStop は非常停止を解除します（admin のみ）
+func (h *RobotHandler) ClearEStop(c *gin.Context) {
+	before := h.controller.EStopStatus()
+	setAudit(c, "estop_clear", "source="+before.Source+" reason="+before.Reason)
+	c.JSON(http.StatusOK, h.controller.ClearEStop(c.Request.Context()))
+}
@@ -0,0 +1,28 @@
+// internal/api/graph_handler.go
+package api
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetGraph はノード・トピック・サービスのつながりを返します
+// ?format=dot で Graphviz の DOT（Subscriber の居ない指令トピックは赤）、?hidden=true で /rosout やパラメータのサービスも含める
+func (h *RobotHandler) GetGraph(c *gin.Context) {
+	format := c.DefaultQuery("format", "json")
+	if format != "json" && format != "dot" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid format", "detail": "format must be json or dot"})
+		return
+	}
+	g, err := h.controller.Graph(c.Query("hidden") == "true")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "get graph failed", "detail": err.Error()})
+		return
+	}
+	if format == "dot" {
+		c.Data(http.StatusOK, "text/vnd.graphviz; charset=utf-8", []byte(g.DOT()))
+		return
+	}
+	c.JSON(http.StatusOK, g)
+}
@@ -0,0 +1,28 @@
+// internal/api/health_handler.go
+package api
+
+import (
+	"net/http"
+
+	"catchrobo_app/internal/robot"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Healthz はプロセスが生きているか（spin が回っているか）を返します。異常なら 503
+func (h *RobotHandler) Healthz(c *gin.Context) {
+	respondHealth(c, h.controller.Liveness())
+}
+
+// Readyz はアームのノード・カメラ・関節角度まで含めて使える状態かを返します。必須の項目が異常なら 503
+func (h *RobotHandler) Readyz(c *gin.Context) {
+	respondHealth(c, h.controller.Readiness())
+}
+
+func respondHealth(c *gin.Context, report robot.HealthReport) {
+	status := http.StatusOK
+	if report.Status != robot.HealthOK {
+		status = http.StatusServiceUnavailable
+	}
+	c.JSON(status, report)
+}
@@ -0,0 +1,109 @@
+// internal/api/jog_handler.go
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"sync/atomic"
+
+	"catchrobo_app/internal/jog"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/net/websocket"
+)
+
+// jogRequest はクライアントから /api/jog/ws へ送るメッセージです
+// type = velocity（linear/angular を指定、心拍も兼ねる）/ heartbeat / stop
+type jogRequest struct {
+	Type string `json:"type"`
+	jog.Twist
+}
+
+// jogReply はサーバーから返すメッセージです（type = ack は丸めた後の速度、error は理由）
+type jogReply struct {
+	Type  string     `json:"type"`
+	Twist *jog.Twist `json:"twist,omitempty"`
+	Error string     `json:"error,omitempty"`
+}
+
+var jogSessionSeq uint64
+
+// JogWebSocket は速度ジョグの WebSocket です。ボタンを押している間 velocity を送り続け、
+// 心拍が途絶えるか接続が切れるとバックエンド側でゼロ速度を送って止めます
+func (h *RobotHandler) JogWebSocket(c *gin.Context) {
+	jogger := h.controller.Jogger()
+	client, token := clientID(c), controlToken(c)
+	owner := fmt.Sprintf("%s#%d", client, atomic.AddUint64(&jogSessionSeq, 1))
+
+	server := websocket.Server{
+		// 同一LAN内のタブレットから使うので Origin は確認しない（他のAPIと同じ扱い）
+		Handshake: func(*websocket.Config, *http.Request) error { return nil },
+		Handler: func(ws *websocket.Conn) {
+			// WebSocket は http.Server.Shutdown の待ち対象にならないので、停止処理に入ったら自分で閉じる
+			done := make(chan struct{})
+			defer close(done)
+			go func() {
+				select {
+				case <-h.controller.ShuttingDown():
+					_ = ws.Close()
+				case <-done:
+				}
+			}()
+			defer ws.Close()
+			defer jogger.Stop(owner, "client disconnected")
+			for {
+				var msg jogRequest
+				if err := websocket.JSON.Receive(ws, &msg); err != nil {
+					return
+				}
+				// 接続中に操作権を奪われたら止める（velocity / heartbeat は操作として最終操作時刻も更新する）
+				if msg.Type != "stop" {
+					if _, err := h.control.Use(client, token); err != nil {
+						jogger.Stop(owner, "control lost")
+						_ = websocket.JSON.Send(ws, jogReply{Type: "error", Error: err.Error()})
+						continue
+					}
+				}
+				switch msg.Type {
+				case "velocity":
+					// 非常停止中や試合の状態が指令を許さなければ、このセッションのジョグを止めて理由を返す
+					if err := h.controller.AllowMotion(); err != nil && !msg.Twist.IsZero() {
+						jogger.Stop(owner, err.Error())
+						_ = websocket.JSON.Send(ws, jogReply{Type: "error", Error: err.Error()})
+						continue
+					}
+					applied, err := jogger.Set(owner, msg.Twist)
+					if err != nil {
+						_ = websocket.JSON.Send(ws, jogReply{Type: "error", Error: err.Error()})
+						continue
+					}
+					_ = websocket.JSON.Send(ws, jogReply{Type: "ack", Twist: &applied})
+				case "heartbeat":
+					jogger.Heartbeat(owner)
+				case "stop":
+					jogger.Stop(owner, "client stop")
+					_ = websocket.JSON.Send(ws, jogReply{Type: "ack", Twist: &jog.Twist{}})
+				default:
+					_ = websocket.JSON.Send(ws, jogReply{Type: "error", Error: "unknown message type: " + msg.Type})
+				}
+			}
+		},
+	}
+	server.ServeHTTP(c.Writer, c.Request)
+}
+
+// GetJogStatus は速度ジョグの状態を返します
+func (h *RobotHandler) GetJogStatus(c *gin.Context) {
+	c.JSON(http.StatusOK, h.controller.Jogger().Status())
+}
+
+// StopJog は誰が操作していてもジョグを止めます
+func (h *RobotHandler) StopJog(c *gin.Context) {
+	h.controller.Jogger().Stop("", "stop requested via api")
+	c.JSON(http.StatusOK, gin.H{"ok": true})
+}
+
+// GetJoy はゲームパッドの軸・ボタンの生の値と割り当てを返します（割り当ての調整用）
+func (h *RobotHandler) GetJoy(c *gin.Context) {
+	c.JSON(http.StatusOK, h.controller.Joy())
+}
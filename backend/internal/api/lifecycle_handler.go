@@ -0,0 +1,64 @@
+// internal/api/lifecycle_handler.go
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"catchrobo_app/internal/lifecycle"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TransitionReq は POST /api/lifecycle/:node/transition の本文です
+type TransitionReq struct {
+	Transition string `json:"transition" binding:"required"` // "configure" / "activate" / "deactivate" / "cleanup" / "shutdown" または ID
+}
+
+// GetLifecycleNodes はライフサイクルノードの一覧と、それぞれの状態・今行える遷移を返します
+func (h *RobotHandler) GetLifecycleNodes(c *gin.Context) {
+	nodes, err := h.controller.LifecycleNodes(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "get lifecycle nodes failed", "detail": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"nodes": nodes})
+}
+
+// TransitionLifecycleNode はノードに遷移を行わせます
+// 今の状態から行えない遷移は409（transitions に行えるもの）、ノードが遷移に失敗したら409（result に遷移後の状態）
+func (h *RobotHandler) TransitionLifecycleNode(c *gin.Context) {
+	node := nodeParam(c)
+	var req TransitionReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid transition json", "detail": err.Error()})
+		return
+	}
+	setAudit(c, "lifecycle_transition", fmt.Sprintf("node=%s transition=%s", node, req.Transition))
+
+	ctx := c.Request.Context()
+	res, err := h.controller.ChangeLifecycleState(ctx, node, req.Transition)
+	if errors.Is(err, lifecycle.ErrTransitionUnavailable) {
+		body := gin.H{"error": "transition not available", "detail": err.Error()}
+		if ts, err := h.controller.LifecycleTransitions(ctx, node); err == nil {
+			body["transitions"] = ts
+		}
+		c.JSON(http.StatusConflict, body)
+		return
+	}
+	if err != nil {
+		respondServiceError(c, "lifecycle transition failed", err)
+		return
+	}
+	to := "unknown"
+	if res.To != nil {
+		to = res.To.Label
+	}
+	setAudit(c, "lifecycle_transition", fmt.Sprintf("node=%s transition=%s from=%s to=%s success=%t", node, res.Transition.Label, res.From.Label, to, res.Success))
+	if !res.Success {
+		c.JSON(http.StatusConflict, gin.H{"error": "transition failed", "detail": fmt.Sprintf("%s rejected %s in %s", node, res.Transition.Label, res.From.Label), "result": res})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"result": res})
+}
@@ -0,0 +1,109 @@
+// internal/api/logging.go
+package api
+
+import (
+	"log/slog"
+	"net/http"
+	"regexp"
+	"time"
+
+	"catchrobo_app/internal/logging"
+
+	"github.com/gin-gonic/gin"
+)
+
+// requestIDHeader はリクエストIDを受け取り・返すヘッダです
+const requestIDHeader = "X-Request-Id"
+
+// validRequestID はクライアントが付けてきたリクエストIDとして受け付ける形です（ログを壊さないように制限する）
+var validRequestID = regexp.MustCompile(`^[A-Za-z0-9._:-]{1,64}$`)
+
+var httpLogger = logging.For(logging.ComponentHTTP)
+
+// requestID はリクエストごとのIDを決めてリクエストの context に入れ、応答ヘッダでも返します
+// コントローラーには c.Request.Context() を渡すので、Publish のログにも同じIDが出る
+func requestID() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetHeader(requestIDHeader)
+		if !validRequestID.MatchString(id) {
+			id = logging.NewRequestID()
+		}
+		c.Request = c.Request.WithContext(logging.WithRequestID(c.Request.Context(), id))
+		c.Header(requestIDHeader, id)
+		c.Next()
+	}
+}
+
+// requestLogger はアクセスログを JSON で出力します（認証されたユーザーとリクエストIDを含む）
+// 5xx は error、4xx は warn、それ以外は info
+func requestLogger() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+		status := c.Writer.Status()
+		level := slog.LevelInfo
+		switch {
+		case status >= 500:
+			level = slog.LevelError
+		case status >= 400:
+			level = slog.LevelWarn
+		}
+		ctx := c.Request.Context()
+		if !httpLogger.Enabled(ctx, level) {
+			return
+		}
+		attrs := []any{
+			"method", c.Request.Method,
+			// クエリは出さない（?access_token= や ?control_token= がログに残らないように）
+			"path", c.Request.URL.Path,
+			"route", c.FullPath(),
+			"status", status,
+			"latency_ms", float64(time.Since(start).Microseconds()) / 1000,
+			"client_ip", c.ClientIP(),
+		}
+		if id, ok := identityOf(c); ok {
+			attrs = append(attrs, "user", id.User)
+		}
+		if len(c.Errors) > 0 {
+			attrs = append(attrs, "errors", c.Errors.String())
+		}
+		httpLogger.Log(ctx, level, "request", attrs...)
+	}
+}
+
+// LogHandler はコンポーネントごとのログレベルを扱います
+type LogHandler struct{}
+
+// GetLogLevels はコンポーネントごとの現在のログレベルを返します
+func (LogHandler) GetLogLevels(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"components": logging.Components()})
+}
+
+// SetLogLevels は {"robot": "debug"} の形でコンポーネントごとのログレベルを変えます（再起動すると設定ファイルの値に戻る）
+func (LogHandler) SetLogLevels(c *gin.Context) {
+	var req map[string]string
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid log level json", "detail": err.Error()})
+		return
+	}
+	// 全て確認してから変える（一部だけ変わらないように）
+	levels := make(map[string]slog.Level, len(req))
+	known := logging.Components()
+	for name, s := range req {
+		if _, ok := known[name]; !ok {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "unknown log component", "detail": name, "components": logging.ComponentNames()})
+			return
+		}
+		l, err := logging.ParseLevel(s)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid log level", "detail": err.Error()})
+			return
+		}
+		levels[name] = l
+	}
+	for name, l := range levels {
+		_ = logging.SetLevel(name, l) // 存在は確認済み
+		httpLogger.InfoContext(c.Request.Context(), "log level changed", "target", name, "level", logging.LevelName(l))
+	}
+	c.JSON(http.StatusOK, gin.H{"components": logging.Components()})
+}
@@ -0,0 +1,127 @@
+// internal/api/logs_handler.go
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"catchrobo_app/internal/rosout"
+
+	"github.com/gin-gonic/gin"
+)
+
+// defaultLogLimit は /api/logs で返す件数と、SSE の接続時に送る直近の件数の既定値です
+const defaultLogLimit = 200
+
+// logFilter はクエリ（node / level / q / since）から条件を作ります
+func logFilter(c *gin.Context) (rosout.Filter, error) {
+	f := rosout.Filter{Node: c.Query("node"), Text: c.Query("q")}
+	level, err := rosout.ParseLevel(c.Query("level"))
+	if err != nil {
+		return f, err
+	}
+	f.MinLevel = level
+	// SSE の再接続ではブラウザが Last-Event-ID を付けてくる
+	since := c.Query("since")
+	if since == "" {
+		since = c.GetHeader("Last-Event-ID")
+	}
+	if since != "" {
+		f.AfterSeq, err = strconv.ParseUint(since, 10, 64)
+		if err != nil {
+			return f, fmt.Errorf("since must be a sequence number: %w", err)
+		}
+	}
+	return f, nil
+}
+
+func logLimit(c *gin.Context) (int, error) {
+	s := c.Query("limit")
+	if s == "" {
+		return defaultLogLimit, nil
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("limit must be a non-negative integer")
+	}
+	return n, nil
+}
+
+// GetLogs は /rosout のログを条件で絞って古い順に返します（limit は新しい方から数える。0 なら全件）
+// 例: /api/logs?node=arm&level=warn&q=timeout&limit=50
+func (h *RobotHandler) GetLogs(c *gin.Context) {
+	f, err := logFilter(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid log filter", "detail": err.Error()})
+		return
+	}
+	limit, err := logLimit(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid log filter", "detail": err.Error()})
+		return
+	}
+	buf := h.controller.Rosout()
+	c.JSON(http.StatusOK, gin.H{
+		"entries": buf.Query(f, limit),
+		"stats":   buf.Stats(),
+	})
+}
+
+// StreamLogs は /rosout のログを Server-Sent Events（event: log、id: seq）で送り続けます
+// 接続時に since（または Last-Event-ID）より後の分、無ければ直近 limit 件を送ってから新しいログを送る
+func (h *RobotHandler) StreamLogs(c *gin.Context) {
+	f, err := logFilter(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid log filter", "detail": err.Error()})
+		return
+	}
+	limit, err := logLimit(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid log filter", "detail": err.Error()})
+		return
+	}
+	if f.AfterSeq > 0 {
+		limit = 0
+	}
+
+	buf := h.controller.Rosout()
+	// 取りこぼさないよう、直近の分を読む前に購読する
+	live, cancel := buf.Subscribe()
+	defer cancel()
+
+	flusher, ok := startSSE(c)
+	if !ok {
+		return
+	}
+	for _, e := range buf.Query(f, limit) {
+		if writeSSE(c, flusher, "log", strconv.FormatUint(e.Seq, 10), e) != nil {
+			return
+		}
+		f.AfterSeq = e.Seq
+	}
+
+	heartbeat := time.NewTicker(sseHeartbeat)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-c.Request.Context().Done():
+			return
+		case <-h.controller.ShuttingDown():
+			return
+		case <-heartbeat.C:
+			if writeSSEHeartbeat(c, flusher) != nil {
+				return
+			}
+		case e := <-live:
+			// 直近の分として送ったものは飛ばす
+			if !f.Match(e) {
+				continue
+			}
+			if writeSSE(c, flusher, "log", strconv.FormatUint(e.Seq, 10), e) != nil {
+				return
+			}
+		}
+	}
+}
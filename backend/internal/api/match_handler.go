@@ -0,0 +1,92 @@
+// internal/api/match_handler.go
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"catchrobo_app/internal/match"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetMatch は試合の状態と残り時間を返します
+func (h *RobotHandler) GetMatch(c *gin.Context) {
+	c.JSON(http.StatusOK, h.controller.Match().Status())
+}
+
+// StreamMatch は接続時と状態が変わるたび、時計が進んでいる間は1秒ごとに状態（event: match、id: seq）を送り続けます
+func (h *RobotHandler) StreamMatch(c *gin.Context) {
+	m := h.controller.Match()
+	live, cancel := m.Subscribe()
+	defer cancel()
+
+	flusher, ok := startSSE(c)
+	if !ok {
+		return
+	}
+	st := m.Status()
+	if writeSSE(c, flusher, "match", strconv.FormatUint(st.Seq, 10), st) != nil {
+		return
+	}
+
+	heartbeat := time.NewTicker(sseHeartbeat)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-c.Request.Context().Done():
+			return
+		case <-h.controller.ShuttingDown():
+			return
+		case <-heartbeat.C:
+			if writeSSEHeartbeat(c, flusher) != nil {
+				return
+			}
+		case st := <-live:
+			if writeSSE(c, flusher, "match", strconv.FormatUint(st.Seq, 10), st) != nil {
+				return
+			}
+		}
+	}
+}
+
+// StartMatch は試合を次の段階へ進めます（idle → setting → ready → running、一時停止中なら再開）
+func (h *RobotHandler) StartMatch(c *gin.Context) {
+	h.changeMatch(c, "match_start", h.controller.Match().Start)
+}
+
+// PauseMatch は setting / running の時計を止めます。止めている間は指令を拒否する
+func (h *RobotHandler) PauseMatch(c *gin.Context) {
+	h.changeMatch(c, "match_pause", h.controller.Match().Pause)
+}
+
+// ResetMatch は試合を idle に戻します
+func (h *RobotHandler) ResetMatch(c *gin.Context) {
+	h.changeMatch(c, "match_reset", h.controller.Match().Reset)
+}
+
+// changeMatch は fn で試合の状態を変えて、変えた後の状態を返します。今の状態で行えなければ409
+func (h *RobotHandler) changeMatch(c *gin.Context, action string, fn func() (match.Status, error)) {
+	before := h.controller.Match().Status()
+	setAudit(c, action, "from="+matchStateLabel(before))
+	st, err := fn()
+	if err != nil {
+		if errors.Is(err, match.ErrInvalidTransition) {
+			c.JSON(http.StatusConflict, gin.H{"error": action + " failed", "detail": err.Error(), "match": st})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": action + " failed", "detail": err.Error()})
+		return
+	}
+	setAudit(c, action, "from="+matchStateLabel(before)+" to="+matchStateLabel(st))
+	c.JSON(http.StatusOK, st)
+}
+
+func matchStateLabel(st match.Status) string {
+	if st.Paused {
+		return st.State + "(paused)"
+	}
+	return st.State
+}
@@ -0,0 +1,54 @@
+// internal/api/metrics.go
+package api
+
+import (
+	"strconv"
+	"time"
+
+	"catchrobo_app/internal/metrics"
+
+	"github.com/gin-gonic/gin"
+)
+
+var (
+	metricHTTPRequests = metrics.NewCounterVec("catchrobo_http_requests_total", "HTTP requests per route and status.", "method", "route", "status")
+	metricHTTPDuration = metrics.NewHistogramVec("catchrobo_http_request_duration_seconds", "HTTP request latency per route (streams excluded).",
+		metrics.DefaultDurationBuckets, "method", "route")
+
+	metricMJPEGClients = metrics.NewGaugeVec("catchrobo_mjpeg_clients", "Active MJPEG stream clients.")
+	metricMJPEGSent    = metrics.NewCounterVec("catchrobo_mjpeg_frames_sent_total", "Frames written to MJPEG clients.")
+	metricMJPEGDropped = metrics.NewCounterVec("catchrobo_mjpeg_frames_dropped_total", "Frames an MJPEG client skipped because it was slower than the camera.")
+)
+
+// metricsMiddleware はルートごとのリクエスト数と処理時間を記録します
+// MJPEG・WebSocket・SSE のような長時間の接続は処理時間を記録しない
+func metricsMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		method := c.Request.Method
+		metricHTTPRequests.Inc(method, route, strconv.Itoa(c.Writer.Status()))
+		if !isStreamRoute(route) {
+			metricHTTPDuration.Observe(time.Since(start).Seconds(), method, route)
+		}
+	}
+}
+
+func isStreamRoute(route string) bool {
+	switch route {
+	case "/api/camera/mjpeg", "/api/jog/ws", "/api/logs/stream", "/api/diagnostics/stream", "/api/match/stream":
+		return true
+	}
+	return false
+}
+
+// Metrics は Prometheus のテキスト形式でメトリクスを返します
+func Metrics(c *gin.Context) {
+	c.Status(200)
+	c.Header("Content-Type", metrics.ContentType)
+	metrics.Default.WriteText(c.Writer)
+}
@@ -0,0 +1,113 @@
+// internal/api/params_handler.go
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"catchrobo_app/internal/params"
+	"catchrobo_app/internal/robot"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SetParamsReq は PUT /api/nodes/:node/params の本文です
+type SetParamsReq struct {
+	Params []params.Update `json:"params" binding:"required,min=1,dive"`
+	Atomic *bool           `json:"atomic"` // 省略すると true（全て成功か全て失敗）
+}
+
+// nodeParam は URL のノード名を "/ns/name" にします（名前空間付きは %2F で渡す）
+func nodeParam(c *gin.Context) string {
+	return robot.NormalizeNodeName(c.Param("node"))
+}
+
+// respondServiceError は他ノードのサービス呼び出しのエラーを HTTP ステータスに対応付けて返します
+func respondServiceError(c *gin.Context, msg string, err error) {
+	switch {
+	case errors.Is(err, robot.ErrNodeNotFound), errors.Is(err, robot.ErrServiceNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": msg, "detail": err.Error()})
+	case errors.Is(err, params.ErrInvalidValue):
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg, "detail": err.Error()})
+	case errors.Is(err, robot.ErrServiceTimeout):
+		c.JSON(http.StatusGatewayTimeout, gin.H{"error": msg, "detail": err.Error()})
+	case errors.Is(err, robot.ErrShuttingDown):
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": msg, "detail": err.Error()})
+	default:
+		c.JSON(http.StatusBadGateway, gin.H{"error": msg, "detail": err.Error()})
+	}
+}
+
+// GetNodes は ROS グラフ上のノード名を返します
+func (h *RobotHandler) GetNodes(c *gin.Context) {
+	nodes, err := h.controller.NodeNames()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "get nodes failed", "detail": err.Error()})
+		return
+	}
+	sort.Strings(nodes)
+	c.JSON(http.StatusOK, gin.H{"nodes": nodes})
+}
+
+// GetParams はノードのパラメータの値と説明（型・範囲・read_only など）を返します
+// ?names=a,b で指定したものだけ、?prefix= でその名前空間以下だけにできる
+func (h *RobotHandler) GetParams(c *gin.Context) {
+	node := nodeParam(c)
+	var names []string
+	for _, n := range strings.Split(c.Query("names"), ",") {
+		if n = strings.TrimSpace(n); n != "" {
+			names = append(names, n)
+		}
+	}
+	ps, err := h.controller.Parameters(c.Request.Context(), node, names, c.Query("prefix"))
+	if err != nil {
+		respondServiceError(c, "get parameters failed", err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"node": node, "params": ps})
+}
+
+// SetParams はノードのパラメータを変更します。type を省略すると現在の型に合わせて値を変換する
+// 値が型に合わなければノードに送らずに 400、ノードが拒否したら 409（results に理由）
+func (h *RobotHandler) SetParams(c *gin.Context) {
+	node := nodeParam(c)
+	var req SetParamsReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid params json", "detail": err.Error()})
+		return
+	}
+	seen := map[string]bool{}
+	names := make([]string, 0, len(req.Params))
+	for _, p := range req.Params {
+		if seen[p.Name] {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "duplicate parameter", "detail": p.Name})
+			return
+		}
+		seen[p.Name] = true
+		names = append(names, p.Name)
+	}
+	atomic := req.Atomic == nil || *req.Atomic
+	setAudit(c, "set_parameters", fmt.Sprintf("node=%s names=%s", node, strings.Join(names, ",")))
+
+	res, err := h.controller.SetParameters(c.Request.Context(), node, req.Params, atomic)
+	if err != nil {
+		respondServiceError(c, "set parameters failed", err)
+		return
+	}
+	changes := make([]string, len(res.Changes))
+	for i, ch := range res.Changes {
+		changes[i] = fmt.Sprintf("%s: %v -> %v", ch.Name, ch.Old.Value, ch.New.Value)
+	}
+	setAudit(c, "set_parameters", fmt.Sprintf("node=%s atomic=%t changes=[%s]", node, atomic, strings.Join(changes, "; ")))
+
+	status := http.StatusOK
+	for _, r := range res.Results {
+		if !r.Successful {
+			status = http.StatusConflict
+		}
+	}
+	c.JSON(status, gin.H{"node": node, "atomic": res.Atomic, "results": res.Results, "changes": res.Changes})
+}
@@ -0,0 +1,142 @@
+// internal/api/planner_handler.go
+package api
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+
+	"catchrobo_app/internal/geom"
+	"catchrobo_app/internal/match"
+	"catchrobo_app/internal/planner"
+	"catchrobo_app/internal/ratelimit"
+	"catchrobo_app/internal/robot"
+
+	"github.com/gin-gonic/gin"
+)
+
+// NextStepReq は POST /api/plan/next の本文です
+type NextStepReq struct {
+	// 前の手で取れたか（オペレーターの確認）。記録中の試技に付ける。省略すれば付けない
+	Success *bool `json:"success"`
+}
+
+// respondPlanError は計画まわりのエラーを返します
+func (h *RobotHandler) respondPlanError(c *gin.Context, msg string, st planner.ExecStatus, err error) {
+	switch {
+	case errors.Is(err, planner.ErrInvalidPlan):
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg, "detail": err.Error()})
+	case errors.Is(err, planner.ErrNoPlan), errors.Is(err, planner.ErrBusy), errors.Is(err, planner.ErrAborted):
+		c.JSON(http.StatusConflict, gin.H{"error": msg, "detail": err.Error(), "execution": st})
+	case errors.Is(err, robot.ErrNotArrived):
+		c.JSON(http.StatusGatewayTimeout, gin.H{"error": msg, "detail": err.Error(), "execution": st})
+	case errors.Is(err, robot.ErrShuttingDown), errors.Is(err, match.ErrMotionNotAllowed), errors.Is(err, robot.ErrEStopped), errors.As(err, new(*ratelimit.LimitedError)):
+		respondPublishError(c, msg, err)
+	default:
+		respondTargetError(c, msg, h.controller.Target(), err)
+	}
+}
+
+// GetField はフィールドのセルの座標（指令のフレーム）を返します
+func (h *RobotHandler) GetField(c *gin.Context) {
+	field := h.controller.Field()
+	c.JSON(http.StatusOK, gin.H{
+		"frame_id": robot.CommandFrameId,
+		"field":    field,
+		"blue":     fieldCells(field, planner.SideBlue),
+		"red":      fieldCells(field, planner.SideRed),
+	})
+}
+
+func fieldCells(field planner.Field, side string) gin.H {
+	cells := make(map[string]geom.Vec3, field.Cells())
+	for cell := 1; cell <= field.Cells(); cell++ {
+		if pos, err := field.CellPosition(side, cell); err == nil {
+			cells[strconv.Itoa(cell)] = pos
+		}
+	}
+	return gin.H{"cells": cells, "release": field.ReleasePosition(side)}
+}
+
+// PlanPicks は残っているワークを取る順番を計画して返します（プレビューのみで Publish しない）
+func (h *RobotHandler) PlanPicks(c *gin.Context) {
+	var req planner.Request
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid plan json", "detail": err.Error()})
+		return
+	}
+	plan, err := h.controller.PlanPicks(req)
+	if err != nil {
+		h.respondPlanError(c, "plan failed", h.controller.PlanExecution(), err)
+		return
+	}
+	c.JSON(http.StatusOK, plan)
+}
+
+// GetPlanExecution は計画の実行の状態（次の手と終えた手の数）を返します
+func (h *RobotHandler) GetPlanExecution(c *gin.Context) {
+	c.JSON(http.StatusOK, h.controller.PlanExecution())
+}
+
+// ExecutePlan は計画を作り直して読み込み、最初の1手を実行します
+// 残りの手はオペレーターが取れたかを確かめてから POST /api/plan/next で1手ずつ進める
+func (h *RobotHandler) ExecutePlan(c *gin.Context) {
+	var req planner.Request
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid plan json", "detail": err.Error()})
+		return
+	}
+	ctx := c.Request.Context()
+	plan, err := h.controller.PlanPicks(req)
+	if err != nil {
+		h.respondPlanError(c, "plan failed", h.controller.PlanExecution(), err)
+		return
+	}
+	setAudit(c, "plan_execute", fmt.Sprintf("side=%s steps=%d", plan.Side, len(plan.Steps)))
+	st, err := h.controller.LoadPlan(ctx, plan)
+	if err != nil {
+		h.respondPlanError(c, "load plan failed", st, err)
+		return
+	}
+	st, err = h.controller.RunPlanStep(ctx, nil)
+	if err != nil {
+		h.respondPlanError(c, "plan step failed", st, err)
+		return
+	}
+	c.JSON(http.StatusOK, st)
+}
+
+// NextPlanStep は計画の次の1手を実行します。前の手が失敗していれば同じ手をやり直す
+func (h *RobotHandler) NextPlanStep(c *gin.Context) {
+	var req NextStepReq
+	// 本文は省略できる（前の試技の成否を付けない）
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid step json", "detail": err.Error()})
+		return
+	}
+	prev := h.controller.PlanExecution()
+	detail := fmt.Sprintf("step=%d", prev.Next)
+	if req.Success != nil {
+		detail += fmt.Sprintf(" prev_success=%t", *req.Success)
+	}
+	setAudit(c, "plan_next", detail)
+	st, err := h.controller.RunPlanStep(c.Request.Context(), req.Success)
+	if err != nil {
+		h.respondPlanError(c, "plan step failed", st, err)
+		return
+	}
+	c.JSON(http.StatusOK, st)
+}
+
+// AbortPlan は計画の実行をやめます（実行中の手は次の指令の前で止まる）
+func (h *RobotHandler) AbortPlan(c *gin.Context) {
+	st, err := h.controller.AbortPlan(c.Request.Context())
+	if err != nil {
+		h.respondPlanError(c, "abort plan failed", st, err)
+		return
+	}
+	setAudit(c, "plan_abort", fmt.Sprintf("completed=%d", st.Completed))
+	c.JSON(http.StatusOK, st)
+}
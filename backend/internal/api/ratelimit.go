@@ -0,0 +1,72 @@
+// internal/api/ratelimit.go
+package api
+
+import (
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+
+	"catchrobo_app/internal/match"
+	"catchrobo_app/internal/ratelimit"
+	"catchrobo_app/internal/robot"
+
+	"github.com/gin-gonic/gin"
+)
+
+// rateLimitMiddleware は "METHOD /api/path" ごとの制限をかけます（ルールの無いエンドポイントは素通し）
+func rateLimitMiddleware(l *ratelimit.Limiter) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if err := l.Allow(c.Request.Method + " " + c.FullPath()); err != nil {
+			respondPublishError(c, "too many requests", err)
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// respondPublishError は Publish のエラーを返します。制限を超えた場合は 429 と Retry-After、停止処理中は 503、
+// 試合の状態が指令を許さない場合（セッティング・競技中以外、時間切れ後）は 409 を返す
+func respondPublishError(c *gin.Context, msg string, err error) {
+	var limited *ratelimit.LimitedError
+	if errors.As(err, &limited) {
+		// Retry-After は秒単位なので切り上げる
+		c.Header("Retry-After", strconv.Itoa(int(math.Ceil(limited.RetryAfter.Seconds()))))
+		c.JSON(http.StatusTooManyRequests, gin.H{
+			"error":          msg,
+			"detail":         err.Error(),
+			"key":            limited.Key,
+			"reason":         limited.Reason,
+			"retry_after_ms": limited.RetryAfter.Milliseconds(),
+		})
+		return
+	}
+	if errors.Is(err, robot.ErrShuttingDown) {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": msg, "detail": err.Error()})
+		return
+	}
+	if errors.Is(err, match.ErrMotionNotAllowed) || errors.Is(err, robot.ErrEStopped) {
+		c.JSON(http.StatusConflict, gin.H{"error": msg, "detail": err.Error()})
+		return
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{"error": msg, "detail": err.Error()})
+}
+
+// AdminHandler は運用向けの設定・カウンタを返します
+type AdminHandler struct {
+	controller *robot.RobotController
+	endpoints  *ratelimit.Limiter
+}
+
+func NewAdminHandler(rc *robot.RobotController, endpoints *ratelimit.Limiter) *AdminHandler {
+	return &AdminHandler{controller: rc, endpoints: endpoints}
+}
+
+// GetRateLimits はエンドポイント・トピックごとの制限と受け付け / 拒否の回数を返します
+func (h *AdminHandler) GetRateLimits(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"endpoints": h.endpoints.Stats(),
+		"topics":    h.controller.TopicLimits(),
+	})
+}
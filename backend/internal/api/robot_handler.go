@@ -2,28 +2,54 @@
 package api
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
+	"strconv"
+	"strings"
 	"time"
 
+	"catchrobo_app/internal/control"
+	"catchrobo_app/internal/geom"
+	"catchrobo_app/internal/joint"
+	"catchrobo_app/internal/kinematics"
 	"catchrobo_app/internal/robot"
 
 	"github.com/gin-gonic/gin"
 )
 
-// RobotHandler は robot.Controller を保持します
+// RobotHandler は robot.Controller と操作権を保持します
 type RobotHandler struct {
 	controller *robot.RobotController
+	control    *control.Lock
 }
 
-func NewRobotHandler(rc *robot.RobotController) *RobotHandler {
-	return &RobotHandler{controller: rc}
+func NewRobotHandler(rc *robot.RobotController, lock *control.Lock) *RobotHandler {
+	return &RobotHandler{controller: rc, control: lock}
 }
 
 type PositionReq struct {
 	X float64 `json:"x"`
 	Y float64 `json:"y"`
 	Z float64 `json:"z"`
+	// 姿勢は quaternion / rpy_deg のどちらか一方（省略時は現在の目標姿勢を維持）
+	Quaternion *geom.Quaternion `json:"quaternion,omitempty"`
+	RPYDeg     *RPYDeg          `json:"rpy_deg,omitempty"`
+	FrameId    string           `json:"frame_id"`
+}
+
+// RPYDeg は roll/pitch/yaw を度で表したものです
+type RPYDeg struct {
+	Roll  float64 `json:"roll"`
+	Pitch float64 `json:"pitch"`
+	Yaw   float64 `json:"yaw"`
+}
+
+// IKReq は逆運動学プレビューの入力です（姿勢の指定は PositionReq と同じ）
+type IKReq struct {
+	PositionReq
+	Seed         []float64 `json:"seed,omitempty"`          // 関節モデルの並び順の初期値
+	PositionOnly *bool     `json:"position_only,omitempty"` // 省略時は設定値
 }
 
 type JointAnglesReq struct {
@@ -34,6 +60,53 @@ type DisplacementReq struct {
 	Dx float64 `json:"dx"`
 	Dy float64 `json:"dy"`
 	Dz float64 `json:"dz"`
+	// 回転増分 [deg]（固定軸まわり）
+	DRoll   float64 `json:"droll"`
+	DPitch  float64 `json:"dpitch"`
+	DYaw    float64 `json:"dyaw"`
+	FrameId string  `json:"frame_id"`
+}
+
+// toGoal は入力を検証して robot.PoseGoal に変換します（クォータニオンは正規化する）
+func (r PositionReq) toGoal() (robot.PoseGoal, error) {
+	goal := robot.PoseGoal{Position: geom.Vec3{X: r.X, Y: r.Y, Z: r.Z}, FrameId: r.FrameId}
+	if !goal.Position.IsFinite() {
+		return goal, fmt.Errorf("position: %w", geom.ErrNotFinite)
+	}
+	switch {
+	case r.Quaternion != nil && r.RPYDeg != nil:
+		return goal, fmt.Errorf("specify either quaternion or rpy_deg, not both")
+	case r.Quaternion != nil:
+		q, err := r.Quaternion.Normalize()
+		if err != nil {
+			return goal, fmt.Errorf("quaternion: %w", err)
+		}
+		goal.Orientation = &q
+	case r.RPYDeg != nil:
+		rpy := geom.Vec3{X: r.RPYDeg.Roll, Y: r.RPYDeg.Pitch, Z: r.RPYDeg.Yaw}
+		if !rpy.IsFinite() {
+			return goal, fmt.Errorf("rpy_deg: %w", geom.ErrNotFinite)
+		}
+		q := geom.FromRPY(geom.Deg2Rad(rpy.X), geom.Deg2Rad(rpy.Y), geom.Deg2Rad(rpy.Z))
+		goal.Orientation = &q
+	}
+	return goal, nil
+}
+
+// toDelta は入力を検証して robot.PoseDelta に変換します
+func (r DisplacementReq) toDelta() (robot.PoseDelta, error) {
+	delta := robot.PoseDelta{Translation: geom.Vec3{X: r.Dx, Y: r.Dy, Z: r.Dz}, FrameId: r.FrameId}
+	if !delta.Translation.IsFinite() {
+		return delta, fmt.Errorf("displacement: %w", geom.ErrNotFinite)
+	}
+	rot := geom.Vec3{X: r.DRoll, Y: r.DPitch, Z: r.DYaw}
+	if !rot.IsFinite() {
+		return delta, fmt.Errorf("rotation increment: %w", geom.ErrNotFinite)
+	}
+	if rot != (geom.Vec3{}) {
+		delta.Rotation = geom.FromRPY(geom.Deg2Rad(rot.X), geom.Deg2Rad(rot.Y), geom.Deg2Rad(rot.Z))
+	}
+	return delta, nil
 }
 
 func (h *RobotHandler) SendPositionCommand(c *gin.Context) {
@@ -42,11 +115,123 @@ func (h *RobotHandler) SendPositionCommand(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid position json", "detail": err.Error()})
 		return
 	}
-	if err := h.controller.PublishPosition(req.X, req.Y, req.Z); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "publish position failed", "detail": err.Error()})
+	goal, err := req.toGoal()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid position", "detail": err.Error()})
 		return
 	}
-	c.JSON(http.StatusOK, gin.H{"ok": true})
+	ifMatch, err := parseIfMatch(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid If-Match header", "detail": err.Error()})
+		return
+	}
+	target, err := h.controller.PublishPosition(c.Request.Context(), goal, ifMatch)
+	if err != nil {
+		respondTargetError(c, "publish position failed", target, err)
+		return
+	}
+	setTargetETag(c, target)
+	c.JSON(http.StatusOK, gin.H{"ok": true, "target": target})
+}
+
+// GetTarget は現在の目標位置（版数付き）と計測姿勢を返します
+func (h *RobotHandler) GetTarget(c *gin.Context) {
+	target := h.controller.Target()
+	res := gin.H{"target": target}
+	if measured, ok := h.controller.MeasuredPose(); ok {
+		res["measured"] = measured
+	}
+	setTargetETag(c, target)
+	c.JSON(http.StatusOK, res)
+}
+
+// parseIfMatch は If-Match ヘッダ（"3" / 3 / *）から期待する版数を取り出します
+// ヘッダが無い、または * の場合は nil（無条件更新）を返します
+func parseIfMatch(c *gin.Context) (*uint64, error) {
+	v := strings.TrimSpace(c.GetHeader("If-Match"))
+	if v == "" || v == "*" {
+		return nil, nil
+	}
+	v = strings.TrimPrefix(v, "W/")
+	v = strings.Trim(v, `"`)
+	version, err := strconv.ParseUint(v, 10, 64)
+	if err != nil {
+		return nil, err
+	}
+	return &version, nil
+}
+
+func setTargetETag(c *gin.Context, target robot.TargetSnapshot) {
+	c.Header("ETag", strconv.Quote(strconv.FormatUint(target.Version, 10)))
+}
+
+// respondTargetError は目標位置更新のエラーをHTTPステータスに対応付けて返します
+func respondTargetError(c *gin.Context, msg string, target robot.TargetSnapshot, err error) {
+	switch {
+	case errors.Is(err, robot.ErrTargetVersionMismatch):
+		setTargetETag(c, target)
+		c.JSON(http.StatusPreconditionFailed, gin.H{"error": msg, "detail": err.Error(), "target": target})
+	case errors.Is(err, robot.ErrTargetUninitialized):
+		c.JSON(http.StatusConflict, gin.H{"error": msg, "detail": err.Error(), "target": target})
+	case errors.Is(err, robot.ErrFrameMismatch), errors.Is(err, robot.ErrTransformUnavailable):
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": msg, "detail": err.Error(), "target": target})
+	case errors.Is(err, robot.ErrInvalidPose):
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg, "detail": err.Error()})
+	default:
+		respondIKError(c, msg, err)
+	}
+}
+
+// respondIKError は逆運動学まわりのエラーを返します（届かない目標は解の誤差も返す）
+func respondIKError(c *gin.Context, msg string, err error) {
+	var unreachable *robot.UnreachableError
+	var verr *joint.ValidationError
+	switch {
+	case errors.As(err, &unreachable):
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": msg, "detail": err.Error(), "ik": unreachable.Result})
+	case errors.As(err, &verr):
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": msg, "detail": err.Error(), "violations": verr.Violations})
+	case errors.Is(err, robot.ErrTransformUnavailable):
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": msg, "detail": err.Error()})
+	case errors.Is(err, robot.ErrModelUnavailable):
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": msg, "detail": err.Error()})
+	case errors.Is(err, robot.ErrNoJointData):
+		c.JSON(http.StatusConflict, gin.H{"error": msg, "detail": err.Error()})
+	default:
+		respondPublishError(c, msg, err)
+	}
+}
+
+// GetTransform は from フレームの点を to フレームへ写す変換を返します
+// ?time=<unix秒> を付けるとその時刻で補間します（省略時は最新）
+func (h *RobotHandler) GetTransform(c *gin.Context) {
+	from, to := c.Param("from"), c.Param("to")
+	var at time.Time
+	if v := c.Query("time"); v != "" {
+		sec, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid time", "detail": err.Error()})
+			return
+		}
+		at = time.Unix(0, int64(sec*float64(time.Second)))
+	}
+	t, err := h.controller.LookupTransform(to, from, at)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "lookup transform failed", "detail": err.Error()})
+		return
+	}
+	roll, pitch, yaw := t.Rotation.RPY()
+	c.JSON(http.StatusOK, gin.H{
+		"from":      from,
+		"to":        to,
+		"transform": t,
+		"rpy_deg":   RPYDeg{Roll: geom.Rad2Deg(roll), Pitch: geom.Rad2Deg(pitch), Yaw: geom.Rad2Deg(yaw)},
+	})
+}
+
+// GetFrames は TF バッファが保持しているフレーム（子 → 親）の一覧を返します
+func (h *RobotHandler) GetFrames(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"command_frame": robot.CommandFrameId, "frames": h.controller.Frames()})
 }
 
 func (h *RobotHandler) GetTopics(c *gin.Context) {
@@ -63,72 +248,72 @@ func (h *RobotHandler) Hello(c *gin.Context) {
 }
 
 func (h *RobotHandler) StartMotion(c *gin.Context) {
-	if err := h.controller.PublishStartMotion(); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "publish start motion failed", "detail": err.Error()})
+	if err := h.controller.PublishStartMotion(c.Request.Context()); err != nil {
+		respondPublishError(c, "publish start motion failed", err)
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{"ok": true})
 }
 
 func (h *RobotHandler) DownMotion(c *gin.Context) {
-	if err := h.controller.PublishDownMotion(); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "publish down motion failed", "detail": err.Error()})
+	if err := h.controller.PublishDownMotion(c.Request.Context()); err != nil {
+		respondPublishError(c, "publish down motion failed", err)
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{"ok": true})
 }
 
 func (h *RobotHandler) UpMotion(c *gin.Context) {
-	if err := h.controller.PublishUpMotion(); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "publish up motion failed", "detail": err.Error()})
+	if err := h.controller.PublishUpMotion(c.Request.Context()); err != nil {
+		respondPublishError(c, "publish up motion failed", err)
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{"ok": true})
 }
 
 func (h *RobotHandler) CatchMotion(c *gin.Context) {
-	if err := h.controller.PublishCatchMotion(); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "publish catch motion failed", "detail": err.Error()})
+	if err := h.controller.PublishCatchMotion(c.Request.Context()); err != nil {
+		respondPublishError(c, "publish catch motion failed", err)
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{"ok": true})
 }
 
 func (h *RobotHandler) ReleaseMotion(c *gin.Context) {
-	if err := h.controller.PublishReleaseMotion(); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "publish release motion failed", "detail": err.Error()})
+	if err := h.controller.PublishReleaseMotion(c.Request.Context()); err != nil {
+		respondPublishError(c, "publish release motion failed", err)
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{"ok": true})
 }
 
 func (h *RobotHandler) ResetMotion(c *gin.Context) {
-	if err := h.controller.PublishResetMotion(); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "publish reset motion failed", "detail": err.Error()})
+	if err := h.controller.PublishResetMotion(c.Request.Context()); err != nil {
+		respondPublishError(c, "publish reset motion failed", err)
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{"ok": true})
 }
 
 func (h *RobotHandler) AddDownMotion(c *gin.Context) {
-	if err := h.controller.PublishAddDownMotion(); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "publish add down motion failed", "detail": err.Error()})
+	if err := h.controller.PublishAddDownMotion(c.Request.Context()); err != nil {
+		respondPublishError(c, "publish add down motion failed", err)
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{"ok": true})
 }
 
 func (h *RobotHandler) AddUpMotion(c *gin.Context) {
-	if err := h.controller.PublishAddUpMotion(); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "publish add up motion failed", "detail": err.Error()})
+	if err := h.controller.PublishAddUpMotion(c.Request.Context()); err != nil {
+		respondPublishError(c, "publish add up motion failed", err)
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{"ok": true})
 }
 
 func(h * RobotHandler) MiddleMotion(c *gin.Context) {
-	if err := h.controller.PublishMiddleMotion(); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "publish middle motion failed", "detail": err.Error()})
+	if err := h.controller.PublishMiddleMotion(c.Request.Context()); err != nil {
+		respondPublishError(c, "publish middle motion failed", err)
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{"ok": true})
@@ -140,11 +325,23 @@ func (h *RobotHandler) SendDisplacementCommand(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid displacement json", "detail": err.Error()})
 		return
 	}
-	if err := h.controller.PublishDisplacement(req.Dx, req.Dy, req.Dz); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "publish displacement failed", "detail": err.Error()})
+	delta, err := req.toDelta()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid displacement", "detail": err.Error()})
 		return
 	}
-	c.JSON(http.StatusOK, gin.H{"ok": true})
+	ifMatch, err := parseIfMatch(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid If-Match header", "detail": err.Error()})
+		return
+	}
+	target, err := h.controller.PublishDisplacement(c.Request.Context(), delta, ifMatch)
+	if err != nil {
+		respondTargetError(c, "publish displacement failed", target, err)
+		return
+	}
+	setTargetETag(c, target)
+	c.JSON(http.StatusOK, gin.H{"ok": true, "target": target})
 }
 
 func (h *RobotHandler) SendJointAngles(c *gin.Context) {
@@ -153,11 +350,103 @@ func (h *RobotHandler) SendJointAngles(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid joint angles json", "detail": err.Error()})
 		return
 	}
-	if err := h.controller.PublishJointAngles(req.Angles); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "publish joint angles failed", "detail": err.Error()})
+	if err := h.controller.PublishJointAngles(c.Request.Context(), req.Angles); err != nil {
+		var verr *joint.ValidationError
+		if errors.As(err, &verr) {
+			c.JSON(http.StatusUnprocessableEntity, gin.H{
+				"error":      "joint angles out of limits",
+				"detail":     verr.Error(),
+				"expected":   verr.Expected,
+				"got":        verr.Got,
+				"violations": verr.Violations,
+			})
+			return
+		}
+		if errors.Is(err, robot.ErrNoJointData) {
+			c.JSON(http.StatusConflict, gin.H{"error": "publish joint angles failed", "detail": err.Error()})
+			return
+		}
+		respondPublishError(c, "publish joint angles failed", err)
 		return
 	}
-	c.JSON(http.StatusOK, gin.H{"ok": true})
+	res := gin.H{"ok": true}
+	// URDF があれば指令した関節角度での手先姿勢も返す
+	angles := make([]float64, len(req.Angles))
+	for i, a := range req.Angles {
+		angles[i] = float64(a)
+	}
+	if pose, err := h.controller.ToolPoseFor(angles); err == nil {
+		res["tool_pose"] = pose
+	}
+	c.JSON(http.StatusOK, res)
+}
+
+// SolveIK は目標姿勢の逆運動学を解いて返します（Publish はしない）
+// 届かない場合も 200 で reachable=false と最も近い解を返す
+func (h *RobotHandler) SolveIK(c *gin.Context) {
+	var req IKReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid ik json", "detail": err.Error()})
+		return
+	}
+	goal, err := req.toGoal()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid position", "detail": err.Error()})
+		return
+	}
+	var opts *kinematics.IKOptions
+	if req.PositionOnly != nil {
+		o := h.controller.IKOptions()
+		o.PositionOnly = *req.PositionOnly
+		opts = &o
+	}
+	sol, err := h.controller.SolveIK(goal, req.Seed, opts)
+	var unreachable *robot.UnreachableError
+	if err != nil && !errors.As(err, &unreachable) {
+		respondIKError(c, "ik failed", err)
+		return
+	}
+	res := gin.H{"solution": sol}
+	if sol.Angles != nil {
+		if pose, err := h.controller.ToolPoseFor(sol.Angles); err == nil {
+			res["tool_pose"] = pose
+		}
+	}
+	c.JSON(http.StatusOK, res)
+}
+
+// GetRobotModel は読み込んだ URDF のリンク木と順運動学に使うチェーンを返します
+func (h *RobotHandler) GetRobotModel(c *gin.Context) {
+	model, err := h.controller.RobotModel()
+	if err != nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "robot model unavailable", "detail": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, model)
+}
+
+// GetToolPose は計測（?source=measured、既定）または指令（?source=commanded）の関節角度から手先姿勢を返します
+func (h *RobotHandler) GetToolPose(c *gin.Context) {
+	source := c.DefaultQuery("source", robot.JointSourceMeasured)
+	pose, err := h.controller.ForwardKinematics(source)
+	if err != nil {
+		switch {
+		case errors.Is(err, robot.ErrModelUnavailable), errors.Is(err, robot.ErrNoJointData):
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": "forward kinematics unavailable", "detail": err.Error()})
+		default:
+			c.JSON(http.StatusBadRequest, gin.H{"error": "forward kinematics failed", "detail": err.Error()})
+		}
+		return
+	}
+	c.JSON(http.StatusOK, pose)
+}
+
+// GetJoints は関節名・並び順・可動範囲と最後に送った関節角度を返します（UIのスライダー用）
+func (h *RobotHandler) GetJoints(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"joints":       h.controller.JointModel().Joints,
+		"last_command": h.controller.LastJointCommand(),
+	})
 }
 
 /* ------- Camera Endpoints ------- */
@@ -190,15 +479,25 @@ func (h *RobotHandler) CameraMJPEG(c *gin.Context) {
 	ticker := time.NewTicker(33 * time.Millisecond) // ~30fps 上限
 	defer ticker.Stop()
 
+	metricMJPEGClients.Add(1)
+	defer metricMJPEGClients.Add(-1)
+
 	for {
 		select {
 		case <-c.Request.Context().Done():
 			return
+		case <-h.controller.ShuttingDown():
+			// http.Server.Shutdown はストリームが終わるまで待つので、停止処理に入ったら閉じる
+			return
 		case <-ticker.C:
 			jpg, seq := h.controller.GetLatestJPEG()
 			if len(jpg) == 0 || seq == lastSeq {
 				continue
 			}
+			if lastSeq != 0 && seq > lastSeq+1 {
+				// 送る間に更新されたフレームはこのクライアントには届かない
+				metricMJPEGDropped.Add(float64(seq - lastSeq - 1))
+			}
 			lastSeq = seq
 
 			_, _ = fmt.Fprintf(c.Writer, "--%s\r\n", boundary)
@@ -207,6 +506,7 @@ func (h *RobotHandler) CameraMJPEG(c *gin.Context) {
 			_, _ = c.Writer.Write(jpg)
 			_, _ = fmt.Fprintf(c.Writer, "\r\n")
 			flusher.Flush()
+			metricMJPEGSent.Inc()
 		}
 	}
 }
@@ -2,40 +2,149 @@
 package api
 
 import (
+	"catchrobo_app/internal/audit"
+	"catchrobo_app/internal/auth"
+	"catchrobo_app/internal/config"
+	"catchrobo_app/internal/control"
+	"catchrobo_app/internal/ratelimit"
 	"catchrobo_app/internal/robot"
 
 	"github.com/gin-gonic/gin"
 )
 
 // SetupRouter はGinのルーターを設定し、返します
-func SetupRouter(rc *robot.RobotController) *gin.Engine {
-	r := gin.Default()
+// users が nil なら認証を無効にし、全てのリクエストを admin として扱います
+func SetupRouter(rc *robot.RobotController, cfg *config.Config, users *auth.Store, auditLog *audit.Log) *gin.Engine {
+	r := gin.New()
+	// 名前空間付きのノード名（/api/nodes/%2Farm%2Fdriver/params）を1つのパラメータとして受ける
+	r.UseRawPath = true
+	r.UnescapePathValues = true
+	r.Use(requestID(), requestLogger(), gin.Recovery(), metricsMiddleware())
 
-	robotHandler := NewRobotHandler(rc)
+	lock := control.NewLock(cfg.Control.IdleTimeout())
+	robotHandler := NewRobotHandler(rc, lock)
+	controlHandler := NewControlHandler(lock)
+	authHandler := NewAuthHandler(users, auditLog)
+	endpointLimits := ratelimit.New(cfg.RateLimit.Endpoints)
+	adminHandler := NewAdminHandler(rc, endpointLimits)
+	logHandler := LogHandler{}
 
-	api := r.Group("/api")
+	// ログインだけは認証なしで受け付ける
+	public := r.Group("/api", rateLimitMiddleware(endpointLimits))
+	public.POST("/auth/login", authHandler.Login)
+
+	api := r.Group("/api", rateLimitMiddleware(endpointLimits), authenticate(users), auditMiddleware(auditLog))
+
+	// ---- viewer: カメラと状態の取得 ----
+	viewer := api.Group("", requireRole(auth.RoleViewer))
 	{
-		api.POST("/position", robotHandler.SendPositionCommand)
-		api.POST("/move", robotHandler.SendDisplacementCommand)
-		api.POST("/joint_angles", robotHandler.SendJointAngles)	
-		api.GET("/topics", robotHandler.GetTopics)
-
-		api.POST("/start_motion", robotHandler.StartMotion)
-		api.POST("/down_motion", robotHandler.DownMotion)
-		api.POST("/up_motion", robotHandler.UpMotion)
-		api.POST("/catch_motion", robotHandler.CatchMotion)
-		api.POST("/release_motion", robotHandler.ReleaseMotion)
-		api.POST("/reset_motion", robotHandler.ResetMotion)
-		api.POST("/add_down_motion", robotHandler.AddDownMotion)
-		api.POST("/add_up_motion", robotHandler.AddUpMotion)
-		api.POST("/middle_motion", robotHandler.MiddleMotion)
+		viewer.GET("/auth/me", authHandler.Me)
+		viewer.POST("/auth/logout", authHandler.Logout)
+
+		viewer.GET("/joints", robotHandler.GetJoints)
+		viewer.GET("/urdf", robotHandler.GetRobotModel)
+		viewer.GET("/fk", robotHandler.GetToolPose)
+		viewer.POST("/ik", robotHandler.SolveIK) // プレビューのみで Publish しない
+		viewer.GET("/target", robotHandler.GetTarget)
+		viewer.GET("/tf", robotHandler.GetFrames)
+		viewer.GET("/tf/:from/:to", robotHandler.GetTransform)
+		viewer.GET("/topics", robotHandler.GetTopics)
+		viewer.GET("/control", controlHandler.GetControl)
+		viewer.GET("/jog", robotHandler.GetJogStatus)
+		viewer.GET("/estop", robotHandler.GetEStop)
+		viewer.GET("/joy", robotHandler.GetJoy)
+		viewer.GET("/logs", robotHandler.GetLogs)
+		viewer.GET("/logs/stream", robotHandler.StreamLogs)
+		viewer.GET("/diagnostics", robotHandler.GetDiagnostics)
+		viewer.GET("/diagnostics/stream", robotHandler.StreamDiagnostics)
+		viewer.GET("/nodes", robotHandler.GetNodes)
+		viewer.GET("/graph", robotHandler.GetGraph)
+		viewer.GET("/nodes/:node/params", robotHandler.GetParams)
+		viewer.GET("/lifecycle", robotHandler.GetLifecycleNodes)
+		viewer.GET("/controllers", robotHandler.GetControllers)
+		viewer.GET("/controllers/hardware", robotHandler.GetHardwareComponents)
+		viewer.GET("/match", robotHandler.GetMatch)
+		viewer.GET("/match/stream", robotHandler.StreamMatch)
+		viewer.GET("/matches", robotHandler.GetMatchRecords)
+		viewer.GET("/matches/:id", robotHandler.GetMatchRecord)
+		viewer.GET("/matches/:id/events", robotHandler.GetMatchEvents)
+		viewer.GET("/analytics", robotHandler.GetAnalytics)
+		viewer.GET("/analytics/cells", robotHandler.GetCellAnalytics)
+		viewer.GET("/analytics/sessions", robotHandler.GetSessionAnalytics)
+		viewer.GET("/field", robotHandler.GetField)
+		viewer.POST("/plan", robotHandler.PlanPicks) // プレビューのみで Publish しない
+		viewer.GET("/plan/execution", robotHandler.GetPlanExecution)
 
 		// ---- Camera ----
-		api.GET("/camera/snapshot", robotHandler.CameraSnapshot)
-		api.GET("/camera/mjpeg", robotHandler.CameraMJPEG)
+		viewer.GET("/camera/snapshot", robotHandler.CameraSnapshot)
+		viewer.GET("/camera/mjpeg", robotHandler.CameraMJPEG)
+	}
+
+	// ---- operator: 操作権と指令 ----
+	operator := api.Group("", requireRole(auth.RoleOperator))
+	{
+		// force は admin のみ（ハンドラ内で確認）
+		operator.POST("/control/take", controlHandler.TakeControl)
+		operator.POST("/control/release", controlHandler.ReleaseControl)
+		// 停止は安全側なので操作権が無くても受け付ける
+		operator.POST("/jog/stop", robotHandler.StopJog)
+		operator.POST("/estop", robotHandler.EngageEStop)
+		// 試合の時計は操作権が無くても進められる（審判役の端末から操作するため）
+		operator.POST("/match/start", robotHandler.StartMatch)
+		operator.POST("/match/pause", robotHandler.PauseMatch)
+		operator.POST("/match/reset", robotHandler.ResetMatch)
+		// 試技の記録も同じく操作権が無くても付けられる
+		operator.POST("/matches", robotHandler.BeginMatchRecord)
+		operator.POST("/matches/current/end", robotHandler.EndMatchRecord)
+		operator.POST("/matches/current/attempts", robotHandler.AddAttempt)
+		operator.PUT("/matches/current/attempts/:n", robotHandler.MarkAttempt)
+		// 計画の実行をやめるのは安全側なので操作権が無くても受け付ける
+		operator.POST("/plan/abort", robotHandler.AbortPlan)
+
+		// ---- Commands（操作権を持つクライアントのみ） ----
+		cmd := operator.Group("", rejectWhenShuttingDown(rc), requireControl(lock))
+		cmd.POST("/position", robotHandler.SendPositionCommand)
+		cmd.POST("/move", robotHandler.SendDisplacementCommand)
+		cmd.POST("/joint_angles", robotHandler.SendJointAngles)
+		cmd.POST("/start_motion", robotHandler.StartMotion)
+		cmd.POST("/down_motion", robotHandler.DownMotion)
+		cmd.POST("/up_motion", robotHandler.UpMotion)
+		cmd.POST("/catch_motion", robotHandler.CatchMotion)
+		cmd.POST("/release_motion", robotHandler.ReleaseMotion)
+		cmd.POST("/reset_motion", robotHandler.ResetMotion)
+		cmd.POST("/add_down_motion", robotHandler.AddDownMotion)
+		cmd.POST("/add_up_motion", robotHandler.AddUpMotion)
+		cmd.POST("/middle_motion", robotHandler.MiddleMotion)
+		cmd.GET("/jog/ws", robotHandler.JogWebSocket)
+		// パラメータの変更は設定なので admin のみ（読むのは viewer から）
+		cmd.PUT("/nodes/:node/params", requireRole(auth.RoleAdmin), robotHandler.SetParams)
+		cmd.POST("/lifecycle/:node/transition", robotHandler.TransitionLifecycleNode)
+		cmd.POST("/controllers/load", robotHandler.LoadController)
+		cmd.POST("/controllers/switch", robotHandler.SwitchControllers)
+		cmd.POST("/controllers/:name/configure", robotHandler.ConfigureController)
+		cmd.POST("/plan/execute", robotHandler.ExecutePlan)
+		cmd.POST("/plan/next", robotHandler.NextPlanStep)
+	}
+
+	// ---- admin: 設定・監査 ----
+	admin := api.Group("/admin", requireRole(auth.RoleAdmin))
+	{
+		admin.GET("/ratelimit", adminHandler.GetRateLimits)
+		admin.GET("/users", authHandler.GetUsers)
+		admin.GET("/audit", authHandler.GetAudit)
+		admin.GET("/log/levels", logHandler.GetLogLevels)
+		admin.PUT("/log/levels", logHandler.SetLogLevels)
+		// 非常停止の解除は admin のみ（掛けるのは operator から）
+		admin.POST("/estop/clear", robotHandler.ClearEStop)
 	}
 
 	r.GET("/api/hello", robotHandler.Hello)
 
+	// docker-compose や試合前のスクリプトから使うので認証しない
+	r.GET("/healthz", robotHandler.Healthz)
+	r.GET("/readyz", robotHandler.Readyz)
+	// 練習中にローカルの Prometheus から取得する
+	r.GET("/metrics", Metrics)
+
 	return r
 }
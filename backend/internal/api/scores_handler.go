@@ -0,0 +1,160 @@
+// internal/api/scores_handler.go
+package api
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+
+	"catchrobo_app/internal/score"
+
+	"github.com/gin-gonic/gin"
+)
+
+// BeginRecordReq は POST /api/matches の本文です
+type BeginRecordReq struct {
+	Kind string `json:"kind"` // match（既定）/ practice
+	Side string `json:"side"` // blue / red（空なら最後に使ったサイド）
+}
+
+// respondScoreError は記録まわりのエラーを返します
+func respondScoreError(c *gin.Context, msg string, err error) {
+	switch {
+	case errors.Is(err, score.ErrNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": msg, "detail": err.Error()})
+	case errors.Is(err, score.ErrInvalid):
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg, "detail": err.Error()})
+	case errors.Is(err, score.ErrNoRecord), errors.Is(err, score.ErrAttemptPending):
+		c.JSON(http.StatusConflict, gin.H{"error": msg, "detail": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": msg, "detail": err.Error()})
+	}
+}
+
+// GetMatchRecords は試合・練習の記録の一覧（新しい順）と、新しく始める記録に使う得点のルールを返します
+func (h *RobotHandler) GetMatchRecords(c *gin.Context) {
+	scores := h.controller.Scores()
+	res := gin.H{"records": scores.List(), "rules": scores.Rules()}
+	if cur, ok := scores.Current(); ok {
+		res["current"] = cur.ID
+	}
+	c.JSON(http.StatusOK, res)
+}
+
+// GetMatchRecord は記録（試技と出来事）を返します。id が current なら記録中のもの
+func (h *RobotHandler) GetMatchRecord(c *gin.Context) {
+	rec, err := h.controller.Scores().Get(c.Param("id"))
+	if err != nil {
+		respondScoreError(c, "get match record failed", err)
+		return
+	}
+	c.JSON(http.StatusOK, rec)
+}
+
+// GetMatchEvents は記録の出来事を返します。?format=csv で公式の得点表と突き合わせるための CSV
+func (h *RobotHandler) GetMatchEvents(c *gin.Context) {
+	format := c.DefaultQuery("format", "json")
+	if format != "json" && format != "csv" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid format", "detail": "format must be json or csv"})
+		return
+	}
+	rec, err := h.controller.Scores().Get(c.Param("id"))
+	if err != nil {
+		respondScoreError(c, "get match events failed", err)
+		return
+	}
+	if format == "csv" {
+		var buf bytes.Buffer
+		if err := rec.WriteCSV(&buf); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "write csv failed", "detail": err.Error()})
+			return
+		}
+		c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="match-%s.csv"`, rec.ID))
+		c.Data(http.StatusOK, "text/csv; charset=utf-8", buf.Bytes())
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"id": rec.ID, "score": rec.Score, "events": rec.Events})
+}
+
+// BeginMatchRecord は記録を始めます（記録中のものは終える）
+// 試合の記録は /api/match/start でも自動で始まる。サイドを変えるときは先にこれで始めておく
+func (h *RobotHandler) BeginMatchRecord(c *gin.Context) {
+	var req BeginRecordReq
+	// 本文は省略できる（試合の記録を最後に使ったサイドで始める）
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid record json", "detail": err.Error()})
+		return
+	}
+	if req.Kind == "" {
+		req.Kind = score.KindMatch
+	}
+	setAudit(c, "record_begin", fmt.Sprintf("kind=%s side=%s", req.Kind, req.Side))
+	rec, err := h.controller.Scores().Begin(req.Kind, req.Side)
+	if err != nil {
+		respondScoreError(c, "begin record failed", err)
+		return
+	}
+	c.JSON(http.StatusOK, rec)
+}
+
+// EndMatchRecord は記録中のものを終えます
+func (h *RobotHandler) EndMatchRecord(c *gin.Context) {
+	rec, err := h.controller.Scores().End("ended by operator")
+	if err != nil {
+		respondScoreError(c, "end record failed", err)
+		return
+	}
+	setAudit(c, "record_end", fmt.Sprintf("record=%s score=%d", rec.ID, rec.Score))
+	c.JSON(http.StatusOK, rec.Summary())
+}
+
+// AddAttempt は記録中のものに試技（取ったセルと置いたスロット）を追加します
+// success を付けるとその場で成否まで記録し、付けなければ PUT .../attempts/{n} で後から付ける
+// 後から記録するときは start / end（RFC3339）に実際の時刻を付ける
+func (h *RobotHandler) AddAttempt(c *gin.Context) {
+	var req score.AttemptReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid attempt json", "detail": err.Error()})
+		return
+	}
+	a, err := h.controller.Scores().StartAttempt(req)
+	if err != nil {
+		respondScoreError(c, "add attempt failed", err)
+		return
+	}
+	h.respondAttempt(c, a)
+}
+
+// MarkAttempt は記録中のものの試技に成否を付けます（付け直すと点数も直る）
+func (h *RobotHandler) MarkAttempt(c *gin.Context) {
+	n, err := strconv.Atoi(c.Param("n"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid attempt number", "detail": err.Error()})
+		return
+	}
+	var req score.ResultReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid result json", "detail": err.Error()})
+		return
+	}
+	setAudit(c, "attempt_result", fmt.Sprintf("attempt=%d success=%t", n, *req.Success))
+	a, err := h.controller.Scores().FinishAttempt(n, req)
+	if err != nil {
+		respondScoreError(c, "mark attempt failed", err)
+		return
+	}
+	h.respondAttempt(c, a)
+}
+
+// respondAttempt は試技と記録中のものの合計点を返します
+func (h *RobotHandler) respondAttempt(c *gin.Context, a score.Attempt) {
+	res := gin.H{"attempt": a}
+	if cur, ok := h.controller.Scores().Current(); ok {
+		res["record"] = cur.ID
+		res["score"] = cur.Score
+	}
+	c.JSON(http.StatusOK, res)
+}
@@ -0,0 +1,56 @@
+// internal/api/sse.go
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// sseHeartbeat はプロキシやブラウザに切られないように送るコメント行の間隔です
+const sseHeartbeat = 15 * time.Second
+
+// startSSE は Server-Sent Events の応答ヘッダを書きます。Flush できなければ 500 を返して false
+func startSSE(c *gin.Context) (http.Flusher, bool) {
+	flusher, ok := c.Writer.(http.Flusher)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "streaming is not supported"})
+		return nil, false
+	}
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-store")
+	c.Header("X-Accel-Buffering", "no") // nginx でバッファされないように
+	c.Status(http.StatusOK)
+	flusher.Flush()
+	return flusher, true
+}
+
+// writeSSE は1イベントを書きます。id が空なら id 行を省く（data は JSON）
+func writeSSE(c *gin.Context, flusher http.Flusher, event, id string, data any) error {
+	b, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	if id != "" {
+		if _, err := fmt.Fprintf(c.Writer, "id: %s\n", id); err != nil {
+			return err
+		}
+	}
+	if _, err := fmt.Fprintf(c.Writer, "event: %s\ndata: %s\n\n", event, b); err != nil {
+		return err
+	}
+	flusher.Flush()
+	return nil
+}
+
+// writeSSEHeartbeat はコメント行を書きます
+func writeSSEHeartbeat(c *gin.Context, flusher http.Flusher) error {
+	if _, err := fmt.Fprint(c.Writer, ": ping\n\n"); err != nil {
+		return err
+	}
+	flusher.Flush()
+	return nil
+}
@@ -0,0 +1,276 @@
+// internal/auth/auth.go
+package auth
+
+// authはginに依存しないように書く（ミドルウェアは api パッケージ側）
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+	"gopkg.in/yaml.v3"
+)
+
+var (
+	// ErrInvalidCredentials はユーザー名・パスワード・トークンが正しくない場合に返ります
+	ErrInvalidCredentials = errors.New("invalid credentials")
+	// ErrUnknownRole は viewer / operator / admin 以外のロールが指定された場合に返ります
+	ErrUnknownRole = errors.New("unknown role")
+)
+
+// Role はできることの範囲です。admin は operator の、operator は viewer の権限を含みます
+type Role string
+
+const (
+	RoleViewer   Role = "viewer"   // カメラと状態の取得のみ
+	RoleOperator Role = "operator" // モーションなどの指令
+	RoleAdmin    Role = "admin"    // 設定・操作権の強制取得など
+)
+
+func (r Role) level() int {
+	switch r {
+	case RoleViewer:
+		return 1
+	case RoleOperator:
+		return 2
+	case RoleAdmin:
+		return 3
+	}
+	return 0
+}
+
+// Valid は既知のロールなら true を返します
+func (r Role) Valid() bool { return r.level() > 0 }
+
+// Allows は r が min 以上の権限を持っていれば true を返します
+func (r Role) Allows(min Role) bool { return r.level() >= min.level() }
+
+// Identity は認証されたリクエスト元です
+type Identity struct {
+	User   string `json:"user"`
+	Role   Role   `json:"role"`
+	Method string `json:"method"` // session / token:<トークン名> / anonymous
+}
+
+// 認証方法
+const (
+	MethodSession   = "session"
+	MethodToken     = "token"
+	MethodAnonymous = "anonymous"
+)
+
+// APIToken はスクリプトや常設端末用のトークンです。トークン自体は保存せず SHA-256 だけを持つ
+type APIToken struct {
+	Name string `yaml:"name" json:"name"`
+	Hash string `yaml:"sha256" json:"-"`
+}
+
+// User はユーザーファイルの1人分です。パスワードは bcrypt のハッシュだけを持つ
+type User struct {
+	Name         string     `yaml:"name" json:"name"`
+	Role         Role       `yaml:"role" json:"role"`
+	PasswordHash string     `yaml:"password_bcrypt,omitempty" json:"-"`
+	Tokens       []APIToken `yaml:"tokens,omitempty" json:"tokens,omitempty"`
+}
+
+type usersFile struct {
+	Users []User `yaml:"users"`
+}
+
+// Store はユーザーファイルとログインセッションです
+type Store struct {
+	path       string
+	sessionTTL time.Duration
+
+	mu       sync.RWMutex
+	users    map[string]*User
+	tokens   map[string]*tokenOwner // SHA-256 → 持ち主
+	sessions map[string]*session    // SHA-256 → セッション
+}
+
+type tokenOwner struct {
+	user string
+	name string
+}
+
+type session struct {
+	user    string
+	expires time.Time
+}
+
+// LoadStore は path のユーザーファイルを読み込みます
+func LoadStore(path string, sessionTTL time.Duration) (*Store, error) {
+	s := &Store{path: path, sessionTTL: sessionTTL, sessions: map[string]*session{}}
+	users, err := ReadUsers(path)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.setUsers(users); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return s, nil
+}
+
+// ReadUsers はユーザーファイルを読み込みます
+func ReadUsers(path string) ([]User, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var f usersFile
+	if err := yaml.Unmarshal(b, &f); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+	return f.Users, nil
+}
+
+// WriteUsers はユーザーファイルを書き込みます（ハッシュしか含まないが他人に読ませないよう 0600）
+func WriteUsers(path string, users []User) error {
+	b, err := yaml.Marshal(usersFile{Users: users})
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, b, 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+func (s *Store) setUsers(users []User) error {
+	byName := map[string]*User{}
+	tokens := map[string]*tokenOwner{}
+	for i := range users {
+		u := &users[i]
+		if u.Name == "" {
+			return fmt.Errorf("users[%d]: empty name", i)
+		}
+		if !u.Role.Valid() {
+			return fmt.Errorf("user %q: %w %q", u.Name, ErrUnknownRole, u.Role)
+		}
+		if _, dup := byName[u.Name]; dup {
+			return fmt.Errorf("user %q: duplicated", u.Name)
+		}
+		byName[u.Name] = u
+		for _, t := range u.Tokens {
+			tokens[t.Hash] = &tokenOwner{user: u.Name, name: t.Name}
+		}
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.users, s.tokens = byName, tokens
+	return nil
+}
+
+// Login はユーザー名とパスワードを確認してセッショントークンを発行します
+func (s *Store) Login(name, password string) (token string, id Identity, expires time.Time, err error) {
+	s.mu.RLock()
+	u := s.users[name]
+	s.mu.RUnlock()
+	if u == nil || u.PasswordHash == "" {
+		// ユーザーの有無で応答時間が変わらないようにダミーと比較する
+		_ = bcrypt.CompareHashAndPassword(dummyHash, []byte(password))
+		return "", Identity{}, time.Time{}, ErrInvalidCredentials
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(u.PasswordHash), []byte(password)); err != nil {
+		return "", Identity{}, time.Time{}, ErrInvalidCredentials
+	}
+	token, err = NewToken()
+	if err != nil {
+		return "", Identity{}, time.Time{}, err
+	}
+	now := time.Now()
+	expires = now.Add(s.sessionTTL)
+	s.mu.Lock()
+	s.pruneLocked(now)
+	s.sessions[HashToken(token)] = &session{user: u.Name, expires: expires}
+	s.mu.Unlock()
+	return token, Identity{User: u.Name, Role: u.Role, Method: MethodSession}, expires, nil
+}
+
+// pruneLocked は期限の切れたセッションを消します（呼び出し側で mu を保持）
+// 期限切れのトークンが二度と使われなくても、ログインのたびに消すのでセッションが溜まり続けない
+func (s *Store) pruneLocked(now time.Time) {
+	for h, ss := range s.sessions {
+		if now.After(ss.expires) {
+			delete(s.sessions, h)
+		}
+	}
+}
+
+// Logout はセッションを無効にします
+func (s *Store) Logout(token string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.sessions, HashToken(token))
+}
+
+// Authenticate はセッショントークンまたは API トークンからリクエスト元を特定します
+func (s *Store) Authenticate(token string) (Identity, error) {
+	if token == "" {
+		return Identity{}, ErrInvalidCredentials
+	}
+	h := HashToken(token)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if ss := s.sessions[h]; ss != nil {
+		if time.Now().After(ss.expires) {
+			delete(s.sessions, h)
+			return Identity{}, ErrInvalidCredentials
+		}
+		if u := s.users[ss.user]; u != nil {
+			return Identity{User: u.Name, Role: u.Role, Method: MethodSession}, nil
+		}
+	}
+	if t := s.tokens[h]; t != nil {
+		if u := s.users[t.user]; u != nil {
+			return Identity{User: u.Name, Role: u.Role, Method: MethodToken + ":" + t.name}, nil
+		}
+	}
+	return Identity{}, ErrInvalidCredentials
+}
+
+// Users はユーザー一覧（ハッシュを除く）を名前順に返します
+func (s *Store) Users() []User {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	out := make([]User, 0, len(s.users))
+	for _, u := range s.users {
+		out = append(out, *u)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
+}
+
+// HashPassword はパスワードを bcrypt でハッシュします
+func HashPassword(password string) (string, error) {
+	b, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	return string(b), err
+}
+
+// NewToken はランダムなトークンを作ります（セッション・API トークン共通）
+func NewToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// HashToken はトークンの保存・照合用のハッシュです（トークンは十分長い乱数なので bcrypt は不要）
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+var dummyHash, _ = bcrypt.GenerateFromPassword([]byte("catchrobo-dummy"), bcrypt.DefaultCost)
@@ -0,0 +1,345 @@
+// internal/config/config.go
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"catchrobo_app/internal/diagnostics"
+	"catchrobo_app/internal/geom"
+	"catchrobo_app/internal/jog"
+	"catchrobo_app/internal/joint"
+	"catchrobo_app/internal/kinematics"
+	"catchrobo_app/internal/logging"
+	"catchrobo_app/internal/match"
+	"catchrobo_app/internal/planner"
+	"catchrobo_app/internal/ratelimit"
+	"catchrobo_app/internal/rosout"
+	"catchrobo_app/internal/score"
+
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultPath は設定ファイルの既定の場所です（環境変数 CATCHROBO_CONFIG で上書きできる）
+const DefaultPath = "config.yaml"
+
+// Config はバックエンド全体の設定です
+type Config struct {
+	// 関節の並び順と制限（/api/joint_angles の検証と /api/joints に使う）
+	Joints []joint.Limit `yaml:"joints"`
+	// 計測関節角度（sensor_msgs/JointState）のトピック
+	JointStatesTopic string `yaml:"joint_states_topic"`
+	// アームの URDF の読み込み元と順運動学に使うチェーン
+	URDF URDFConfig `yaml:"urdf"`
+	// URDF の代わりに設定で書いたチェーン（joints があれば URDF より優先する）
+	Chain ChainConfig `yaml:"chain"`
+	// 数値逆運動学（到達可能性チェックと関節角度への変換）
+	IK IKConfig `yaml:"ik"`
+	// WebSocket からの速度ジョグ（TwistStamped）とデッドマン
+	Jog jog.Config `yaml:"jog"`
+	// ゲームパッド（sensor_msgs/Joy）の購読と割り当てファイル
+	Joy JoyConfig `yaml:"joy"`
+	// 連打・押しっぱなしで指令が溜まらないようにする制限
+	RateLimit RateLimitConfig `yaml:"rate_limit"`
+	// 操作権（1人のオペレーターだけが指令を送れるようにする）
+	Control ControlConfig `yaml:"control"`
+	// 認証とロール（viewer / operator / admin）
+	Auth AuthConfig `yaml:"auth"`
+	// 状態を変えたリクエストとログインを誰が行ったかの記録
+	Audit AuditConfig `yaml:"audit"`
+	// SIGINT / SIGTERM を受けたときの停止手順
+	Shutdown ShutdownConfig `yaml:"shutdown"`
+	// /readyz の判定基準
+	Health HealthConfig `yaml:"health"`
+	// ログの形式とコンポーネントごとのレベル
+	Log LogConfig `yaml:"log"`
+	// 全ノードのログ（/rosout）を /api/logs で見るためのバッファ
+	Rosout RosoutConfig `yaml:"rosout"`
+	// モータードライバーやカメラの診断（/diagnostics）
+	Diagnostics DiagnosticsConfig `yaml:"diagnostics"`
+	// 他ノードのサービス呼び出し（パラメータの取得・変更など）
+	Services ServicesConfig `yaml:"services"`
+	// ros2_control のコントローラーの切り替え
+	ControllerManager ControllerManagerConfig `yaml:"controller_manager"`
+	// 試合の時間（セッティング・競技）と、試合の状態による指令の制限
+	Match match.Config `yaml:"match"`
+	// 試技（取って置くまで）の記録と得点のルール
+	Score ScoreConfig `yaml:"score"`
+	// フィールドのセルの座標（取る順番の計画に使う）
+	Field planner.Field `yaml:"field"`
+	// 取る順番の計画の速さの仮定と、実行するときの待ち方
+	Planner planner.Config `yaml:"planner"`
+}
+
+// ScoreConfig は試技の記録と得点の設定です
+type ScoreConfig struct {
+	Dir   string      `yaml:"dir"`  // 試合・練習ごとの記録（JSON）の保存先（空なら保存しない）
+	Side  string      `yaml:"side"` // 試合の開始で自動で記録を始めるときのサイド（blue / red）
+	Rules score.Rules `yaml:"rules"`
+}
+
+// ControllerManagerConfig は ros2_control の controller_manager の設定です
+type ControllerManagerConfig struct {
+	Node string `yaml:"node"` // controller_manager のノード名
+}
+
+// ServicesConfig は他ノードのサービス呼び出しの設定です
+type ServicesConfig struct {
+	TimeoutS float64 `yaml:"timeout_s"` // 1回の呼び出しで応答を待つ上限
+}
+
+// Timeout は TimeoutS を time.Duration で返します
+func (c ServicesConfig) Timeout() time.Duration {
+	return time.Duration(c.TimeoutS * float64(time.Second))
+}
+
+// DiagnosticsConfig は /diagnostics の購読の設定です
+type DiagnosticsConfig struct {
+	Topic       string  `yaml:"topic"`         // 空なら購読しない
+	StaleAfterS float64 `yaml:"stale_after_s"` // この時間更新の無い項目を stale にする
+}
+
+// StaleAfter は StaleAfterS を time.Duration で返します
+func (c DiagnosticsConfig) StaleAfter() time.Duration {
+	return time.Duration(c.StaleAfterS * float64(time.Second))
+}
+
+// RosoutConfig は /rosout の購読の設定です
+type RosoutConfig struct {
+	Topic      string `yaml:"topic"`       // 空なら購読しない
+	BufferSize int    `yaml:"buffer_size"` // 残す件数
+}
+
+// LogConfig はログの設定です。レベルは /api/admin/log/levels で起動中にも変えられる
+type LogConfig struct {
+	logging.Config `yaml:",inline"`
+	// rcl のロガーの標準出力。false なら ROS のログは /rosout にだけ出し、標準出力は JSON のログだけにする
+	ROSStdout bool `yaml:"ros_stdout"`
+}
+
+// HealthConfig は /readyz で準備完了とみなす条件です
+type HealthConfig struct {
+	MaxCameraAgeS      float64  `yaml:"max_camera_age_s"`      // いずれかのカメラのフレームがこれより新しいこと
+	MaxJointStateAgeS  float64  `yaml:"max_joint_state_age_s"` // 計測関節角度がこれより新しいこと
+	RequireCamera      bool     `yaml:"require_camera"`
+	RequireJointStates bool     `yaml:"require_joint_states"`
+	OptionalTopics     []string `yaml:"optional_topics"` // Subscriber が居なくてもよい指令トピック
+}
+
+// ShutdownConfig は停止手順の設定です
+type ShutdownConfig struct {
+	DrainTimeoutS float64     `yaml:"drain_timeout_s"` // 処理中のリクエストを待つ上限
+	Park          *ParkConfig `yaml:"park"`            // 省略すると駐機姿勢を送らない
+}
+
+// DrainTimeout は DrainTimeoutS を time.Duration で返します
+func (c ShutdownConfig) DrainTimeout() time.Duration {
+	return time.Duration(c.DrainTimeoutS * float64(time.Second))
+}
+
+// ParkConfig は停止時に送る駐機姿勢です
+type ParkConfig struct {
+	Position geom.Vec3  `yaml:"position"`
+	RPYDeg   *geom.Vec3 `yaml:"rpy_deg"`   // 省略すると現在の目標姿勢の向きを維持
+	FrameId  string     `yaml:"frame_id"`  // 省略すると base_link
+	SettleMs int        `yaml:"settle_ms"` // 送ってからノードを閉じるまで待つ時間
+}
+
+// AuthConfig は認証の設定です。users_file は cmd/useradd で作る
+type AuthConfig struct {
+	Enabled     bool    `yaml:"enabled"`
+	UsersFile   string  `yaml:"users_file"`
+	SessionTTLH float64 `yaml:"session_ttl_h"` // ログインで発行したトークンの有効時間
+}
+
+// SessionTTL は SessionTTLH を time.Duration で返します
+func (c AuthConfig) SessionTTL() time.Duration {
+	return time.Duration(c.SessionTTLH * float64(time.Hour))
+}
+
+// AuditConfig は監査ログの設定です
+type AuditConfig struct {
+	File string `yaml:"file"` // JSON Lines。空なら記録しない
+}
+
+// ControlConfig は操作権の設定です
+type ControlConfig struct {
+	IdleTimeoutS float64 `yaml:"idle_timeout_s"` // この時間操作が無ければ操作権を自動で解放する
+}
+
+// IdleTimeout は IdleTimeoutS を time.Duration で返します
+func (c ControlConfig) IdleTimeout() time.Duration {
+	return time.Duration(c.IdleTimeoutS * float64(time.Second))
+}
+
+// RateLimitConfig はエンドポイント（"POST /api/catch_motion" の形）とトピックごとの制限です
+// エンドポイントの制限は HTTP だけに、トピックの制限はゲームパッドなど全ての経路の Publish にかかります
+type RateLimitConfig struct {
+	Endpoints map[string]ratelimit.Rule `yaml:"endpoints"`
+	Topics    map[string]ratelimit.Rule `yaml:"topics"`
+}
+
+// JoyConfig はゲームパッド入力の設定です。割り当ては mapping_file に書き、起動中に書き換えると読み直します
+type JoyConfig struct {
+	Topic       string         `yaml:"topic"` // 空なら購読しない
+	MappingFile string         `yaml:"mapping_file"`
+	Side        string         `yaml:"side"`  // セル送り（next_cell / prev_cell）で使うサイド（blue / red）
+	Cells       JoyCellsConfig `yaml:"cells"` // セル送りで順に送るセルの座標
+}
+
+// JoyCellsConfig はセル送りのセルの座標（指令のフレーム、青基準）です。赤は x を反転し、列の並びも逆にする
+// セル番号は 1 始まりで 行*列数+列+1（画面のブロックの番号と同じ）
+type JoyCellsConfig struct {
+	RowsX []float64 `yaml:"rows_x"` // 行ごとの x [m]
+	ColsY []float64 `yaml:"cols_y"` // 列ごとの y [m]
+	Z     float64   `yaml:"z"`
+}
+
+// Count はセルの数です
+func (c JoyCellsConfig) Count() int {
+	return len(c.RowsX) * len(c.ColsY)
+}
+
+// Position はサイドのセル番号の座標を返します
+func (c JoyCellsConfig) Position(side string, cell int) (geom.Vec3, error) {
+	if cell < 1 || cell > c.Count() {
+		return geom.Vec3{}, fmt.Errorf("joy cell %d is out of 1..%d", cell, c.Count())
+	}
+	row, col := (cell-1)/len(c.ColsY), (cell-1)%len(c.ColsY)
+	switch side {
+	case "blue":
+		return geom.Vec3{X: c.RowsX[row], Y: c.ColsY[col], Z: c.Z}, nil
+	case "red":
+		return geom.Vec3{X: -c.RowsX[row], Y: c.ColsY[len(c.ColsY)-1-col], Z: c.Z}, nil
+	}
+	return geom.Vec3{}, fmt.Errorf("joy side must be blue or red, got %q", side)
+}
+
+// ChainConfig は設定で直接書く運動学チェーンです
+type ChainConfig struct {
+	Base   string                 `yaml:"base"`
+	Tip    string                 `yaml:"tip"`
+	Joints []kinematics.JointSpec `yaml:"joints"`
+}
+
+// IKConfig は逆運動学の使い方です
+type IKConfig struct {
+	CheckReachability bool                 `yaml:"check_reachability"` // 届かない目標を Publish 前に拒否する
+	SendAsJoints      bool                 `yaml:"send_as_joints"`     // 目標姿勢を関節角度に変換して joint_angles で送る
+	Options           kinematics.IKOptions `yaml:",inline"`
+}
+
+// URDFConfig は URDF の読み込み元です。File が空でなければファイルを優先します
+type URDFConfig struct {
+	File      string `yaml:"file"`
+	Topic     string `yaml:"topic"`      // std_msgs/String（transient local）
+	ParamNode string `yaml:"param_node"` // robot_description パラメータを持つノード
+	BaseLink  string `yaml:"base_link"`
+	TipLink   string `yaml:"tip_link"` // 空なら最も深い末端リンク
+}
+
+// Default は設定ファイルが無い場合の設定を返します
+func Default() *Config {
+	return &Config{
+		Joints:           joint.DefaultModel().Joints,
+		JointStatesTopic: "/joint_states",
+		URDF: URDFConfig{
+			Topic:     "/robot_description",
+			ParamNode: "/robot_state_publisher",
+			BaseLink:  "base_link",
+		},
+		IK: IKConfig{
+			CheckReachability: true,
+			Options:           kinematics.DefaultIKOptions(),
+		},
+		Jog: jog.DefaultConfig(),
+		Joy: JoyConfig{
+			Topic:       "/joy",
+			MappingFile: "joy_mapping.yaml",
+			Side:        "blue",
+			// 画面のフィールドと同じ 10行×4列
+			Cells: JoyCellsConfig{
+				RowsX: []float64{0.55, 0.45, 0.30, 0.20, 0.05, -0.05, -0.20, -0.30, -0.45, -0.55},
+				ColsY: []float64{0.497, 0.397, 0.297, 0.197},
+			},
+		},
+		RateLimit: RateLimitConfig{
+			Endpoints: defaultEndpointLimits(),
+			Topics:    defaultTopicLimits(),
+		},
+		Control: ControlConfig{IdleTimeoutS: 120},
+		Auth: AuthConfig{
+			UsersFile:   "data/users.yaml",
+			SessionTTLH: 12,
+		},
+		Audit:    AuditConfig{File: "data/audit.log"},
+		Shutdown: ShutdownConfig{DrainTimeoutS: 5},
+		Health: HealthConfig{
+			MaxCameraAgeS:      2,
+			MaxJointStateAgeS:  1,
+			RequireCamera:      true,
+			RequireJointStates: true,
+			OptionalTopics:     []string{"/arm_move/joint_angles", "/arm_move/jog_twist"},
+		},
+		Log:    LogConfig{Config: logging.Config{Level: "info", Format: "json"}},
+		Rosout: RosoutConfig{Topic: "/rosout", BufferSize: rosout.DefaultCapacity},
+		Diagnostics: DiagnosticsConfig{
+			Topic:       "/diagnostics",
+			StaleAfterS: diagnostics.DefaultStaleAfter.Seconds(),
+		},
+		Services:          ServicesConfig{TimeoutS: 10},
+		ControllerManager: ControllerManagerConfig{Node: "/controller_manager"},
+		Match:             match.DefaultConfig(),
+		Score:             ScoreConfig{Dir: "data/matches", Side: score.SideBlue, Rules: score.DefaultRules()},
+		Field:             planner.DefaultField(),
+		Planner:           planner.DefaultConfig(),
+	}
+}
+
+// defaultEndpointLimits は認証なしで受け付けるログインの総当たりを抑える既定の制限です（1回ごとに bcrypt を計算するため）
+func defaultEndpointLimits() map[string]ratelimit.Rule {
+	return map[string]ratelimit.Rule{
+		"POST /api/auth/login": {Rate: 0.5, Burst: 5},
+	}
+}
+
+// defaultTopicLimits はモーション指令の連打と目標姿勢の送りすぎを抑える既定の制限です
+func defaultTopicLimits() map[string]ratelimit.Rule {
+	limits := map[string]ratelimit.Rule{
+		"/arm_move/goal_pose":    {Rate: 20, Burst: 5},
+		"/arm_move/joint_angles": {Rate: 20, Burst: 5},
+	}
+	for _, m := range []string{"start", "reset", "catch", "release", "up", "down", "add_up", "add_down", "middle"} {
+		limits["/arm_move/"+m+"_motion"] = ratelimit.Rule{DebounceMs: 300}
+	}
+	return limits
+}
+
+// Load は path の YAML を読み込みます。ファイルが無ければ既定値を返します
+func Load(path string) (*Config, error) {
+	cfg := Default()
+	b, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return cfg, nil
+		}
+		return nil, fmt.Errorf("read config: %w", err)
+	}
+	if err := yaml.Unmarshal(b, cfg); err != nil {
+		return nil, fmt.Errorf("parse config %s: %w", path, err)
+	}
+	if err := cfg.JointModel().Check(); err != nil {
+		return nil, fmt.Errorf("config %s: joints: %w", path, err)
+	}
+	if err := cfg.Jog.Check(); err != nil {
+		return nil, fmt.Errorf("config %s: jog: %w", path, err)
+	}
+	return cfg, nil
+}
+
+// JointModel は関節設定から joint.Model を作ります
+func (c *Config) JointModel() *joint.Model {
+	return &joint.Model{Joints: c.Joints}
+}
@@ -0,0 +1,178 @@
+// internal/control/lock.go
+package control
+
+// controlはginに依存しないように書く（クライアントの識別とミドルウェアは api パッケージ側）
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrHeld は他のクライアントが操作権を持っている場合に返ります
+	ErrHeld = errors.New("control is held by another client")
+	// ErrNotHolder は操作権を持っていないクライアント（トークンが違う場合も含む）が指令を送ったり解放しようとした場合に返ります
+	ErrNotHolder = errors.New("client does not hold control")
+	// ErrNotTaken は誰も操作権を取っていないのに指令を送ろうとした場合に返ります（先に take する）
+	ErrNotTaken = errors.New("control has not been taken")
+)
+
+// tokenBytes は操作権のトークンの長さです
+const tokenBytes = 32
+
+// DefaultIdleTimeout は操作が無いまま操作権を保持できる時間の既定値です
+const DefaultIdleTimeout = 2 * time.Minute
+
+// Status は操作権の状態です（トークンは含めない）
+type Status struct {
+	Held         bool      `json:"held"`
+	Owner        string    `json:"owner,omitempty"`
+	Name         string    `json:"name,omitempty"` // 表示用（UIで入力した名前など）
+	AcquiredAt   time.Time `json:"acquired_at"`
+	LastActivity time.Time `json:"last_activity"`
+	ExpiresAt    time.Time `json:"expires_at"` // この時刻まで操作が無ければ自動で解放される
+	IdleTimeoutS float64   `json:"idle_timeout_s"`
+	Takeovers    uint64    `json:"takeovers"` // 強制的に奪った回数
+}
+
+// Lock は1人のオペレーターだけが指令を送れるようにする操作権です
+// Take で推測できないトークンを発行し、指令にはクライアントとトークンの両方が一致することを求める
+// 一定時間操作が無ければ自動で解放されます
+type Lock struct {
+	mu          sync.Mutex
+	idleTimeout time.Duration
+	owner       string
+	token       string
+	name        string
+	acquiredAt  time.Time
+	lastActive  time.Time
+	takeovers   uint64
+	now         func() time.Time
+}
+
+// NewLock は操作権を作ります（idleTimeout が0以下なら DefaultIdleTimeout）
+func NewLock(idleTimeout time.Duration) *Lock {
+	if idleTimeout <= 0 {
+		idleTimeout = DefaultIdleTimeout
+	}
+	return &Lock{idleTimeout: idleTimeout, now: time.Now}
+}
+
+// Take は client に操作権を渡し、指令に付けるトークンを返します。他のクライアントが持っている場合は force のときだけ奪います
+// 持ち主が取り直した場合もトークンは新しくする（前のトークンは使えなくなる）
+func (l *Lock) Take(client, name string, force bool) (Status, string, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := l.now()
+	l.expire(now)
+	if l.owner != "" && l.owner != client {
+		if !force {
+			return l.status(), "", ErrHeld
+		}
+		l.takeovers++
+	}
+	token, err := newToken()
+	if err != nil {
+		return l.status(), "", err
+	}
+	if l.owner != client {
+		l.acquiredAt = now
+	}
+	l.owner, l.token, l.name, l.lastActive = client, token, name, now
+	return l.status(), token, nil
+}
+
+// Release は操作権を手放します。force なら持ち主でなくても（トークンが無くても）解放します
+func (l *Lock) Release(client, token string, force bool) (Status, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.expire(l.now())
+	if l.owner == "" {
+		return l.status(), nil
+	}
+	if !force && !l.holdsLocked(client, token) {
+		return l.status(), ErrNotHolder
+	}
+	l.clear()
+	return l.status(), nil
+}
+
+// Use は client が token で指令を送ってよいか確認し、よければ最終操作時刻を更新します
+func (l *Lock) Use(client, token string) (Status, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := l.now()
+	l.expire(now)
+	switch {
+	case l.owner == "":
+		return l.status(), ErrNotTaken
+	case l.owner != client:
+		return l.status(), ErrHeld
+	case !l.holdsLocked(client, token):
+		return l.status(), ErrNotHolder
+	}
+	l.lastActive = now
+	return l.status(), nil
+}
+
+// Holds は client が token で操作権を持っているかを返します（最終操作時刻は変えない）
+func (l *Lock) Holds(client, token string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.expire(l.now())
+	return l.holdsLocked(client, token)
+}
+
+// holdsLocked はクライアントとトークンが持ち主と一致するかを返します（呼び出し側で mu を保持）
+func (l *Lock) holdsLocked(client, token string) bool {
+	return l.owner != "" && l.owner == client && token != "" &&
+		subtle.ConstantTimeCompare([]byte(l.token), []byte(token)) == 1
+}
+
+// Status は現在の状態を返します
+func (l *Lock) Status() Status {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.expire(l.now())
+	return l.status()
+}
+
+// expire は一定時間操作の無い操作権を解放します（呼び出し側で mu を保持）
+func (l *Lock) expire(now time.Time) {
+	if l.owner != "" && now.Sub(l.lastActive) > l.idleTimeout {
+		l.clear()
+	}
+}
+
+func (l *Lock) clear() {
+	l.owner, l.token, l.name = "", "", ""
+	l.acquiredAt, l.lastActive = time.Time{}, time.Time{}
+}
+
+func (l *Lock) status() Status {
+	st := Status{
+		Held:         l.owner != "",
+		Owner:        l.owner,
+		Name:         l.name,
+		AcquiredAt:   l.acquiredAt,
+		LastActivity: l.lastActive,
+		IdleTimeoutS: l.idleTimeout.Seconds(),
+		Takeovers:    l.takeovers,
+	}
+	if st.Held {
+		st.ExpiresAt = l.lastActive.Add(l.idleTimeout)
+	}
+	return st
+}
+
+func newToken() (string, error) {
+	b := make([]byte, tokenBytes)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("generate control token: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
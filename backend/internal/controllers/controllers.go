@@ -0,0 +1,95 @@
+// internal/controllers/controllers.go
+package controllers
+
+// controllersはROSにもginにも依存しないように書く（controller_manager のサービス呼び出しは robot、HTTP は api パッケージ側）
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// エラー
+var (
+	// ErrInvalidSwitch は切り替えの指定が正しくない場合に返ります（controller_manager には送らない）
+	ErrInvalidSwitch = errors.New("invalid controller switch")
+	// ErrRejected は controller_manager が要求を受け付けなかった（ok: false）場合に返ります
+	ErrRejected = errors.New("controller manager rejected the request")
+)
+
+// 切り替えの厳しさ（controller_manager_msgs/srv/SwitchController の STRICT / BEST_EFFORT）
+const (
+	Strict     = "strict"      // 1つでも切り替えられなければ何も変えない
+	BestEffort = "best_effort" // 切り替えられるものだけ切り替える
+)
+
+// Controller は controller_manager に読み込まれたコントローラーです
+type Controller struct {
+	Name                      string   `json:"name"`
+	State                     string   `json:"state"` // unconfigured / inactive / active / finalized
+	Type                      string   `json:"type"`
+	ClaimedInterfaces         []string `json:"claimed_interfaces"`
+	RequiredCommandInterfaces []string `json:"required_command_interfaces"`
+	RequiredStateInterfaces   []string `json:"required_state_interfaces"`
+	IsChainable               bool     `json:"is_chainable"`
+	IsChained                 bool     `json:"is_chained"`
+}
+
+// Interface はハードウェアのコマンド・状態インターフェースです
+type Interface struct {
+	Name      string `json:"name"`
+	Available bool   `json:"available"`
+	Claimed   bool   `json:"claimed"`
+}
+
+// HardwareComponent は ros2_control のハードウェアコンポーネントです
+type HardwareComponent struct {
+	Name              string      `json:"name"`
+	Type              string      `json:"type"` // actuator / sensor / system
+	ClassType         string      `json:"class_type"`
+	State             string      `json:"state"` // ライフサイクルの状態（active など）
+	CommandInterfaces []Interface `json:"command_interfaces"`
+	StateInterfaces   []Interface `json:"state_interfaces"`
+}
+
+// Switch はコントローラーの切り替えの要求です
+type Switch struct {
+	Activate     []string `json:"activate"`
+	Deactivate   []string `json:"deactivate"`
+	Strictness   string   `json:"strictness"`    // strict（既定）/ best_effort
+	ActivateAsap bool     `json:"activate_asap"` // 有効化を次の周期を待たずに行う
+	TimeoutS     float64  `json:"timeout_s"`     // controller_manager 側で切り替えを待つ上限（0なら待ち続ける）
+}
+
+// Timeout は TimeoutS を time.Duration で返します
+func (s Switch) Timeout() time.Duration {
+	return time.Duration(s.TimeoutS * float64(time.Second))
+}
+
+// Normalize は空の strictness を strict にして、指定を確かめます
+func (s *Switch) Normalize() error {
+	switch strings.ToLower(s.Strictness) {
+	case "", Strict:
+		s.Strictness = Strict
+	case BestEffort, "best-effort":
+		s.Strictness = BestEffort
+	default:
+		return fmt.Errorf("%w: unknown strictness %q (strict / best_effort)", ErrInvalidSwitch, s.Strictness)
+	}
+	if len(s.Activate) == 0 && len(s.Deactivate) == 0 {
+		return fmt.Errorf("%w: nothing to activate or deactivate", ErrInvalidSwitch)
+	}
+	if s.TimeoutS < 0 {
+		return fmt.Errorf("%w: timeout_s must not be negative", ErrInvalidSwitch)
+	}
+	deactivate := map[string]bool{}
+	for _, name := range s.Deactivate {
+		deactivate[name] = true
+	}
+	for _, name := range s.Activate {
+		if deactivate[name] {
+			return fmt.Errorf("%w: %s is in both activate and deactivate", ErrInvalidSwitch, name)
+		}
+	}
+	return nil
+}
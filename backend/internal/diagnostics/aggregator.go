@@ -0,0 +1,256 @@
+// internal/diagnostics/aggregator.go
+package diagnostics
+
+// diagnosticsはROSにもginにも依存しないように書く（/diagnostics の購読は robot、HTTP と SSE は api パッケージ側）
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+)
+
+// レベル（diagnostic_msgs/DiagnosticStatus の定数と同じ値）。まとめるときは大きい方を取る
+const (
+	LevelOK    = 0
+	LevelWarn  = 1
+	LevelError = 2
+	LevelStale = 3 // staleAfter の間更新が無い
+)
+
+// DefaultStaleAfter は更新が無いと stale とみなすまでの時間の既定値です（diagnostic_aggregator と同じ）
+const DefaultStaleAfter = 5 * time.Second
+
+// maxChanges は残しておくレベル変化の件数です
+const maxChanges = 200
+
+// subscriberBuffer は購読者ごとのチャネルの長さです。溢れた分は捨てる
+const subscriberBuffer = 64
+
+// LevelName はレベルの名前を返します
+func LevelName(level int) string {
+	switch level {
+	case LevelOK:
+		return "ok"
+	case LevelWarn:
+		return "warn"
+	case LevelError:
+		return "error"
+	}
+	return "stale"
+}
+
+// Status は1つの診断項目です（hardware_id と name の組で区別する）
+type Status struct {
+	Name       string            `json:"name"`
+	HardwareID string            `json:"hardware_id"`
+	Level      int               `json:"level"` // stale なら LevelStale
+	LevelName  string            `json:"level_name"`
+	Message    string            `json:"message"`
+	Values     map[string]string `json:"values,omitempty"`
+	ReceivedAt time.Time         `json:"received_at"`
+	AgeS       float64           `json:"age_s"`
+}
+
+// Hardware は hardware_id ごとにまとめた項目です
+type Hardware struct {
+	HardwareID string   `json:"hardware_id"`
+	Level      int      `json:"level"` // 項目の中で最も悪いレベル
+	LevelName  string   `json:"level_name"`
+	Statuses   []Status `json:"statuses"`
+}
+
+// Tree は全体をまとめたものです
+type Tree struct {
+	Level       int        `json:"level"` // 全体で最も悪いレベル（項目が無ければ ok）
+	LevelName   string     `json:"level_name"`
+	Counts      [4]int     `json:"counts"` // レベルごとの項目数（ok, warn, error, stale）
+	Hardware    []Hardware `json:"hardware"`
+	StaleAfterS float64    `json:"stale_after_s"`
+	Time        time.Time  `json:"time"`
+}
+
+// Change はある項目のレベルが変わったことを表します
+type Change struct {
+	Seq        uint64    `json:"seq"`
+	Time       time.Time `json:"time"`
+	HardwareID string    `json:"hardware_id"`
+	Name       string    `json:"name"`
+	From       string    `json:"from"` // 初めて受け取った項目は空
+	To         string    `json:"to"`
+	Message    string    `json:"message"`
+}
+
+type key struct {
+	hardwareID string
+	name       string
+}
+
+// Aggregator は /diagnostics の最新の状態を項目ごとに持ち、レベルの変化を配ります
+type Aggregator struct {
+	staleAfter time.Duration
+
+	mu       sync.Mutex
+	statuses map[key]*Status
+	changes  []Change
+	seq      uint64
+	subs     map[chan Change]struct{}
+	now      func() time.Time
+}
+
+// New は staleAfter（0以下なら DefaultStaleAfter）で stale を判定する Aggregator を作ります
+func New(staleAfter time.Duration) *Aggregator {
+	if staleAfter <= 0 {
+		staleAfter = DefaultStaleAfter
+	}
+	return &Aggregator{
+		staleAfter: staleAfter,
+		statuses:   map[key]*Status{},
+		subs:       map[chan Change]struct{}{},
+		now:        time.Now,
+	}
+}
+
+// Update は受け取った DiagnosticArray の各項目で状態を更新します
+func (a *Aggregator) Update(statuses []Status) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	now := a.now()
+	for _, s := range statuses {
+		k := key{s.HardwareID, s.Name}
+		prev := a.statuses[k]
+		from := ""
+		if prev != nil {
+			from = LevelName(prev.Level)
+		}
+		if s.Level > LevelStale || s.Level < LevelOK {
+			s.Level = LevelError // 未知のレベルは error とみなす
+		}
+		s.LevelName = LevelName(s.Level)
+		s.ReceivedAt = now
+		a.statuses[k] = &s
+		if prev == nil || prev.Level != s.Level {
+			a.emit(now, s, from)
+		}
+	}
+}
+
+// CheckStale は staleAfter の間更新の無い項目を stale にします（定期的に呼ぶ）
+func (a *Aggregator) CheckStale() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	now := a.now()
+	for _, s := range a.statuses {
+		if s.Level != LevelStale && now.Sub(s.ReceivedAt) > a.staleAfter {
+			from := LevelName(s.Level)
+			s.Level, s.LevelName = LevelStale, LevelName(LevelStale)
+			a.emit(now, *s, from)
+		}
+	}
+}
+
+// Run は ctx が終わるまで1秒ごとに CheckStale を呼びます
+func (a *Aggregator) Run(ctx context.Context) {
+	t := time.NewTicker(time.Second)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+			a.CheckStale()
+		}
+	}
+}
+
+// emit はレベルの変化を記録して購読者に配ります（呼び出し側で mu を保持）
+func (a *Aggregator) emit(now time.Time, s Status, from string) {
+	a.seq++
+	c := Change{
+		Seq: a.seq, Time: now, HardwareID: s.HardwareID, Name: s.Name,
+		From: from, To: s.LevelName, Message: s.Message,
+	}
+	a.changes = append(a.changes, c)
+	if len(a.changes) > maxChanges {
+		a.changes = a.changes[len(a.changes)-maxChanges:]
+	}
+	for ch := range a.subs {
+		select {
+		case ch <- c:
+		default:
+		}
+	}
+}
+
+// Tree は hardware_id ごとにまとめた現在の状態を返します（悪いものが先、同じレベルなら名前順）
+func (a *Aggregator) Tree() Tree {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	now := a.now()
+	t := Tree{Level: LevelOK, StaleAfterS: a.staleAfter.Seconds(), Time: now}
+	byHW := map[string]*Hardware{}
+	for _, s := range a.statuses {
+		st := *s
+		st.AgeS = now.Sub(st.ReceivedAt).Seconds()
+		hw := byHW[st.HardwareID]
+		if hw == nil {
+			hw = &Hardware{HardwareID: st.HardwareID, Level: LevelOK}
+			byHW[st.HardwareID] = hw
+		}
+		hw.Statuses = append(hw.Statuses, st)
+		hw.Level = max(hw.Level, st.Level)
+		t.Level = max(t.Level, st.Level)
+		t.Counts[st.Level]++
+	}
+	for _, hw := range byHW {
+		hw.LevelName = LevelName(hw.Level)
+		sort.Slice(hw.Statuses, func(i, j int) bool {
+			si, sj := hw.Statuses[i], hw.Statuses[j]
+			if si.Level != sj.Level {
+				return si.Level > sj.Level
+			}
+			return si.Name < sj.Name
+		})
+		t.Hardware = append(t.Hardware, *hw)
+	}
+	sort.Slice(t.Hardware, func(i, j int) bool {
+		hi, hj := t.Hardware[i], t.Hardware[j]
+		if hi.Level != hj.Level {
+			return hi.Level > hj.Level
+		}
+		return hi.HardwareID < hj.HardwareID
+	})
+	if t.Hardware == nil {
+		t.Hardware = []Hardware{}
+	}
+	t.LevelName = LevelName(t.Level)
+	return t
+}
+
+// Changes は afterSeq より後のレベル変化を古い順に返します
+func (a *Aggregator) Changes(afterSeq uint64) []Change {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	out := []Change{}
+	for _, c := range a.changes {
+		if c.Seq > afterSeq {
+			out = append(out, c)
+		}
+	}
+	return out
+}
+
+// Subscribe はレベル変化を受け取るチャネルと、購読をやめる関数を返します
+func (a *Aggregator) Subscribe() (<-chan Change, func()) {
+	ch := make(chan Change, subscriberBuffer)
+	a.mu.Lock()
+	a.subs[ch] = struct{}{}
+	a.mu.Unlock()
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() {
+			a.mu.Lock()
+			delete(a.subs, ch)
+			a.mu.Unlock()
+		})
+	}
+}
@@ -0,0 +1,175 @@
+// internal/geom/geom.go
+package geom
+
+// geomはROSにもginにも依存しない幾何計算だけを置く
+import (
+	"errors"
+	"math"
+)
+
+// ErrNotFinite は NaN / Inf を含む値が渡された場合に返ります
+var ErrNotFinite = errors.New("value is NaN or Inf")
+
+// ErrZeroQuaternion はノルムが0のクォータニオンを正規化しようとした場合に返ります
+var ErrZeroQuaternion = errors.New("quaternion has zero norm")
+
+// Vec3 は3次元ベクトルです
+type Vec3 struct {
+	X float64 `json:"x"`
+	Y float64 `json:"y"`
+	Z float64 `json:"z"`
+}
+
+func (v Vec3) Add(o Vec3) Vec3 { return Vec3{v.X + o.X, v.Y + o.Y, v.Z + o.Z} }
+
+func (v Vec3) Sub(o Vec3) Vec3 { return Vec3{v.X - o.X, v.Y - o.Y, v.Z - o.Z} }
+
+func (v Vec3) Scale(s float64) Vec3 { return Vec3{v.X * s, v.Y * s, v.Z * s} }
+
+func (v Vec3) Dot(o Vec3) float64 { return v.X*o.X + v.Y*o.Y + v.Z*o.Z }
+
+func (v Vec3) Cross(o Vec3) Vec3 {
+	return Vec3{
+		v.Y*o.Z - v.Z*o.Y,
+		v.Z*o.X - v.X*o.Z,
+		v.X*o.Y - v.Y*o.X,
+	}
+}
+
+func (v Vec3) Norm() float64 { return math.Sqrt(v.Dot(v)) }
+
+// IsFinite は全要素が NaN / Inf でなければ true を返します
+func (v Vec3) IsFinite() bool {
+	return isFinite(v.X) && isFinite(v.Y) && isFinite(v.Z)
+}
+
+// Quaternion は回転を表す単位クォータニオンです（ROSと同じ x, y, z, w の順）
+type Quaternion struct {
+	X float64 `json:"x"`
+	Y float64 `json:"y"`
+	Z float64 `json:"z"`
+	W float64 `json:"w"`
+}
+
+// Identity は無回転のクォータニオンを返します
+func Identity() Quaternion { return Quaternion{W: 1} }
+
+// FromRPY は roll/pitch/yaw [rad]（固定軸 X→Y→Z の順の回転）からクォータニオンを作ります
+func FromRPY(roll, pitch, yaw float64) Quaternion {
+	cr, sr := math.Cos(roll/2), math.Sin(roll/2)
+	cp, sp := math.Cos(pitch/2), math.Sin(pitch/2)
+	cy, sy := math.Cos(yaw/2), math.Sin(yaw/2)
+	return Quaternion{
+		X: sr*cp*cy - cr*sp*sy,
+		Y: cr*sp*cy + sr*cp*sy,
+		Z: cr*cp*sy - sr*sp*cy,
+		W: cr*cp*cy + sr*sp*sy,
+	}
+}
+
+// FromAxisAngle は回転軸と角度 [rad] からクォータニオンを作ります（軸は正規化されていなくてよい）
+func FromAxisAngle(axis Vec3, angle float64) Quaternion {
+	n := axis.Norm()
+	if n == 0 {
+		return Identity()
+	}
+	s := math.Sin(angle/2) / n
+	return Quaternion{X: axis.X * s, Y: axis.Y * s, Z: axis.Z * s, W: math.Cos(angle / 2)}
+}
+
+// RPY は roll/pitch/yaw [rad] を返します
+func (q Quaternion) RPY() (roll, pitch, yaw float64) {
+	roll = math.Atan2(2*(q.W*q.X+q.Y*q.Z), 1-2*(q.X*q.X+q.Y*q.Y))
+	sp := 2 * (q.W*q.Y - q.Z*q.X)
+	if sp > 1 {
+		sp = 1
+	} else if sp < -1 {
+		sp = -1
+	}
+	pitch = math.Asin(sp)
+	yaw = math.Atan2(2*(q.W*q.Z+q.X*q.Y), 1-2*(q.Y*q.Y+q.Z*q.Z))
+	return
+}
+
+// Mul はクォータニオンの積 q*o を返します（o を回転した後に q を回転する）
+func (q Quaternion) Mul(o Quaternion) Quaternion {
+	return Quaternion{
+		X: q.W*o.X + q.X*o.W + q.Y*o.Z - q.Z*o.Y,
+		Y: q.W*o.Y - q.X*o.Z + q.Y*o.W + q.Z*o.X,
+		Z: q.W*o.Z + q.X*o.Y - q.Y*o.X + q.Z*o.W,
+		W: q.W*o.W - q.X*o.X - q.Y*o.Y - q.Z*o.Z,
+	}
+}
+
+// Conj は共役（単位クォータニオンなら逆回転）を返します
+func (q Quaternion) Conj() Quaternion { return Quaternion{-q.X, -q.Y, -q.Z, q.W} }
+
+// Rotate はベクトル v を q で回転します
+func (q Quaternion) Rotate(v Vec3) Vec3 {
+	u := Vec3{q.X, q.Y, q.Z}
+	t := u.Cross(v).Scale(2)
+	return v.Add(t.Scale(q.W)).Add(u.Cross(t))
+}
+
+func (q Quaternion) Norm() float64 {
+	return math.Sqrt(q.X*q.X + q.Y*q.Y + q.Z*q.Z + q.W*q.W)
+}
+
+// IsFinite は全要素が NaN / Inf でなければ true を返します
+func (q Quaternion) IsFinite() bool {
+	return isFinite(q.X) && isFinite(q.Y) && isFinite(q.Z) && isFinite(q.W)
+}
+
+// Normalize は単位クォータニオンを返します。NaN / Inf やノルム0は正規化できないのでエラーにします
+func (q Quaternion) Normalize() (Quaternion, error) {
+	if !q.IsFinite() {
+		return Quaternion{}, ErrNotFinite
+	}
+	n := q.Norm()
+	if n < 1e-9 {
+		return Quaternion{}, ErrZeroQuaternion
+	}
+	return Quaternion{q.X / n, q.Y / n, q.Z / n, q.W / n}, nil
+}
+
+// Slerp は a から b へ割合 t (0〜1) で球面線形補間したクォータニオンを返します
+func Slerp(a, b Quaternion, t float64) Quaternion {
+	dot := a.X*b.X + a.Y*b.Y + a.Z*b.Z + a.W*b.W
+	// 遠回りしないように符号をそろえる
+	if dot < 0 {
+		b = Quaternion{-b.X, -b.Y, -b.Z, -b.W}
+		dot = -dot
+	}
+	var wa, wb float64
+	if dot > 0.9995 {
+		// ほぼ同じ向きなら線形補間で十分
+		wa, wb = 1-t, t
+	} else {
+		theta := math.Acos(dot)
+		sin := math.Sin(theta)
+		wa = math.Sin((1-t)*theta) / sin
+		wb = math.Sin(t*theta) / sin
+	}
+	q := Quaternion{
+		X: wa*a.X + wb*b.X,
+		Y: wa*a.Y + wb*b.Y,
+		Z: wa*a.Z + wb*b.Z,
+		W: wa*a.W + wb*b.W,
+	}
+	if n, err := q.Normalize(); err == nil {
+		return n
+	}
+	return q
+}
+
+// Lerp は a から b へ割合 t (0〜1) で線形補間したベクトルを返します
+func Lerp(a, b Vec3, t float64) Vec3 { return a.Add(b.Sub(a).Scale(t)) }
+
+// IsZero はゼロ値（未設定）かどうかを返します
+func (q Quaternion) IsZero() bool { return q == Quaternion{} }
+
+func Deg2Rad(d float64) float64 { return d * math.Pi / 180 }
+
+func Rad2Deg(r float64) float64 { return r * 180 / math.Pi }
+
+func isFinite(f float64) bool { return !math.IsNaN(f) && !math.IsInf(f, 0) }
@@ -0,0 +1,45 @@
+// internal/geom/transform.go
+package geom
+
+// Transform は剛体変換（回転してから平行移動）です
+// 親フレームから見た子フレームの姿勢として使い、Apply で子フレームの点を親フレームへ写します
+type Transform struct {
+	Translation Vec3       `json:"translation"`
+	Rotation    Quaternion `json:"rotation"`
+}
+
+// IdentityTransform は恒等変換を返します
+func IdentityTransform() Transform { return Transform{Rotation: Identity()} }
+
+// Apply は点 p を変換します
+func (t Transform) Apply(p Vec3) Vec3 { return t.Rotation.Rotate(p).Add(t.Translation) }
+
+// Mul は合成変換 t∘o（o を適用した後に t を適用する）を返します
+func (t Transform) Mul(o Transform) Transform {
+	return Transform{
+		Translation: t.Rotation.Rotate(o.Translation).Add(t.Translation),
+		Rotation:    t.Rotation.Mul(o.Rotation),
+	}
+}
+
+// Inverse は逆変換を返します
+func (t Transform) Inverse() Transform {
+	inv := t.Rotation.Conj()
+	return Transform{
+		Translation: inv.Rotate(t.Translation).Scale(-1),
+		Rotation:    inv,
+	}
+}
+
+// IsFinite は全要素が NaN / Inf でなければ true を返します
+func (t Transform) IsFinite() bool {
+	return t.Translation.IsFinite() && t.Rotation.IsFinite()
+}
+
+// Interpolate は a から b へ割合 ratio (0〜1) で補間した変換を返します
+func Interpolate(a, b Transform, ratio float64) Transform {
+	return Transform{
+		Translation: Lerp(a.Translation, b.Translation, ratio),
+		Rotation:    Slerp(a.Rotation, b.Rotation, ratio),
+	}
+}
@@ -0,0 +1,266 @@
+// internal/graph/graph.go
+package graph
+
+// graphはROSにもginにも依存しないように書く（グラフの問い合わせは robot、HTTP は api パッケージ側）
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Node は ROS のノードと、そのノードが使っているトピック・サービスです
+type Node struct {
+	Name       string   `json:"name"` // "/ns/name"
+	Publishes  []string `json:"publishes"`
+	Subscribes []string `json:"subscribes"`
+	Services   []string `json:"services"` // 提供しているサービス
+	Clients    []string `json:"clients"`  // 呼び出すサービス
+}
+
+// Topic はトピックと、その Publisher・Subscriber のノードです
+type Topic struct {
+	Name        string   `json:"name"`
+	Types       []string `json:"types"`
+	Publishers  []string `json:"publishers"`
+	Subscribers []string `json:"subscribers"`
+	Command     bool     `json:"command"`     // このアプリが指令を送るトピック
+	Unconnected bool     `json:"unconnected"` // 指令トピックなのに Subscriber が居ない（DOT では赤）
+}
+
+// Service はサービスと、その提供元・呼び出し元のノードです
+type Service struct {
+	Name    string   `json:"name"`
+	Types   []string `json:"types"`
+	Servers []string `json:"servers"`
+	Clients []string `json:"clients"`
+}
+
+// Graph はノード・トピック・サービスのつながりです（いずれも名前順）
+type Graph struct {
+	Nodes    []Node    `json:"nodes"`
+	Topics   []Topic   `json:"topics"`
+	Services []Service `json:"services"`
+}
+
+// hiddenTopics は全てのノードが使うので既定では省くトピックです
+var hiddenTopics = map[string]bool{"/rosout": true, "/parameter_events": true}
+
+// Hidden はグラフを見やすくするために既定では省くトピック・サービスかを返します
+// /rosout・/parameter_events・パラメータのサービス（rcl_interfaces）・"_" で始まる名前
+func Hidden(name string, types []string) bool {
+	if hiddenTopics[name] {
+		return true
+	}
+	for _, part := range strings.Split(name, "/") {
+		if strings.HasPrefix(part, "_") {
+			return true
+		}
+	}
+	for _, t := range types {
+		if strings.HasPrefix(t, "rcl_interfaces/srv/") {
+			return true
+		}
+	}
+	return false
+}
+
+// Builder はノードごとの問い合わせ結果からグラフを組み立てます
+type Builder struct {
+	includeHidden bool
+	nodes         map[string]*Node
+	topics        map[string]*Topic
+	services      map[string]*Service
+	commands      map[string]bool
+}
+
+// NewBuilder は Builder を作ります。includeHidden なら Hidden なものも含める
+func NewBuilder(includeHidden bool) *Builder {
+	return &Builder{
+		includeHidden: includeHidden,
+		nodes:         map[string]*Node{},
+		topics:        map[string]*Topic{},
+		services:      map[string]*Service{},
+		commands:      map[string]bool{},
+	}
+}
+
+func (b *Builder) node(name string) *Node {
+	n := b.nodes[name]
+	if n == nil {
+		n = &Node{Name: name, Publishes: []string{}, Subscribes: []string{}, Services: []string{}, Clients: []string{}}
+		b.nodes[name] = n
+	}
+	return n
+}
+
+func (b *Builder) topic(name string, types []string) *Topic {
+	t := b.topics[name]
+	if t == nil {
+		t = &Topic{Name: name, Types: []string{}, Publishers: []string{}, Subscribers: []string{}}
+		b.topics[name] = t
+	}
+	t.Types = appendUnique(t.Types, types...)
+	return t
+}
+
+func (b *Builder) service(name string, types []string) *Service {
+	s := b.services[name]
+	if s == nil {
+		s = &Service{Name: name, Types: []string{}, Servers: []string{}, Clients: []string{}}
+		b.services[name] = s
+	}
+	s.Types = appendUnique(s.Types, types...)
+	return s
+}
+
+// AddNode はノードを追加します（何も使っていないノードも出すため）
+func (b *Builder) AddNode(node string) {
+	b.node(node)
+}
+
+// AddPublisher は node が topic に Publish していることを追加します
+func (b *Builder) AddPublisher(node, topic string, types []string) {
+	if !b.includeHidden && Hidden(topic, types) {
+		return
+	}
+	n, t := b.node(node), b.topic(topic, types)
+	n.Publishes = appendUnique(n.Publishes, topic)
+	t.Publishers = appendUnique(t.Publishers, node)
+}
+
+// AddSubscriber は node が topic を購読していることを追加します
+func (b *Builder) AddSubscriber(node, topic string, types []string) {
+	if !b.includeHidden && Hidden(topic, types) {
+		return
+	}
+	n, t := b.node(node), b.topic(topic, types)
+	n.Subscribes = appendUnique(n.Subscribes, topic)
+	t.Subscribers = appendUnique(t.Subscribers, node)
+}
+
+// AddServer は node が service を提供していることを追加します
+func (b *Builder) AddServer(node, service string, types []string) {
+	if !b.includeHidden && Hidden(service, types) {
+		return
+	}
+	n, s := b.node(node), b.service(service, types)
+	n.Services = appendUnique(n.Services, service)
+	s.Servers = appendUnique(s.Servers, node)
+}
+
+// AddClient は node が service を呼び出すことを追加します
+func (b *Builder) AddClient(node, service string, types []string) {
+	if !b.includeHidden && Hidden(service, types) {
+		return
+	}
+	n, s := b.node(node), b.service(service, types)
+	n.Clients = appendUnique(n.Clients, service)
+	s.Clients = appendUnique(s.Clients, node)
+}
+
+// MarkCommand は topic をこのアプリの指令トピックにします
+func (b *Builder) MarkCommand(topic string) {
+	b.commands[topic] = true
+}
+
+// Build はグラフを返します。指令トピックは Subscriber が居なければ Unconnected にする
+func (b *Builder) Build() *Graph {
+	for name := range b.commands {
+		b.topic(name, nil).Command = true
+	}
+	g := &Graph{Nodes: []Node{}, Topics: []Topic{}, Services: []Service{}}
+	for _, n := range b.nodes {
+		sort.Strings(n.Publishes)
+		sort.Strings(n.Subscribes)
+		sort.Strings(n.Services)
+		sort.Strings(n.Clients)
+		g.Nodes = append(g.Nodes, *n)
+	}
+	for _, t := range b.topics {
+		sort.Strings(t.Publishers)
+		sort.Strings(t.Subscribers)
+		t.Unconnected = t.Command && len(t.Subscribers) == 0
+		g.Topics = append(g.Topics, *t)
+	}
+	for _, s := range b.services {
+		sort.Strings(s.Servers)
+		sort.Strings(s.Clients)
+		g.Services = append(g.Services, *s)
+	}
+	sort.Slice(g.Nodes, func(i, j int) bool { return g.Nodes[i].Name < g.Nodes[j].Name })
+	sort.Slice(g.Topics, func(i, j int) bool { return g.Topics[i].Name < g.Topics[j].Name })
+	sort.Slice(g.Services, func(i, j int) bool { return g.Services[i].Name < g.Services[j].Name })
+	return g
+}
+
+// DOT は Graphviz の DOT 形式にします
+// ノードは楕円、トピックは四角、サービスは六角形。Subscriber の居ない指令トピックは赤
+func (g *Graph) DOT() string {
+	var b strings.Builder
+	b.WriteString("digraph ros {\n")
+	b.WriteString("  rankdir=LR;\n")
+	b.WriteString("  node [fontname=\"Helvetica\", fontsize=10];\n")
+	for _, n := range g.Nodes {
+		fmt.Fprintf(&b, "  %s [label=%s, shape=ellipse];\n", dotID("n", n.Name), dotQuote(n.Name))
+	}
+	for _, t := range g.Topics {
+		attrs := "shape=box"
+		switch {
+		case t.Unconnected:
+			attrs += ", color=red, fontcolor=red, penwidth=2"
+		case t.Command:
+			attrs += ", penwidth=2"
+		}
+		fmt.Fprintf(&b, "  %s [label=%s, %s];\n", dotID("t", t.Name), dotQuote(t.Name), attrs)
+	}
+	for _, s := range g.Services {
+		fmt.Fprintf(&b, "  %s [label=%s, shape=hexagon];\n", dotID("s", s.Name), dotQuote(s.Name))
+	}
+	for _, t := range g.Topics {
+		edge := ""
+		if t.Unconnected {
+			edge = " [color=red]"
+		}
+		for _, n := range t.Publishers {
+			fmt.Fprintf(&b, "  %s -> %s%s;\n", dotID("n", n), dotID("t", t.Name), edge)
+		}
+		for _, n := range t.Subscribers {
+			fmt.Fprintf(&b, "  %s -> %s;\n", dotID("t", t.Name), dotID("n", n))
+		}
+	}
+	for _, s := range g.Services {
+		for _, n := range s.Clients {
+			fmt.Fprintf(&b, "  %s -> %s [style=dashed];\n", dotID("n", n), dotID("s", s.Name))
+		}
+		for _, n := range s.Servers {
+			fmt.Fprintf(&b, "  %s -> %s [style=dashed];\n", dotID("s", s.Name), dotID("n", n))
+		}
+	}
+	b.WriteString("}\n")
+	return b.String()
+}
+
+// dotID はノード・トピック・サービスで同じ名前があっても区別できる ID を作ります
+func dotID(kind, name string) string {
+	return dotQuote(kind + ":" + name)
+}
+
+func dotQuote(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(s) + `"`
+}
+
+func appendUnique(list []string, items ...string) []string {
+	for _, it := range items {
+		found := false
+		for _, x := range list {
+			if x == it {
+				found = true
+				break
+			}
+		}
+		if !found {
+			list = append(list, it)
+		}
+	}
+	return list
+}
@@ -0,0 +1,214 @@
+// internal/jog/jog.go
+package jog
+
+// jogはROSにもginにも依存しないように書く（Publish は関数で受け取る）
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"catchrobo_app/internal/geom"
+)
+
+// ErrHeld は他のクライアントがジョグしている間に速度指令を送ろうとした場合に返ります
+var ErrHeld = errors.New("jog is held by another client")
+
+// Config は速度ジョグの設定です
+type Config struct {
+	Topic            string  `yaml:"topic" json:"topic"`                               // geometry_msgs/TwistStamped
+	FrameId          string  `yaml:"frame_id" json:"frame_id"`                         // 速度を表すフレーム
+	RateHz           float64 `yaml:"rate_hz" json:"rate_hz"`                           // 押している間に Publish する周期
+	HeartbeatTimeout int     `yaml:"heartbeat_timeout_ms" json:"heartbeat_timeout_ms"` // これを超えて心拍が来なければ停止
+	MaxLinear        float64 `yaml:"max_linear" json:"max_linear"`                     // [m/s]
+	MaxAngular       float64 `yaml:"max_angular" json:"max_angular"`                   // [rad/s]
+}
+
+// DefaultConfig は既定の設定です
+func DefaultConfig() Config {
+	return Config{
+		Topic:            "/arm_move/jog_twist",
+		FrameId:          "base_link",
+		RateHz:           50,
+		HeartbeatTimeout: 300,
+		MaxLinear:        0.1,
+		MaxAngular:       0.5,
+	}
+}
+
+// MaxRateHz は rate_hz の上限です（大きすぎると周期が0になり Ticker を作れない）
+const MaxRateHz = 1000
+
+// Check は設定の値を確かめます（rate_hz が0以下なら既定値を使うので許す）
+func (c Config) Check() error {
+	if math.IsNaN(c.RateHz) || c.RateHz > MaxRateHz {
+		return fmt.Errorf("rate_hz must be at most %d", MaxRateHz)
+	}
+	return nil
+}
+
+// Twist は並進・回転速度です
+type Twist struct {
+	Linear  geom.Vec3 `json:"linear"`
+	Angular geom.Vec3 `json:"angular"`
+}
+
+// IsZero は停止指令かどうかを返します
+func (t Twist) IsZero() bool { return t == Twist{} }
+
+// Status はジョグの現在の状態です
+type Status struct {
+	Active        bool      `json:"active"`
+	Twist         Twist     `json:"twist"`
+	Owner         string    `json:"owner,omitempty"`
+	LastHeartbeat time.Time `json:"last_heartbeat"`
+	Published     uint64    `json:"published"`
+	Timeouts      uint64    `json:"timeouts"` // 心拍途絶で止めた回数
+	Config        Config    `json:"config"`
+}
+
+// Jogger は速度指令を一定周期で Publish し、心拍が途絶えたらゼロ速度を送って止めます
+// Publish は mu を保持したまま行う（Stop のゼロ速度の後に古い速度が送られないように）。
+// そのため publish から Jogger を呼び返してはいけない
+type Jogger struct {
+	cfg     Config
+	publish func(Twist) error
+	onStop  func(reason string)
+
+	mu        sync.Mutex
+	twist     Twist
+	active    bool
+	owner     string
+	lastBeat  time.Time
+	published uint64
+	timeouts  uint64
+}
+
+// New は Jogger を作ります。onStop は心拍途絶などで止めたときに呼ばれます（nil 可）
+func New(cfg Config, publish func(Twist) error, onStop func(reason string)) *Jogger {
+	def := DefaultConfig()
+	if cfg.RateHz <= 0 {
+		cfg.RateHz = def.RateHz
+	}
+	if cfg.HeartbeatTimeout <= 0 {
+		cfg.HeartbeatTimeout = def.HeartbeatTimeout
+	}
+	return &Jogger{cfg: cfg, publish: publish, onStop: onStop}
+}
+
+// Config は設定を返します
+func (j *Jogger) Config() Config { return j.cfg }
+
+// Set は owner からの速度指令を受け付けます（心拍も兼ねる）。上限を超える成分は上限に丸めます
+// 他の owner がジョグしている間は ErrHeld。ゼロ速度（ボタンを離した）ならすぐにゼロ速度を送る
+func (j *Jogger) Set(owner string, t Twist) (Twist, error) {
+	t.Linear = clampNorm(t.Linear, j.cfg.MaxLinear)
+	t.Angular = clampNorm(t.Angular, j.cfg.MaxAngular)
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.active && j.owner != owner {
+		return Twist{}, ErrHeld
+	}
+	wasActive := j.active
+	j.owner = owner
+	j.lastBeat = time.Now()
+	j.twist = t
+	j.active = !t.IsZero()
+	if wasActive && !j.active {
+		// Run は動いている間しか送らないので、ここで送らないと最後の速度が残る
+		return t, j.publish(Twist{})
+	}
+	return t, nil
+}
+
+// Heartbeat は owner が操作を続けていることを伝えます（速度は変えない）
+func (j *Jogger) Heartbeat(owner string) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.owner == owner {
+		j.lastBeat = time.Now()
+	}
+}
+
+// Stop はゼロ速度を送って止めます。owner が空でなければその owner が操作中の場合のみ止めます
+func (j *Jogger) Stop(owner, reason string) {
+	j.mu.Lock()
+	if owner != "" && j.owner != owner {
+		j.mu.Unlock()
+		return
+	}
+	wasActive := j.active
+	j.twist, j.active = Twist{}, false
+	_ = j.publish(Twist{})
+	j.mu.Unlock()
+
+	if wasActive && j.onStop != nil {
+		j.onStop(reason)
+	}
+}
+
+// Status は現在の状態を返します
+func (j *Jogger) Status() Status {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return Status{
+		Active:        j.active,
+		Twist:         j.twist,
+		Owner:         j.owner,
+		LastHeartbeat: j.lastBeat,
+		Published:     j.published,
+		Timeouts:      j.timeouts,
+		Config:        j.cfg,
+	}
+}
+
+// Run は ctx が終わるまで一定周期で速度を Publish します。終了時にもゼロ速度を送ります
+func (j *Jogger) Run(ctx context.Context) {
+	period := time.Duration(float64(time.Second) / j.cfg.RateHz)
+	timeout := time.Duration(j.cfg.HeartbeatTimeout) * time.Millisecond
+	ticker := time.NewTicker(period)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			j.Stop("", "shutdown")
+			return
+		case now := <-ticker.C:
+			j.mu.Lock()
+			if !j.active {
+				j.mu.Unlock()
+				continue
+			}
+			if now.Sub(j.lastBeat) > timeout {
+				// デッドマン: 心拍が途絶えたらゼロ速度を送って止める
+				// 判定と停止を同じロックの中で行う（間に届いた Set の速度をゼロで上書きしないように）
+				j.timeouts++
+				j.twist, j.active = Twist{}, false
+				_ = j.publish(Twist{})
+				j.mu.Unlock()
+				if j.onStop != nil {
+					j.onStop("heartbeat timeout")
+				}
+				continue
+			}
+			j.published++
+			_ = j.publish(j.twist)
+			j.mu.Unlock()
+		}
+	}
+}
+
+// clampNorm はベクトルの大きさを max 以下に抑えます（max が0以下なら抑えない）。NaN / Inf はゼロにします
+func clampNorm(v geom.Vec3, max float64) geom.Vec3 {
+	if !v.IsFinite() {
+		return geom.Vec3{}
+	}
+	n := v.Norm()
+	if max > 0 && n > max {
+		return v.Scale(max / n)
+	}
+	return v
+}
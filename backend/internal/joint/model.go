@@ -0,0 +1,173 @@
+// internal/joint/model.go
+package joint
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+// Limit は1関節分の名前と可動範囲です（角度は [rad]）
+type Limit struct {
+	Name    string  `yaml:"name" json:"name"`
+	Min     float64 `yaml:"min" json:"min"`
+	Max     float64 `yaml:"max" json:"max"`
+	MaxStep float64 `yaml:"max_step" json:"max_step"` // 1回の指令で動かせる最大量（0なら制限なし）
+}
+
+// Model は関節の並び順と制限です。指令の配列はこの順に並んでいる必要があります
+type Model struct {
+	Joints []Limit `json:"joints"`
+}
+
+// 違反の種類
+const (
+	ReasonNotFinite = "not_finite"
+	ReasonBelowMin  = "below_min"
+	ReasonAboveMax  = "above_max"
+	ReasonStepLimit = "step_too_large"
+)
+
+// Violation は1関節分の違反内容です
+type Violation struct {
+	Index  int     `json:"index"`
+	Name   string  `json:"name"`
+	Value  float64 `json:"value"`
+	Reason string  `json:"reason"`
+	Min    float64 `json:"min"`
+	Max    float64 `json:"max"`
+	// step_too_large のときだけ意味を持つ
+	Previous float64 `json:"previous,omitempty"`
+	MaxStep  float64 `json:"max_step,omitempty"`
+}
+
+// ValidationError は関節角度指令が制限を満たさない場合に返ります
+type ValidationError struct {
+	Expected   int         `json:"expected"`
+	Got        int         `json:"got"`
+	Violations []Violation `json:"violations"`
+}
+
+func (e *ValidationError) Error() string {
+	if e.Expected != e.Got {
+		return fmt.Sprintf("expected %d joint angles, got %d", e.Expected, e.Got)
+	}
+	parts := make([]string, 0, len(e.Violations))
+	for _, v := range e.Violations {
+		parts = append(parts, fmt.Sprintf("%s=%g (%s)", v.Name, v.Value, v.Reason))
+	}
+	return "joint limit violation: " + strings.Join(parts, ", ")
+}
+
+// DefaultModel は設定が無い場合の6関節 ±π のモデルを返します
+func DefaultModel() *Model {
+	m := &Model{}
+	for i := 1; i <= 6; i++ {
+		m.Joints = append(m.Joints, Limit{Name: fmt.Sprintf("joint%d", i), Min: -math.Pi, Max: math.Pi})
+	}
+	return m
+}
+
+// Check は設定値そのものの矛盾（名前の重複や min > max）を調べます
+func (m *Model) Check() error {
+	if len(m.Joints) == 0 {
+		return fmt.Errorf("no joints configured")
+	}
+	seen := map[string]bool{}
+	for i, j := range m.Joints {
+		if j.Name == "" {
+			return fmt.Errorf("joint %d has no name", i)
+		}
+		if seen[j.Name] {
+			return fmt.Errorf("duplicate joint name %q", j.Name)
+		}
+		seen[j.Name] = true
+		if j.Min > j.Max {
+			return fmt.Errorf("joint %q: min %g > max %g", j.Name, j.Min, j.Max)
+		}
+		if j.MaxStep < 0 {
+			return fmt.Errorf("joint %q: negative max_step %g", j.Name, j.MaxStep)
+		}
+	}
+	return nil
+}
+
+// Names は関節名を並び順で返します
+func (m *Model) Names() []string {
+	names := make([]string, len(m.Joints))
+	for i, j := range m.Joints {
+		names[i] = j.Name
+	}
+	return names
+}
+
+// Validate は angles が関節数・可動範囲・1回あたりの変化量を満たすか調べます。
+// prev は今の関節角度で、nil なら変化量のチェックは行いません（HasStepLimit なら呼び出し側で必ず渡すこと）
+func (m *Model) Validate(angles, prev []float64) error {
+	if len(angles) != len(m.Joints) {
+		return &ValidationError{Expected: len(m.Joints), Got: len(angles)}
+	}
+	var vs []Violation
+	for i, j := range m.Joints {
+		a := angles[i]
+		v := Violation{Index: i, Name: j.Name, Value: a, Min: j.Min, Max: j.Max}
+		switch {
+		case math.IsNaN(a) || math.IsInf(a, 0):
+			v.Reason = ReasonNotFinite
+		case a < j.Min:
+			v.Reason = ReasonBelowMin
+		case a > j.Max:
+			v.Reason = ReasonAboveMax
+		case j.MaxStep > 0 && len(prev) == len(angles) && math.Abs(a-prev[i]) > j.MaxStep:
+			v.Reason = ReasonStepLimit
+			v.Previous = prev[i]
+			v.MaxStep = j.MaxStep
+		default:
+			continue
+		}
+		vs = append(vs, v)
+	}
+	if len(vs) > 0 {
+		return &ValidationError{Expected: len(m.Joints), Got: len(angles), Violations: vs}
+	}
+	return nil
+}
+
+// HasStepLimit は max_step を設定した関節があるかを返します
+func (m *Model) HasStepLimit() bool {
+	for _, j := range m.Joints {
+		if j.MaxStep > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// Ordered は関節名 → 角度の対応を並び順の角度に直します（足りない関節があればエラー）
+func (m *Model) Ordered(byName map[string]float64) ([]float64, error) {
+	out := make([]float64, len(m.Joints))
+	var missing []string
+	for i, j := range m.Joints {
+		v, ok := byName[j.Name]
+		if !ok {
+			missing = append(missing, j.Name)
+			continue
+		}
+		out[i] = v
+	}
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("missing joints: %s", strings.Join(missing, ", "))
+	}
+	return out, nil
+}
+
+// ByName は並び順の角度を関節名 → 角度の対応に直します（余った値は捨てる）
+func (m *Model) ByName(angles []float64) map[string]float64 {
+	out := make(map[string]float64, len(m.Joints))
+	for i, j := range m.Joints {
+		if i < len(angles) {
+			out[j.Name] = angles[i]
+		}
+	}
+	return out
+}
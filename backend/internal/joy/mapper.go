@@ -0,0 +1,276 @@
+// internal/joy/mapper.go
+package joy
+
+// joyはROSにもginにも依存しないように書く（sensor_msgs/Joy の購読は robot パッケージ側で行う）
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"catchrobo_app/internal/geom"
+)
+
+// StaleAfter を超えて sensor_msgs/Joy が来なければ切断とみなし、軸による移動を止めます
+const StaleAfter = 500 * time.Millisecond
+
+// reloadInterval は割り当てファイルの更新を確認する周期です
+const reloadInterval = time.Second
+
+// Delta は軸から作った1周期分の相対移動です
+type Delta struct {
+	Translation geom.Vec3 // [m]
+	RPYDeg      geom.Vec3 // roll / pitch / yaw の増分 [deg]
+	FrameId     string
+}
+
+// Status は /api/joy で返す状態です（割り当ての調整用に生の値も返す）
+type Status struct {
+	Connected   bool      `json:"connected"`
+	Axes        []float32 `json:"axes"`
+	Buttons     []int32   `json:"buttons"`
+	LastMessage time.Time `json:"last_message"`
+	Received    uint64    `json:"received"`
+	EStopped    bool      `json:"estopped"`
+
+	MappingFile string    `json:"mapping_file"`
+	Mapping     Mapping   `json:"mapping"`
+	LoadedAt    time.Time `json:"loaded_at"`
+	LoadError   string    `json:"load_error,omitempty"`
+	Actions     []string  `json:"actions"` // 割り当て可能なアクション
+
+	LastAction   string    `json:"last_action,omitempty"`
+	LastActionAt time.Time `json:"last_action_at"`
+	LastError    string    `json:"last_error,omitempty"`
+}
+
+// Mapper は sensor_msgs/Joy を割り当てに従ってアクションと相対移動に変換します
+type Mapper struct {
+	path    string
+	actions map[string]func() error
+	names   []string
+	move    func(Delta) error
+	warn    func(error)
+
+	mu       sync.Mutex
+	mapping  Mapping
+	modTime  time.Time
+	loadedAt time.Time
+	loadErr  string
+
+	axes     []float32
+	buttons  []int32
+	pressed  []bool // ButtonBinding ごとの前回の押下状態（立ち上がりでアクションを実行する）
+	lastMsg  time.Time
+	received uint64
+	estopped bool
+
+	lastAction   string
+	lastActionAt time.Time
+	lastErr      string
+}
+
+// New は Mapper を作り、path の割り当てを読み込みます。
+// actions はボタンに割り当てられるアクション（names の順で一覧に出す）、move は軸による相対移動の送信先です
+func New(path string, names []string, actions map[string]func() error, move func(Delta) error, warn func(error)) *Mapper {
+	m := &Mapper{path: path, actions: actions, names: names, move: move, warn: warn}
+	m.mapping.RateHz = DefaultRateHz
+	m.reload()
+	return m
+}
+
+// Update は sensor_msgs/Joy を1つ受け取ります。押された瞬間のボタンに割り当てたアクションを実行します
+func (m *Mapper) Update(axes []float32, buttons []int32) {
+	m.mu.Lock()
+	m.axes = append(m.axes[:0], axes...)
+	m.buttons = append(m.buttons[:0], buttons...)
+	m.lastMsg = time.Now()
+	m.received++
+
+	var fire []string
+	if len(m.pressed) != len(m.mapping.Buttons) {
+		m.pressed = make([]bool, len(m.mapping.Buttons))
+	}
+	for i, b := range m.mapping.Buttons {
+		now := b.pressed(axes, buttons)
+		if now && !m.pressed[i] {
+			fire = append(fire, b.Action)
+		}
+		m.pressed[i] = now
+	}
+	m.mu.Unlock()
+
+	for _, action := range fire {
+		m.run(action)
+	}
+}
+
+// run はアクションを1つ実行します。estop 中は resume 以外を無視します
+func (m *Mapper) run(action string) {
+	m.mu.Lock()
+	switch action {
+	case ActionEStop:
+		m.estopped = true
+	case ActionResume:
+		m.estopped = false
+	default:
+		if m.estopped {
+			m.mu.Unlock()
+			return
+		}
+	}
+	m.mu.Unlock()
+
+	var err error
+	if f := m.actions[action]; f != nil {
+		err = f()
+	}
+
+	m.mu.Lock()
+	m.lastAction, m.lastActionAt = action, time.Now()
+	m.lastErr = ""
+	if err != nil {
+		m.lastErr = err.Error()
+	}
+	m.mu.Unlock()
+	if err != nil {
+		m.notify(fmt.Errorf("joy action %s: %w", action, err))
+	}
+}
+
+// Run は ctx が終わるまで、軸による相対移動の送信と割り当てファイルの再読み込みを行います
+func (m *Mapper) Run(ctx context.Context) {
+	reload := time.NewTicker(reloadInterval)
+	defer reload.Stop()
+	rate := m.Status().Mapping.RateHz
+	ticker := time.NewTicker(time.Duration(float64(time.Second) / rate))
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-reload.C:
+			if m.reload() {
+				if r := m.Status().Mapping.RateHz; r != rate {
+					rate = r
+					ticker.Reset(time.Duration(float64(time.Second) / rate))
+				}
+			}
+		case now := <-ticker.C:
+			d, ok := m.delta(now, rate)
+			if !ok {
+				continue
+			}
+			if err := m.move(d); err != nil {
+				m.mu.Lock()
+				m.lastErr = err.Error()
+				m.mu.Unlock()
+			}
+		}
+	}
+}
+
+// delta は現在の軸の値から1周期分の相対移動を作ります。動かさない場合は false を返します
+func (m *Mapper) delta(now time.Time, rate float64) (Delta, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.estopped || m.move == nil || now.Sub(m.lastMsg) > StaleAfter {
+		return Delta{}, false
+	}
+	d := Delta{FrameId: m.mapping.FrameId}
+	for _, a := range m.mapping.Axes {
+		step := a.value(m.axes) * a.Scale / rate
+		switch a.Target {
+		case TargetX:
+			d.Translation.X += step
+		case TargetY:
+			d.Translation.Y += step
+		case TargetZ:
+			d.Translation.Z += step
+		case TargetRoll:
+			d.RPYDeg.X += step
+		case TargetPitch:
+			d.RPYDeg.Y += step
+		case TargetYaw:
+			d.RPYDeg.Z += step
+		}
+	}
+	if d.Translation == (geom.Vec3{}) && d.RPYDeg == (geom.Vec3{}) {
+		return Delta{}, false
+	}
+	return d, true
+}
+
+// reload は割り当てファイルが更新されていれば読み直します。読めなかった場合は前の割り当てを使い続けます
+func (m *Mapper) reload() bool {
+	if m.path == "" {
+		return false
+	}
+	st, err := os.Stat(m.path)
+	if err != nil {
+		m.setLoadError(err)
+		return false
+	}
+	m.mu.Lock()
+	same := st.ModTime().Equal(m.modTime)
+	m.mu.Unlock()
+	if same {
+		return false
+	}
+
+	mapping, err := LoadMapping(m.path, func(action string) bool {
+		_, ok := m.actions[action]
+		return ok
+	})
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.modTime = st.ModTime()
+	if err != nil {
+		m.loadErr = err.Error()
+		m.notify(fmt.Errorf("load joy mapping: %w", err))
+		return false
+	}
+	m.mapping = mapping
+	m.pressed = nil
+	m.loadedAt = time.Now()
+	m.loadErr = ""
+	return true
+}
+
+func (m *Mapper) setLoadError(err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.loadErr != err.Error() {
+		m.loadErr = err.Error()
+		m.notify(fmt.Errorf("load joy mapping: %w", err))
+	}
+}
+
+// Status は現在の状態を返します
+func (m *Mapper) Status() Status {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return Status{
+		Connected:    !m.lastMsg.IsZero() && time.Since(m.lastMsg) <= StaleAfter,
+		Axes:         append([]float32(nil), m.axes...),
+		Buttons:      append([]int32(nil), m.buttons...),
+		LastMessage:  m.lastMsg,
+		Received:     m.received,
+		EStopped:     m.estopped,
+		MappingFile:  m.path,
+		Mapping:      m.mapping,
+		LoadedAt:     m.loadedAt,
+		LoadError:    m.loadErr,
+		Actions:      append(append([]string(nil), m.names...), ActionEStop, ActionResume),
+		LastAction:   m.lastAction,
+		LastActionAt: m.lastActionAt,
+		LastError:    m.lastErr,
+	}
+}
+
+func (m *Mapper) notify(err error) {
+	if m.warn != nil {
+		m.warn(err)
+	}
+}
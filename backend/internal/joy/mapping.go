@@ -0,0 +1,157 @@
+// internal/joy/mapping.go
+package joy
+
+import (
+	"fmt"
+	"math"
+	"os"
+
+	"gopkg.in/yaml.v3"
+)
+
+// 軸で動かす対象
+const (
+	TargetX     = "x"
+	TargetY     = "y"
+	TargetZ     = "z"
+	TargetRoll  = "roll"
+	TargetPitch = "pitch"
+	TargetYaw   = "yaw"
+)
+
+// マッパー自身が持つアクション（ボタンに割り当てられる）
+const (
+	ActionEStop  = "estop"  // 止めてからゲームパッドの入力を無視する
+	ActionResume = "resume" // estop を解除する
+)
+
+// ButtonBinding はボタン（または軸を倒した方向）とアクションの対応です
+// button を書けばボタン、axis と direction（+1 / -1）を書けば十字キーのような軸を押下として扱います
+type ButtonBinding struct {
+	Button    *int    `yaml:"button,omitempty" json:"button,omitempty"`
+	Axis      *int    `yaml:"axis,omitempty" json:"axis,omitempty"`
+	Direction float64 `yaml:"direction,omitempty" json:"direction,omitempty"`
+	Action    string  `yaml:"action" json:"action"`
+}
+
+// AxisBinding は軸と相対移動の対応です
+type AxisBinding struct {
+	Axis     int     `yaml:"axis" json:"axis"`
+	Target   string  `yaml:"target" json:"target"`     // x / y / z / roll / pitch / yaw
+	Scale    float64 `yaml:"scale" json:"scale"`       // 倒し切ったときの速さ [m/s]（roll/pitch/yaw は [deg/s]）
+	Deadzone float64 `yaml:"deadzone" json:"deadzone"` // これ未満の入力は0とみなす（0〜1）
+	Invert   bool    `yaml:"invert" json:"invert"`
+}
+
+// Mapping はゲームパッドの割り当てファイルの中身です
+type Mapping struct {
+	FrameId string          `yaml:"frame_id" json:"frame_id"` // 相対移動のフレーム（空なら現在の目標のフレーム）
+	RateHz  float64         `yaml:"rate_hz" json:"rate_hz"`   // 軸による相対移動を送る周期
+	Buttons []ButtonBinding `yaml:"buttons" json:"buttons"`
+	Axes    []AxisBinding   `yaml:"axes" json:"axes"`
+}
+
+// DefaultRateHz は rate_hz を省略した場合の周期です
+const DefaultRateHz = 10
+
+// MaxRateHz は rate_hz の上限です（これより速く送っても arm 側で溜まるだけで、大きすぎると周期が0になる）
+const MaxRateHz = 100
+
+// axisPressThreshold は軸を押下とみなす倒し具合です
+const axisPressThreshold = 0.5
+
+// LoadMapping は path の割り当てを読み込み、known に無いアクションや不正な値があればエラーにします
+func LoadMapping(path string, known func(action string) bool) (Mapping, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return Mapping{}, err
+	}
+	var m Mapping
+	if err := yaml.Unmarshal(b, &m); err != nil {
+		return Mapping{}, fmt.Errorf("parse %s: %w", path, err)
+	}
+	if m.RateHz <= 0 {
+		m.RateHz = DefaultRateHz
+	}
+	if err := m.check(known); err != nil {
+		return Mapping{}, fmt.Errorf("%s: %w", path, err)
+	}
+	return m, nil
+}
+
+func (m Mapping) check(known func(string) bool) error {
+	if !(m.RateHz > 0 && m.RateHz <= MaxRateHz) {
+		return fmt.Errorf("rate_hz must be in (0, %d]", MaxRateHz)
+	}
+	for i, b := range m.Buttons {
+		switch {
+		case b.Button != nil && b.Axis != nil:
+			return fmt.Errorf("buttons[%d]: specify either button or axis", i)
+		case b.Button != nil:
+			if *b.Button < 0 {
+				return fmt.Errorf("buttons[%d]: negative button index", i)
+			}
+		case b.Axis != nil:
+			if *b.Axis < 0 {
+				return fmt.Errorf("buttons[%d]: negative axis index", i)
+			}
+			if b.Direction != 1 && b.Direction != -1 {
+				return fmt.Errorf("buttons[%d]: direction must be 1 or -1", i)
+			}
+		default:
+			return fmt.Errorf("buttons[%d]: button or axis is required", i)
+		}
+		if b.Action != ActionEStop && b.Action != ActionResume && (known == nil || !known(b.Action)) {
+			return fmt.Errorf("buttons[%d]: unknown action %q", i, b.Action)
+		}
+	}
+	for i, a := range m.Axes {
+		if a.Axis < 0 {
+			return fmt.Errorf("axes[%d]: negative axis index", i)
+		}
+		switch a.Target {
+		case TargetX, TargetY, TargetZ, TargetRoll, TargetPitch, TargetYaw:
+		default:
+			return fmt.Errorf("axes[%d]: unknown target %q", i, a.Target)
+		}
+		if math.IsNaN(a.Scale) || math.IsInf(a.Scale, 0) {
+			return fmt.Errorf("axes[%d]: scale is not finite", i)
+		}
+		if !(a.Deadzone >= 0 && a.Deadzone < 1) {
+			return fmt.Errorf("axes[%d]: deadzone must be in [0, 1)", i)
+		}
+	}
+	return nil
+}
+
+// pressed は割り当てが押されているかを返します（範囲外のインデックスは押されていない扱い）
+func (b ButtonBinding) pressed(axes []float32, buttons []int32) bool {
+	if b.Button != nil {
+		return *b.Button < len(buttons) && buttons[*b.Button] != 0
+	}
+	if b.Axis != nil && *b.Axis < len(axes) {
+		return float64(axes[*b.Axis])*b.Direction > axisPressThreshold
+	}
+	return false
+}
+
+// value はデッドゾーンを除いて -1〜1 に伸ばし直した軸の値を返します
+func (a AxisBinding) value(axes []float32) float64 {
+	if a.Axis >= len(axes) {
+		return 0
+	}
+	v := float64(axes[a.Axis])
+	if math.IsNaN(v) || math.IsInf(v, 0) {
+		return 0
+	}
+	v = math.Max(-1, math.Min(1, v))
+	mag := math.Abs(v)
+	if mag < a.Deadzone {
+		return 0
+	}
+	v = math.Copysign((mag-a.Deadzone)/(1-a.Deadzone), v)
+	if a.Invert {
+		v = -v
+	}
+	return v
+}
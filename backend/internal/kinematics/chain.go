@@ -0,0 +1,216 @@
+// internal/kinematics/chain.go
+package kinematics
+
+// kinematicsはROSに依存しない運動学計算だけを置く
+import (
+	"fmt"
+	"math"
+
+	"catchrobo_app/internal/geom"
+	"catchrobo_app/internal/urdf"
+)
+
+// 関節の種類（URDF と同じ名前）
+const (
+	Revolute   = "revolute"
+	Continuous = "continuous"
+	Prismatic  = "prismatic"
+	Fixed      = "fixed"
+)
+
+// Joint は運動学チェーンの1要素です。親リンクから origin だけ移動した後、軸まわりに q だけ動きます
+type Joint struct {
+	Name   string         `yaml:"name" json:"name"`
+	Type   string         `yaml:"type" json:"type"`
+	Child  string         `yaml:"child" json:"child"`
+	Origin geom.Transform `yaml:"-" json:"origin"`
+	Axis   geom.Vec3      `yaml:"-" json:"axis"`
+	Lower  float64        `yaml:"lower" json:"lower"`
+	Upper  float64        `yaml:"upper" json:"upper"`
+}
+
+// Movable は q を持つ関節かどうかを返します
+func (j Joint) Movable() bool { return j.Type != Fixed }
+
+// Limited は可動範囲を持つ関節かどうかを返します（continuous と範囲未設定は制限なし）
+func (j Joint) Limited() bool { return j.Type != Continuous && j.Lower < j.Upper }
+
+// Transform は関節変位 q のときの親リンクから子リンクへの変換です
+func (j Joint) Transform(q float64) geom.Transform {
+	switch j.Type {
+	case Revolute, Continuous:
+		return j.Origin.Mul(geom.Transform{Rotation: geom.FromAxisAngle(j.Axis, q)})
+	case Prismatic:
+		return j.Origin.Mul(geom.Transform{Translation: j.Axis.Scale(q / j.Axis.Norm()), Rotation: geom.Identity()})
+	default:
+		return j.Origin
+	}
+}
+
+// Chain は base リンクから tip リンクまでの関節の並びです（fixed を含む）
+type Chain struct {
+	Base   string  `json:"base"`
+	Tip    string  `json:"tip"`
+	Joints []Joint `json:"joints"`
+}
+
+// FromURDF は URDF の base から tip までをチェーンにします。tip が空なら最も深い末端リンクを使います
+func FromURDF(r *urdf.Robot, base, tip string) (*Chain, error) {
+	if base == "" {
+		root, err := r.Root()
+		if err != nil {
+			return nil, err
+		}
+		base = root
+	}
+	if tip == "" {
+		tip = r.DeepestLeaf(base)
+	}
+	path, err := r.Path(base, tip)
+	if err != nil {
+		return nil, err
+	}
+	c := &Chain{Base: base, Tip: tip}
+	for _, uj := range path {
+		origin, err := uj.OriginTransform()
+		if err != nil {
+			return nil, err
+		}
+		axis, err := uj.AxisVec()
+		if err != nil {
+			return nil, err
+		}
+		j := Joint{Name: uj.Name, Type: uj.Type, Child: uj.Child.Link, Origin: origin, Axis: axis}
+		switch uj.Type {
+		case Revolute, Continuous, Prismatic, Fixed:
+		default:
+			return nil, fmt.Errorf("joint %q: unsupported type %q", uj.Name, uj.Type)
+		}
+		if j.Movable() && axis.Norm() == 0 {
+			return nil, fmt.Errorf("joint %q: zero axis", uj.Name)
+		}
+		if uj.Limit != nil {
+			j.Lower, j.Upper = uj.Limit.Lower, uj.Limit.Upper
+		}
+		c.Joints = append(c.Joints, j)
+	}
+	return c, nil
+}
+
+// Movable は可動関節の名前をチェーン順に返します。FK の q はこの順に並べます
+func (c *Chain) Movable() []string {
+	var names []string
+	for _, j := range c.Joints {
+		if j.Movable() {
+			names = append(names, j.Name)
+		}
+	}
+	return names
+}
+
+// DOF は可動関節の数です
+func (c *Chain) DOF() int { return len(c.Movable()) }
+
+// FK は可動関節の変位 q から base リンクに対する tip リンクの姿勢を求めます
+func (c *Chain) FK(q []float64) (geom.Transform, error) {
+	frames, err := c.Frames(q)
+	if err != nil {
+		return geom.Transform{}, err
+	}
+	if len(frames) == 0 {
+		return geom.IdentityTransform(), nil
+	}
+	return frames[len(frames)-1], nil
+}
+
+// Frames は各関節の子リンクの base に対する姿勢をチェーン順に返します
+func (c *Chain) Frames(q []float64) ([]geom.Transform, error) {
+	if len(q) != c.DOF() {
+		return nil, fmt.Errorf("expected %d joint values, got %d", c.DOF(), len(q))
+	}
+	frames := make([]geom.Transform, 0, len(c.Joints))
+	acc := geom.IdentityTransform()
+	k := 0
+	for _, j := range c.Joints {
+		v := 0.0
+		if j.Movable() {
+			v = q[k]
+			k++
+			if math.IsNaN(v) || math.IsInf(v, 0) {
+				return nil, fmt.Errorf("joint %q: %w", j.Name, geom.ErrNotFinite)
+			}
+		}
+		acc = acc.Mul(j.Transform(v))
+		frames = append(frames, acc)
+	}
+	return frames, nil
+}
+
+// Positions は関節名 → 変位の対応からチェーン順の q を作ります。足りない関節があればエラーにします
+func (c *Chain) Positions(byName map[string]float64) ([]float64, error) {
+	names := c.Movable()
+	q := make([]float64, len(names))
+	var missing []string
+	for i, n := range names {
+		v, ok := byName[n]
+		if !ok {
+			missing = append(missing, n)
+			continue
+		}
+		q[i] = v
+	}
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("missing joint values for %v", missing)
+	}
+	return q, nil
+}
+
+// JointSpec は設定ファイルでチェーンを直接書く場合の1関節分です（URDF の joint と同じ意味）
+type JointSpec struct {
+	Name  string     `yaml:"name" json:"name"`
+	Type  string     `yaml:"type" json:"type"`
+	Child string     `yaml:"child" json:"child"`
+	XYZ   [3]float64 `yaml:"xyz" json:"xyz"`
+	RPY   [3]float64 `yaml:"rpy" json:"rpy"`
+	Axis  [3]float64 `yaml:"axis" json:"axis"`
+	Lower float64    `yaml:"lower" json:"lower"`
+	Upper float64    `yaml:"upper" json:"upper"`
+}
+
+// FromSpecs は設定ファイルに書かれた関節の並びからチェーンを作ります
+func FromSpecs(base, tip string, specs []JointSpec) (*Chain, error) {
+	if len(specs) == 0 {
+		return nil, fmt.Errorf("no joints in chain")
+	}
+	c := &Chain{Base: base, Tip: tip}
+	for i, s := range specs {
+		j := Joint{
+			Name:   s.Name,
+			Type:   s.Type,
+			Child:  s.Child,
+			Origin: geom.Transform{Translation: geom.Vec3{X: s.XYZ[0], Y: s.XYZ[1], Z: s.XYZ[2]}, Rotation: geom.FromRPY(s.RPY[0], s.RPY[1], s.RPY[2])},
+			Axis:   geom.Vec3{X: s.Axis[0], Y: s.Axis[1], Z: s.Axis[2]},
+			Lower:  s.Lower,
+			Upper:  s.Upper,
+		}
+		if j.Type == "" {
+			j.Type = Revolute
+		}
+		if j.Child == "" {
+			j.Child = fmt.Sprintf("%s_link%d", base, i+1)
+		}
+		switch j.Type {
+		case Revolute, Continuous, Prismatic, Fixed:
+		default:
+			return nil, fmt.Errorf("joint %q: unsupported type %q", j.Name, j.Type)
+		}
+		if j.Movable() && j.Axis.Norm() == 0 {
+			return nil, fmt.Errorf("joint %q: zero axis", j.Name)
+		}
+		c.Joints = append(c.Joints, j)
+	}
+	if c.Tip == "" {
+		c.Tip = c.Joints[len(c.Joints)-1].Child
+	}
+	return c, nil
+}
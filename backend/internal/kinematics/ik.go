@@ -0,0 +1,304 @@
+// internal/kinematics/ik.go
+package kinematics
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+
+	"catchrobo_app/internal/geom"
+)
+
+// IKOptions は数値逆運動学（減衰最小二乗法）の設定です
+type IKOptions struct {
+	PositionOnly         bool    `yaml:"position_only" json:"position_only"`                 // 姿勢を無視して位置だけ合わせる
+	MaxIterations        int     `yaml:"max_iterations" json:"max_iterations"`               // 1回の試行あたりの反復回数
+	Damping              float64 `yaml:"damping" json:"damping"`                             // 減衰係数 λ
+	PositionTolerance    float64 `yaml:"position_tolerance" json:"position_tolerance"`       // [m]
+	OrientationTolerance float64 `yaml:"orientation_tolerance" json:"orientation_tolerance"` // [rad]
+	Restarts             int     `yaml:"restarts" json:"restarts"`                           // 収束しなかった時に初期値を変えて試す回数
+	MaxStep              float64 `yaml:"max_step" json:"max_step"`                           // 1反復で動かす最大量 [rad or m]
+}
+
+// DefaultIKOptions は既定の設定です
+func DefaultIKOptions() IKOptions {
+	return IKOptions{
+		PositionOnly:         true,
+		MaxIterations:        200,
+		Damping:              0.05,
+		PositionTolerance:    0.002,
+		OrientationTolerance: 0.02,
+		Restarts:             8,
+		MaxStep:              0.2,
+	}
+}
+
+// IKResult は逆運動学の結果です。Converged が false なら Q は最も誤差の小さかった解です
+type IKResult struct {
+	Q                []float64 `json:"q"`
+	Converged        bool      `json:"converged"`
+	Iterations       int       `json:"iterations"`
+	PositionError    float64   `json:"position_error"`
+	OrientationError float64   `json:"orientation_error"`
+}
+
+// IK は base に対する tip の目標姿勢 target を満たす可動関節の変位を求めます。
+// seed は初期値（nil なら関節範囲の中央）で、結果は関節範囲内に収まります
+func (c *Chain) IK(target geom.Transform, seed []float64, opts IKOptions) (IKResult, error) {
+	dof := c.DOF()
+	if dof == 0 {
+		return IKResult{}, fmt.Errorf("chain %s -> %s has no movable joints", c.Base, c.Tip)
+	}
+	if !target.IsFinite() {
+		return IKResult{}, fmt.Errorf("target: %w", geom.ErrNotFinite)
+	}
+	rot, err := target.Rotation.Normalize()
+	if err != nil {
+		return IKResult{}, fmt.Errorf("target rotation: %w", err)
+	}
+	target.Rotation = rot
+	if seed != nil && len(seed) != dof {
+		return IKResult{}, fmt.Errorf("expected %d seed values, got %d", dof, len(seed))
+	}
+	def := DefaultIKOptions()
+	if opts.MaxIterations <= 0 {
+		opts.MaxIterations = def.MaxIterations
+	}
+	if opts.Damping <= 0 {
+		opts.Damping = def.Damping
+	}
+	if opts.PositionTolerance <= 0 {
+		opts.PositionTolerance = def.PositionTolerance
+	}
+	if opts.OrientationTolerance <= 0 {
+		opts.OrientationTolerance = def.OrientationTolerance
+	}
+	if opts.MaxStep <= 0 {
+		opts.MaxStep = def.MaxStep
+	}
+
+	movable := c.movableJoints()
+	q0 := make([]float64, dof)
+	if seed != nil {
+		copy(q0, seed)
+	} else {
+		for i, j := range movable {
+			if j.Limited() {
+				q0[i] = (j.Lower + j.Upper) / 2
+			}
+		}
+	}
+	clamp(movable, q0)
+
+	// 初期値を変えながら試し、収束した時点で返す（乱数は毎回同じ結果になるよう固定）
+	rng := rand.New(rand.NewSource(1))
+	var best IKResult
+	bestCost := math.Inf(1)
+	total := 0
+	for attempt := 0; attempt <= opts.Restarts; attempt++ {
+		q := q0
+		if attempt > 0 {
+			q = randomConfig(movable, rng)
+		}
+		res := c.solve(target, q, movable, opts)
+		total += res.Iterations
+		cost := res.PositionError
+		if !opts.PositionOnly {
+			cost += res.OrientationError
+		}
+		if cost < bestCost {
+			best, bestCost = res, cost
+		}
+		if res.Converged {
+			break
+		}
+	}
+	best.Iterations = total
+	return best, nil
+}
+
+func (c *Chain) solve(target geom.Transform, q0 []float64, movable []Joint, opts IKOptions) IKResult {
+	q := append([]float64(nil), q0...)
+	rows := 6
+	if opts.PositionOnly {
+		rows = 3
+	}
+	res := IKResult{}
+	for it := 0; it < opts.MaxIterations; it++ {
+		pose, jac := c.jacobian(q)
+		ep := target.Translation.Sub(pose.Translation)
+		eo := rotationError(target.Rotation, pose.Rotation)
+		res.Q, res.Iterations = append([]float64(nil), q...), it
+		res.PositionError, res.OrientationError = ep.Norm(), eo.Norm()
+		if res.PositionError <= opts.PositionTolerance && (opts.PositionOnly || res.OrientationError <= opts.OrientationTolerance) {
+			res.Converged = true
+			return res
+		}
+
+		e := []float64{ep.X, ep.Y, ep.Z, eo.X, eo.Y, eo.Z}[:rows]
+		// dq = Jᵀ (J Jᵀ + λ² I)⁻¹ e
+		a := make([][]float64, rows)
+		for r := 0; r < rows; r++ {
+			a[r] = make([]float64, rows)
+			for s := 0; s < rows; s++ {
+				sum := 0.0
+				for k := range q {
+					sum += jac[r][k] * jac[s][k]
+				}
+				a[r][s] = sum
+			}
+			a[r][r] += opts.Damping * opts.Damping
+		}
+		y, ok := solveLinear(a, e)
+		if !ok {
+			break
+		}
+		maxAbs := 0.0
+		dq := make([]float64, len(q))
+		for k := range q {
+			for r := 0; r < rows; r++ {
+				dq[k] += jac[r][k] * y[r]
+			}
+			maxAbs = math.Max(maxAbs, math.Abs(dq[k]))
+		}
+		scale := 1.0
+		if maxAbs > opts.MaxStep {
+			scale = opts.MaxStep / maxAbs
+		}
+		for k := range q {
+			q[k] += dq[k] * scale
+		}
+		clamp(movable, q)
+	}
+	// 最後の更新後の誤差で結果を確定する
+	pose, _ := c.FK(q)
+	res.Q = q
+	res.Iterations = opts.MaxIterations
+	res.PositionError = target.Translation.Sub(pose.Translation).Norm()
+	res.OrientationError = rotationError(target.Rotation, pose.Rotation).Norm()
+	res.Converged = res.PositionError <= opts.PositionTolerance && (opts.PositionOnly || res.OrientationError <= opts.OrientationTolerance)
+	return res
+}
+
+// jacobian は手先姿勢と 6×DOF のヤコビ行列（並進3行・回転3行）を返します
+func (c *Chain) jacobian(q []float64) (geom.Transform, [6][]float64) {
+	var jac [6][]float64
+	for r := range jac {
+		jac[r] = make([]float64, len(q))
+	}
+	type axisAt struct {
+		pos, axis geom.Vec3
+		prismatic bool
+	}
+	axes := make([]axisAt, 0, len(q))
+	acc := geom.IdentityTransform()
+	k := 0
+	for _, j := range c.Joints {
+		v := 0.0
+		if j.Movable() {
+			// 関節の軸は origin を適用した直後の座標系で定義される
+			before := acc.Mul(j.Origin)
+			axes = append(axes, axisAt{
+				pos:       before.Translation,
+				axis:      before.Rotation.Rotate(j.Axis.Scale(1 / j.Axis.Norm())),
+				prismatic: j.Type == Prismatic,
+			})
+			v = q[k]
+			k++
+		}
+		acc = acc.Mul(j.Transform(v))
+	}
+	for i, a := range axes {
+		var lin, ang geom.Vec3
+		if a.prismatic {
+			lin = a.axis
+		} else {
+			lin = a.axis.Cross(acc.Translation.Sub(a.pos))
+			ang = a.axis
+		}
+		jac[0][i], jac[1][i], jac[2][i] = lin.X, lin.Y, lin.Z
+		jac[3][i], jac[4][i], jac[5][i] = ang.X, ang.Y, ang.Z
+	}
+	return acc, jac
+}
+
+func (c *Chain) movableJoints() []Joint {
+	var js []Joint
+	for _, j := range c.Joints {
+		if j.Movable() {
+			js = append(js, j)
+		}
+	}
+	return js
+}
+
+// rotationError は current から target への回転を回転ベクトル（軸×角度）で返します
+func rotationError(target, current geom.Quaternion) geom.Vec3 {
+	d := target.Mul(current.Conj())
+	if d.W < 0 {
+		d = geom.Quaternion{X: -d.X, Y: -d.Y, Z: -d.Z, W: -d.W}
+	}
+	v := geom.Vec3{X: d.X, Y: d.Y, Z: d.Z}
+	s := v.Norm()
+	if s < 1e-12 {
+		return geom.Vec3{}
+	}
+	angle := 2 * math.Atan2(s, d.W)
+	return v.Scale(angle / s)
+}
+
+func clamp(js []Joint, q []float64) {
+	for i, j := range js {
+		if j.Limited() {
+			q[i] = math.Min(math.Max(q[i], j.Lower), j.Upper)
+		}
+	}
+}
+
+func randomConfig(js []Joint, rng *rand.Rand) []float64 {
+	q := make([]float64, len(js))
+	for i, j := range js {
+		switch {
+		case j.Limited():
+			q[i] = j.Lower + rng.Float64()*(j.Upper-j.Lower)
+		case j.Type == Prismatic:
+			q[i] = 0
+		default:
+			q[i] = (rng.Float64()*2 - 1) * math.Pi
+		}
+	}
+	return q
+}
+
+// solveLinear は部分ピボット付きガウスの消去法で a x = b を解きます（a は正方行列、書き換える）
+func solveLinear(a [][]float64, b []float64) ([]float64, bool) {
+	n := len(b)
+	x := append([]float64(nil), b...)
+	for col := 0; col < n; col++ {
+		piv := col
+		for r := col + 1; r < n; r++ {
+			if math.Abs(a[r][col]) > math.Abs(a[piv][col]) {
+				piv = r
+			}
+		}
+		if math.Abs(a[piv][col]) < 1e-12 {
+			return nil, false
+		}
+		a[col], a[piv] = a[piv], a[col]
+		x[col], x[piv] = x[piv], x[col]
+		for r := col + 1; r < n; r++ {
+			f := a[r][col] / a[col][col]
+			for k := col; k < n; k++ {
+				a[r][k] -= f * a[col][k]
+			}
+			x[r] -= f * x[col]
+		}
+	}
+	for r := n - 1; r >= 0; r-- {
+		for k := r + 1; k < n; k++ {
+			x[r] -= a[r][k] * x[k]
+		}
+		x[r] /= a[r][r]
+	}
+	return x, true
+}
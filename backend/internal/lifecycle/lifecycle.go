@@ -0,0 +1,65 @@
+// internal/lifecycle/lifecycle.go
+package lifecycle
+
+// lifecycleはROSにもginにも依存しないように書く（lifecycle_msgs のサービス呼び出しは robot、HTTP は api パッケージ側）
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrTransitionUnavailable は今の状態から行えない遷移を指定した場合に返ります
+var ErrTransitionUnavailable = errors.New("transition not available")
+
+// State はノードの状態です
+type State struct {
+	ID    uint8  `json:"id"`
+	Label string `json:"label"`
+}
+
+// Transition は今の状態から行える遷移です
+type Transition struct {
+	ID    uint8  `json:"id"`
+	Label string `json:"label"`
+	Goal  State  `json:"goal"` // 成功したときの状態
+}
+
+// Node は管理対象のノードの状態です
+type Node struct {
+	Name        string       `json:"name"` // "/ns/name"
+	State       *State       `json:"state,omitempty"`
+	Transitions []Transition `json:"transitions,omitempty"`
+	Error       string       `json:"error,omitempty"` // 状態を取得できなかった理由
+}
+
+// Result は遷移の結果です
+type Result struct {
+	Node       string     `json:"node"`
+	Transition Transition `json:"transition"`
+	Success    bool       `json:"success"`
+	From       State      `json:"from"`
+	To         *State     `json:"to,omitempty"` // 遷移後に取得した状態（取得できなければ無し）
+}
+
+// Resolve は名前（"activate" など）または ID（"3"）で available から遷移を選びます
+// "shutdown" は今の状態に応じた unconfigured_shutdown / inactive_shutdown / active_shutdown になる
+func Resolve(available []Transition, name string) (Transition, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	for _, t := range available {
+		if strings.ToLower(t.Label) == name || fmt.Sprint(t.ID) == name {
+			return t, nil
+		}
+	}
+	if name == "shutdown" {
+		for _, t := range available {
+			if strings.HasSuffix(strings.ToLower(t.Label), "shutdown") {
+				return t, nil
+			}
+		}
+	}
+	labels := make([]string, len(available))
+	for i, t := range available {
+		labels[i] = t.Label
+	}
+	return Transition{}, fmt.Errorf("%w: %q (available: %s)", ErrTransitionUnavailable, name, strings.Join(labels, ", "))
+}
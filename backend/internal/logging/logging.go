@@ -0,0 +1,261 @@
+// internal/logging/logging.go
+package logging
+
+// loggingはROSにもginにも依存しないように書く（リクエストIDの付与は api、ROS のロガーへの反映は robot パッケージ側）
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// ErrUnknownComponent は存在しないコンポーネントのレベルを変えようとした場合に返ります
+var ErrUnknownComponent = errors.New("unknown log component")
+
+// コンポーネント名。ログの component に入り、レベルはコンポーネントごとに変えられる
+const (
+	ComponentMain  = "main"  // 起動・停止
+	ComponentHTTP  = "http"  // アクセスログ
+	ComponentRobot = "robot" // 指令の Publish と ROS の購読（ROS のロガーにも出す）
+)
+
+// Config はログの設定です
+type Config struct {
+	Level      string            `yaml:"level"`      // debug / info / warn / error
+	Format     string            `yaml:"format"`     // json / text
+	Components map[string]string `yaml:"components"` // コンポーネントごとのレベル（省略すると level）
+}
+
+type component struct {
+	level    slog.LevelVar
+	onChange []func(slog.Level)
+}
+
+var (
+	mu sync.Mutex
+	// Setup までは標準エラー出力に JSON で書く（レベルの既定値は info）
+	base       slog.Handler = slog.NewJSONHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelDebug})
+	components              = map[string]*component{ComponentMain: {}, ComponentHTTP: {}, ComponentRobot: {}}
+	loggers                 = map[string]*slog.Logger{}
+)
+
+// Setup は出力形式とレベルを設定し、slog と標準の log の出力先を For(ComponentMain) にします
+func Setup(cfg Config, w io.Writer) error {
+	def, err := ParseLevel(cfg.Level)
+	if err != nil {
+		return err
+	}
+	opts := &slog.HandlerOptions{Level: slog.LevelDebug} // レベルは component ごとに判定する
+	var h slog.Handler
+	switch strings.ToLower(cfg.Format) {
+	case "", "json":
+		h = slog.NewJSONHandler(w, opts)
+	case "text":
+		h = slog.NewTextHandler(w, opts)
+	default:
+		return fmt.Errorf("unknown log format %q (json / text)", cfg.Format)
+	}
+
+	levels := map[string]slog.Level{}
+	for name, s := range cfg.Components {
+		l, err := ParseLevel(s)
+		if err != nil {
+			return fmt.Errorf("components.%s: %w", name, err)
+		}
+		levels[name] = l
+	}
+
+	mu.Lock()
+	base = h
+	for name := range levels {
+		if components[name] == nil {
+			components[name] = &component{}
+		}
+	}
+	mu.Unlock()
+	for name := range Components() {
+		l, ok := levels[name]
+		if !ok {
+			l = def
+		}
+		_ = SetLevel(name, l)
+	}
+	slog.SetDefault(For(ComponentMain))
+	return nil
+}
+
+// For は component 用のロガーを返します。ctx にリクエストIDがあれば request_id として出力します
+func For(name string) *slog.Logger {
+	mu.Lock()
+	defer mu.Unlock()
+	if l := loggers[name]; l != nil {
+		return l
+	}
+	comp := components[name]
+	if comp == nil {
+		comp = &component{}
+		components[name] = comp
+	}
+	l := slog.New(&handler{comp: comp}).With("component", name)
+	loggers[name] = l
+	return l
+}
+
+// SetLevel は component のレベルを変えます
+func SetLevel(name string, level slog.Level) error {
+	mu.Lock()
+	comp := components[name]
+	var hooks []func(slog.Level)
+	if comp != nil {
+		comp.level.Set(level)
+		hooks = append(hooks, comp.onChange...)
+	}
+	mu.Unlock()
+	if comp == nil {
+		return fmt.Errorf("%w %q", ErrUnknownComponent, name)
+	}
+	for _, fn := range hooks {
+		fn(level)
+	}
+	return nil
+}
+
+// OnLevelChange は component のレベルが変わったときに fn を呼びます（ROS のロガーへの反映用）
+// 登録時にも現在のレベルで1回呼ぶ
+func OnLevelChange(name string, fn func(slog.Level)) {
+	mu.Lock()
+	comp := components[name]
+	if comp == nil {
+		comp = &component{}
+		components[name] = comp
+	}
+	comp.onChange = append(comp.onChange, fn)
+	level := comp.level.Level()
+	mu.Unlock()
+	fn(level)
+}
+
+// Components はコンポーネントごとの現在のレベルを返します
+func Components() map[string]string {
+	mu.Lock()
+	defer mu.Unlock()
+	out := make(map[string]string, len(components))
+	for name, comp := range components {
+		out[name] = LevelName(comp.level.Level())
+	}
+	return out
+}
+
+// ComponentNames はコンポーネント名を名前順に返します
+func ComponentNames() []string {
+	levels := Components()
+	names := make([]string, 0, len(levels))
+	for name := range levels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ParseLevel は debug / info / warn / error を slog.Level にします（空なら info）
+func ParseLevel(s string) (slog.Level, error) {
+	switch strings.ToLower(s) {
+	case "debug":
+		return slog.LevelDebug, nil
+	case "", "info":
+		return slog.LevelInfo, nil
+	case "warn", "warning":
+		return slog.LevelWarn, nil
+	case "error":
+		return slog.LevelError, nil
+	}
+	return 0, fmt.Errorf("unknown log level %q (debug / info / warn / error)", s)
+}
+
+// LevelName は slog.Level を ParseLevel で読める名前にします
+func LevelName(l slog.Level) string {
+	switch {
+	case l < slog.LevelInfo:
+		return "debug"
+	case l < slog.LevelWarn:
+		return "info"
+	case l < slog.LevelError:
+		return "warn"
+	}
+	return "error"
+}
+
+// ---- リクエストID ----
+
+type requestIDKey struct{}
+
+// WithRequestID は ctx にリクエストIDを入れます
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// RequestID は ctx のリクエストIDを返します（無ければ空文字列）
+func RequestID(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// NewRequestID はランダムなリクエストIDを作ります
+func NewRequestID() string {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "unknown"
+	}
+	return hex.EncodeToString(b)
+}
+
+// ---- slog.Handler ----
+
+// handler はコンポーネントのレベルで判定し、ctx のリクエストIDを加えて Setup の出力先に書きます
+// Setup より前に作られたロガーでも Setup 後の出力先に書けるよう、With・WithGroup は出力のたびに base へ適用する
+type handler struct {
+	comp *component
+	ops  []func(slog.Handler) slog.Handler
+}
+
+func (h *handler) Enabled(_ context.Context, l slog.Level) bool {
+	return l >= h.comp.level.Level()
+}
+
+func (h *handler) Handle(ctx context.Context, r slog.Record) error {
+	if id := RequestID(ctx); id != "" {
+		r.AddAttrs(slog.String("request_id", id))
+	}
+	mu.Lock()
+	b := base
+	mu.Unlock()
+	for _, op := range h.ops {
+		b = op(b)
+	}
+	return b.Handle(ctx, r)
+}
+
+func (h *handler) with(op func(slog.Handler) slog.Handler) *handler {
+	return &handler{comp: h.comp, ops: append(append([]func(slog.Handler) slog.Handler(nil), h.ops...), op)}
+}
+
+func (h *handler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return h.with(func(b slog.Handler) slog.Handler { return b.WithAttrs(attrs) })
+}
+
+func (h *handler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	return h.with(func(b slog.Handler) slog.Handler { return b.WithGroup(name) })
+}
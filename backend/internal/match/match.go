@@ -0,0 +1,331 @@
+// internal/match/match.go
+package match
+
+// matchはROSにもginにも依存しないように書く（指令の拒否は robot、HTTP と SSE は api パッケージ側）
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+)
+
+// 試合の状態
+const (
+	StateIdle     = "idle"     // 試合をしていない
+	StateSetting  = "setting"  // セッティングタイム（setup_s から減る）
+	StateReady    = "ready"    // セッティングが終わり、開始を待っている
+	StateRunning  = "running"  // 競技中（length_s から減る）
+	StateFinished = "finished" // 時間切れ。reset するまで指令を受け付けない
+)
+
+// エラー
+var (
+	// ErrInvalidTransition は今の状態で行えない操作（終了後の start など）の場合に返ります
+	ErrInvalidTransition = errors.New("invalid match transition")
+	// ErrMotionNotAllowed は試合の状態が指令を許さない場合に返ります
+	ErrMotionNotAllowed = errors.New("motion not allowed in current match state")
+)
+
+// tickInterval は時間切れを確かめる間隔です
+const tickInterval = 100 * time.Millisecond
+
+// subscriberBuffer は購読者ごとのチャネルの長さです。溢れた分は捨てる（次の通知で追いつく）
+const subscriberBuffer = 16
+
+// Config は試合の時間と指令の制限の設定です
+type Config struct {
+	SetupS    float64 `yaml:"setup_s"`    // セッティングタイム（0ならすぐ ready）
+	LengthS   float64 `yaml:"length_s"`   // 競技時間
+	AllowIdle bool    `yaml:"allow_idle"` // 試合をしていない（idle）ときも指令を許す（練習用。既定は拒否）
+}
+
+// DefaultConfig は既定の設定です
+func DefaultConfig() Config {
+	return Config{SetupS: 60, LengthS: 180}
+}
+
+// Status は試合の今の状態です
+type Status struct {
+	Seq           uint64    `json:"seq"` // 状態が変わるたびに増える
+	State         string    `json:"state"`
+	Paused        bool      `json:"paused"`
+	RemainingS    float64   `json:"remaining_s"` // setting / running の残り時間
+	PhaseS        float64   `json:"phase_s"`     // setting / running の長さ
+	MotionAllowed bool      `json:"motion_allowed"`
+	Reason        string    `json:"reason,omitempty"` // 指令を受け付けない理由
+	Time          time.Time `json:"time"`
+}
+
+// Match は試合の状態と残り時間を持ち、指令を受け付けてよいかを決めます
+type Match struct {
+	cfg    Config
+	onHalt func(reason string)
+
+	mu        sync.Mutex
+	state     string
+	paused    bool
+	phase     time.Duration // 今の段階の長さ
+	remaining time.Duration // resumedAt の時点の残り時間
+	resumedAt time.Time
+	seq       uint64
+	lastSec   int // 最後に配った残り秒数（1秒ごとに配るため）
+	subs      map[chan Status]struct{}
+	now       func() time.Time
+}
+
+// New は Match を作ります。onHalt は指令を受け付けなくなったとき（時間切れ・一時停止など）に呼ばれます（nil 可）
+func New(cfg Config, onHalt func(reason string)) *Match {
+	return &Match{
+		cfg:    cfg,
+		onHalt: onHalt,
+		state:  StateIdle,
+		subs:   map[chan Status]struct{}{},
+		now:    time.Now,
+	}
+}
+
+// Config は設定を返します
+func (m *Match) Config() Config { return m.cfg }
+
+// Start は状態に応じて次へ進めます
+// idle → setting、setting → ready（セッティングを早めに終える）、ready → running、一時停止中なら再開
+func (m *Match) Start() (Status, error) {
+	return m.transition("started", func(now time.Time) error {
+		switch {
+		case m.paused:
+			m.paused = false
+			m.resumedAt = now
+		case m.state == StateIdle:
+			m.enter(StateSetting, m.cfg.SetupS, now)
+			if m.phase <= 0 {
+				m.enter(StateReady, 0, now)
+			}
+		case m.state == StateSetting:
+			m.enter(StateReady, 0, now)
+		case m.state == StateReady:
+			m.enter(StateRunning, m.cfg.LengthS, now)
+		case m.state == StateRunning:
+			return fmt.Errorf("%w: match is already running", ErrInvalidTransition)
+		default:
+			return fmt.Errorf("%w: match is %s, reset first", ErrInvalidTransition, m.state)
+		}
+		return nil
+	})
+}
+
+// Pause は setting / running の時計を止めます（再開は Start）
+func (m *Match) Pause() (Status, error) {
+	return m.transition("paused", func(now time.Time) error {
+		if m.state != StateSetting && m.state != StateRunning {
+			return fmt.Errorf("%w: cannot pause when %s", ErrInvalidTransition, m.state)
+		}
+		if m.paused {
+			return fmt.Errorf("%w: match is already paused", ErrInvalidTransition)
+		}
+		m.remaining = m.remainingLocked(now)
+		m.paused = true
+		return nil
+	})
+}
+
+// Reset はどの状態からでも idle に戻します
+func (m *Match) Reset() (Status, error) {
+	return m.transition("reset", func(now time.Time) error {
+		m.paused = false
+		m.enter(StateIdle, 0, now)
+		return nil
+	})
+}
+
+// enter は state に移り、length 秒の時計を始めます（呼び出し側で mu を保持）
+func (m *Match) enter(state string, lengthS float64, now time.Time) {
+	m.state = state
+	m.phase = time.Duration(lengthS * float64(time.Second))
+	m.remaining = m.phase
+	m.resumedAt = now
+}
+
+// transition は fn で状態を変え、変わったら配ります。指令を受け付けなくなったら onHalt を呼ぶ
+func (m *Match) transition(reason string, fn func(now time.Time) error) (Status, error) {
+	m.mu.Lock()
+	now := m.now()
+	// Tick より先に時間切れになっていれば、それを反映してから操作する
+	expired := m.expireLocked(now)
+	wasAllowed := m.allowLocked() == nil
+	if err := fn(now); err != nil {
+		st := m.statusLocked(now)
+		m.mu.Unlock()
+		m.halt(expired)
+		return st, err
+	}
+	st := m.changedLocked(now)
+	halted := wasAllowed && m.allowLocked() != nil
+	m.mu.Unlock()
+	m.halt(expired)
+	if halted {
+		m.halt("match " + reason)
+	}
+	return st, nil
+}
+
+// expireLocked は時計が0になっていれば次の状態へ進めます（呼び出し側で mu を保持）
+// setting の時間切れで ready、running の時間切れで finished になる。指令を受け付けなくなったら onHalt に渡す理由を返す
+func (m *Match) expireLocked(now time.Time) string {
+	if !m.timedLocked() || m.remainingLocked(now) > 0 {
+		return ""
+	}
+	wasAllowed := m.allowLocked() == nil
+	reason := "setup time is over"
+	if m.state == StateSetting {
+		m.enter(StateReady, 0, now)
+	} else {
+		m.enter(StateFinished, 0, now)
+		reason = "time is up"
+	}
+	m.changedLocked(now)
+	if wasAllowed && m.allowLocked() != nil {
+		return "match " + reason
+	}
+	return ""
+}
+
+// halt は reason が空でなければ onHalt を呼びます（mu を放してから呼ぶ）
+func (m *Match) halt(reason string) {
+	if reason != "" && m.onHalt != nil {
+		m.onHalt(reason)
+	}
+}
+
+// changedLocked は状態が変わったことを記録して購読者に配ります（呼び出し側で mu を保持）
+func (m *Match) changedLocked(now time.Time) Status {
+	m.seq++
+	st := m.statusLocked(now)
+	m.broadcastLocked(st)
+	return st
+}
+
+func (m *Match) broadcastLocked(st Status) {
+	m.lastSec = int(math.Ceil(st.RemainingS))
+	for ch := range m.subs {
+		select {
+		case ch <- st:
+		default:
+		}
+	}
+}
+
+// timedLocked は時計が進んでいるか（setting / running で一時停止していない）を返します
+func (m *Match) timedLocked() bool {
+	return (m.state == StateSetting || m.state == StateRunning) && !m.paused
+}
+
+func (m *Match) remainingLocked(now time.Time) time.Duration {
+	if !m.timedLocked() {
+		return m.remaining
+	}
+	return max(m.remaining-now.Sub(m.resumedAt), 0)
+}
+
+// Tick は時間切れを確かめて次の状態へ進め、残り秒数が変わったら配ります（定期的に呼ぶ）
+func (m *Match) Tick() {
+	m.mu.Lock()
+	now := m.now()
+	expired := m.expireLocked(now)
+	if expired == "" && m.timedLocked() {
+		st := m.statusLocked(now)
+		if int(math.Ceil(st.RemainingS)) != m.lastSec {
+			m.broadcastLocked(st)
+		}
+	}
+	m.mu.Unlock()
+	m.halt(expired)
+}
+
+// Run は ctx が終わるまで Tick を呼びます
+func (m *Match) Run(ctx context.Context) {
+	t := time.NewTicker(tickInterval)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+			m.Tick()
+		}
+	}
+}
+
+// Allow は今の状態で指令を送ってよければ nil を返します
+// setting / running（一時停止中を除く）と、allow_idle なら idle で許す。時間切れ後は reset するまで拒否する
+// Tick を待たずにここでも残り時間を確かめるので、時間切れはその瞬間から効く
+func (m *Match) Allow() error {
+	m.mu.Lock()
+	expired := m.expireLocked(m.now())
+	err := m.allowLocked()
+	m.mu.Unlock()
+	if expired != "" {
+		// Allow は Publish の途中（速度ジョグのロックを保持したまま）でも呼ばれるので、onHalt は別の goroutine で呼ぶ
+		go m.halt(expired)
+	}
+	return err
+}
+
+func (m *Match) allowLocked() error {
+	switch {
+	case m.paused:
+		return fmt.Errorf("%w: match is paused", ErrMotionNotAllowed)
+	case m.state == StateSetting, m.state == StateRunning:
+		return nil
+	case m.state == StateIdle && m.cfg.AllowIdle:
+		return nil
+	case m.state == StateFinished:
+		return fmt.Errorf("%w: time is up", ErrMotionNotAllowed)
+	}
+	return fmt.Errorf("%w: match is %s", ErrMotionNotAllowed, m.state)
+}
+
+// Status は今の状態を返します
+func (m *Match) Status() Status {
+	m.mu.Lock()
+	now := m.now()
+	expired := m.expireLocked(now)
+	st := m.statusLocked(now)
+	m.mu.Unlock()
+	m.halt(expired)
+	return st
+}
+
+func (m *Match) statusLocked(now time.Time) Status {
+	st := Status{
+		Seq:        m.seq,
+		State:      m.state,
+		Paused:     m.paused,
+		RemainingS: m.remainingLocked(now).Seconds(),
+		PhaseS:     m.phase.Seconds(),
+		Time:       now,
+	}
+	if err := m.allowLocked(); err != nil {
+		st.Reason = err.Error()
+	} else {
+		st.MotionAllowed = true
+	}
+	return st
+}
+
+// Subscribe は状態の変化と残り秒数（1秒ごと）を受け取るチャネルと、購読をやめる関数を返します
+func (m *Match) Subscribe() (<-chan Status, func()) {
+	ch := make(chan Status, subscriberBuffer)
+	m.mu.Lock()
+	m.subs[ch] = struct{}{}
+	m.mu.Unlock()
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() {
+			m.mu.Lock()
+			delete(m.subs, ch)
+			m.mu.Unlock()
+		})
+	}
+}
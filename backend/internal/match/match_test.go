@@ -0,0 +1,244 @@
+// internal/match/match_test.go
+package match
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+// clock は進め方をテストで決める時計です
+type clock struct {
+	mu sync.Mutex
+	t  time.Time
+}
+
+func (c *clock) now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.t
+}
+
+func (c *clock) advance(d time.Duration) {
+	c.mu.Lock()
+	c.t = c.t.Add(d)
+	c.mu.Unlock()
+}
+
+// halts は onHalt に渡された理由を集めます（Allow からは別の goroutine で呼ばれる）
+type halts struct {
+	ch chan string
+}
+
+func (h *halts) onHalt(reason string) { h.ch <- reason }
+
+// wait は理由が届くのを待ちます
+func (h *halts) wait(t *testing.T) string {
+	t.Helper()
+	select {
+	case r := <-h.ch:
+		return r
+	case <-time.After(time.Second):
+		t.Fatal("onHalt was not called")
+		return ""
+	}
+}
+
+func (h *halts) none(t *testing.T) {
+	t.Helper()
+	select {
+	case r := <-h.ch:
+		t.Fatalf("unexpected onHalt(%q)", r)
+	case <-time.After(20 * time.Millisecond):
+	}
+}
+
+func newTestMatch(cfg Config) (*Match, *clock, *halts) {
+	c := &clock{t: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)}
+	h := &halts{ch: make(chan string, 8)}
+	m := New(cfg, h.onHalt)
+	m.now = c.now
+	return m, c, h
+}
+
+func mustStart(t *testing.T, m *Match, want string) {
+	t.Helper()
+	st, err := m.Start()
+	if err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	if st.State != want {
+		t.Fatalf("Start() state = %s, want %s", st.State, want)
+	}
+}
+
+func TestDefaultRejectsIdle(t *testing.T) {
+	tests := []struct {
+		name      string
+		allowIdle bool
+		wantErr   bool
+	}{
+		{"default", false, true},
+		{"allow_idle", true, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := DefaultConfig()
+			cfg.AllowIdle = tt.allowIdle
+			m, _, _ := newTestMatch(cfg)
+			err := m.Allow()
+			if got := err != nil; got != tt.wantErr {
+				t.Fatalf("Allow() in idle = %v, want error %t", err, tt.wantErr)
+			}
+			if tt.wantErr && !errors.Is(err, ErrMotionNotAllowed) {
+				t.Fatalf("Allow() error = %v, want ErrMotionNotAllowed", err)
+			}
+			if st := m.Status(); st.MotionAllowed == tt.wantErr {
+				t.Fatalf("Status().MotionAllowed = %t", st.MotionAllowed)
+			}
+		})
+	}
+}
+
+func TestAllowByState(t *testing.T) {
+	m, _, _ := newTestMatch(Config{SetupS: 60, LengthS: 180})
+	steps := []struct {
+		op      func() (Status, error)
+		state   string
+		allowed bool
+	}{
+		{m.Start, StateSetting, true},
+		{m.Start, StateReady, false},
+		{m.Start, StateRunning, true},
+		{m.Pause, StateRunning, false},
+		{m.Start, StateRunning, true},
+		{m.Reset, StateIdle, false},
+	}
+	for i, s := range steps {
+		st, err := s.op()
+		if err != nil {
+			t.Fatalf("step %d: error = %v", i, err)
+		}
+		if st.State != s.state {
+			t.Fatalf("step %d: state = %s, want %s", i, st.State, s.state)
+		}
+		if err := m.Allow(); (err == nil) != s.allowed {
+			t.Fatalf("step %d (%s paused=%t): Allow() = %v, want allowed %t", i, st.State, st.Paused, err, s.allowed)
+		}
+	}
+}
+
+func TestAllowExpiresWithoutTick(t *testing.T) {
+	m, c, h := newTestMatch(Config{SetupS: 10, LengthS: 30})
+	mustStart(t, m, StateSetting)
+
+	c.advance(10*time.Second - time.Millisecond)
+	if err := m.Allow(); err != nil {
+		t.Fatalf("Allow() just before setup ends = %v", err)
+	}
+	// Tick を呼ばなくても時間切れの瞬間から拒否する
+	c.advance(time.Millisecond)
+	if err := m.Allow(); !errors.Is(err, ErrMotionNotAllowed) {
+		t.Fatalf("Allow() when setup ends = %v, want ErrMotionNotAllowed", err)
+	}
+	if r := h.wait(t); r != "match setup time is over" {
+		t.Fatalf("onHalt(%q)", r)
+	}
+	if st := m.Status(); st.State != StateReady {
+		t.Fatalf("state = %s, want %s", st.State, StateReady)
+	}
+
+	mustStart(t, m, StateRunning)
+	c.advance(30 * time.Second)
+	err := m.Allow()
+	if !errors.Is(err, ErrMotionNotAllowed) {
+		t.Fatalf("Allow() when time is up = %v, want ErrMotionNotAllowed", err)
+	}
+	if r := h.wait(t); r != "match time is up" {
+		t.Fatalf("onHalt(%q)", r)
+	}
+	// 時間切れの後は何度聞いても止める通知は1回だけ
+	if err := m.Allow(); err == nil {
+		t.Fatal("Allow() after time is up = nil")
+	}
+	h.none(t)
+	if st := m.Status(); st.State != StateFinished || st.RemainingS != 0 {
+		t.Fatalf("status = %s remaining %.3f, want finished with 0", st.State, st.RemainingS)
+	}
+}
+
+func TestPauseStopsTheClock(t *testing.T) {
+	m, c, h := newTestMatch(Config{SetupS: 0, LengthS: 30})
+	mustStart(t, m, StateReady)
+	mustStart(t, m, StateRunning)
+
+	c.advance(10 * time.Second)
+	if _, err := m.Pause(); err != nil {
+		t.Fatalf("Pause() error = %v", err)
+	}
+	if r := h.wait(t); r != "match paused" {
+		t.Fatalf("onHalt(%q)", r)
+	}
+	if _, err := m.Pause(); !errors.Is(err, ErrInvalidTransition) {
+		t.Fatalf("Pause() twice error = %v, want ErrInvalidTransition", err)
+	}
+	// 一時停止中は時計が進まない
+	c.advance(time.Minute)
+	if err := m.Allow(); !errors.Is(err, ErrMotionNotAllowed) {
+		t.Fatalf("Allow() while paused = %v, want ErrMotionNotAllowed", err)
+	}
+	if st := m.Status(); st.State != StateRunning || st.RemainingS != 20 {
+		t.Fatalf("paused status = %s remaining %.3f, want running with 20", st.State, st.RemainingS)
+	}
+
+	mustStart(t, m, StateRunning)
+	c.advance(20*time.Second - time.Millisecond)
+	if err := m.Allow(); err != nil {
+		t.Fatalf("Allow() before the resumed clock ends = %v", err)
+	}
+	c.advance(time.Millisecond)
+	if err := m.Allow(); !errors.Is(err, ErrMotionNotAllowed) {
+		t.Fatalf("Allow() after the resumed clock ends = %v, want ErrMotionNotAllowed", err)
+	}
+	h.wait(t)
+}
+
+func TestTransitionAppliesExpiryFirst(t *testing.T) {
+	m, c, h := newTestMatch(Config{SetupS: 0, LengthS: 30})
+	mustStart(t, m, StateReady)
+	mustStart(t, m, StateRunning)
+	c.advance(time.Minute)
+
+	// 時間切れを Tick より先に反映するので、running のつもりの start は finished から断られる
+	st, err := m.Start()
+	if !errors.Is(err, ErrInvalidTransition) {
+		t.Fatalf("Start() after time is up error = %v, want ErrInvalidTransition", err)
+	}
+	if st.State != StateFinished {
+		t.Fatalf("state = %s, want %s", st.State, StateFinished)
+	}
+	if r := h.wait(t); r != "match time is up" {
+		t.Fatalf("onHalt(%q)", r)
+	}
+	if _, err := m.Pause(); !errors.Is(err, ErrInvalidTransition) {
+		t.Fatalf("Pause() when finished error = %v, want ErrInvalidTransition", err)
+	}
+	if st, err := m.Reset(); err != nil || st.State != StateIdle {
+		t.Fatalf("Reset() = %s, %v", st.State, err)
+	}
+	h.none(t)
+}
+
+func TestTickExpires(t *testing.T) {
+	m, c, h := newTestMatch(Config{SetupS: 5, LengthS: 30})
+	mustStart(t, m, StateSetting)
+	c.advance(5 * time.Second)
+	m.Tick()
+	if r := h.wait(t); r != "match setup time is over" {
+		t.Fatalf("onHalt(%q)", r)
+	}
+	if st := m.Status(); st.State != StateReady {
+		t.Fatalf("state = %s, want %s", st.State, StateReady)
+	}
+}
@@ -0,0 +1,325 @@
+// internal/metrics/metrics.go
+package metrics
+
+// metricsはROSにもginにも依存しないように書く（Prometheus のテキスト形式だけを出力する最小限の実装）
+import (
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Registry は出力するメトリクスの一覧です
+type Registry struct {
+	mu         sync.Mutex
+	collectors []collector
+	names      map[string]bool
+}
+
+type collector interface {
+	name() string
+	write(w io.Writer)
+}
+
+// Default はパッケージ関数 NewCounterVec などが登録するレジストリです
+var Default = NewRegistry()
+
+func NewRegistry() *Registry {
+	return &Registry{names: map[string]bool{}}
+}
+
+func (r *Registry) register(c collector) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.names[c.name()] {
+		panic("metrics: duplicate metric " + c.name())
+	}
+	r.names[c.name()] = true
+	r.collectors = append(r.collectors, c)
+}
+
+// WriteText は Prometheus のテキスト形式（0.0.4）で書き出します
+func (r *Registry) WriteText(w io.Writer) {
+	r.mu.Lock()
+	cs := append([]collector(nil), r.collectors...)
+	r.mu.Unlock()
+	sort.Slice(cs, func(i, j int) bool { return cs[i].name() < cs[j].name() })
+	for _, c := range cs {
+		c.write(w)
+	}
+}
+
+// ContentType は WriteText の出力の Content-Type です
+const ContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// ---- 共通 ----
+
+type desc struct {
+	n      string
+	help   string
+	typ    string
+	labels []string
+}
+
+func (d desc) name() string { return d.n }
+
+func (d desc) header(w io.Writer) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", d.n, strings.ReplaceAll(d.help, "\n", " "), d.n, d.typ)
+}
+
+// labelKey はラベル値の組を map のキーにします
+func (d desc) labelKey(values []string) string {
+	if len(values) != len(d.labels) {
+		panic(fmt.Sprintf("metrics: %s expects %d label values, got %d", d.n, len(d.labels), len(values)))
+	}
+	return strings.Join(values, "\xff")
+}
+
+// formatLabels は {a="x",b="y"} を作ります。extra は histogram の le 用
+func formatLabels(names, values []string, extra ...string) string {
+	if len(names) == 0 && len(extra) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteByte('{')
+	for i, n := range names {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(n)
+		b.WriteString(`="`)
+		b.WriteString(escape(values[i]))
+		b.WriteByte('"')
+	}
+	for i := 0; i+1 < len(extra); i += 2 {
+		if b.Len() > 1 {
+			b.WriteByte(',')
+		}
+		b.WriteString(extra[i])
+		b.WriteString(`="`)
+		b.WriteString(escape(extra[i+1]))
+		b.WriteByte('"')
+	}
+	b.WriteByte('}')
+	return b.String()
+}
+
+func escape(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(s)
+}
+
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+type series struct {
+	values []string
+	v      float64
+}
+
+// vec はラベル値の組ごとの値です（counter と gauge で共通）
+type vec struct {
+	desc
+	mu     sync.Mutex
+	series map[string]*series
+}
+
+func (v *vec) get(values []string) *series {
+	key := v.labelKey(values)
+	s := v.series[key]
+	if s == nil {
+		s = &series{values: append([]string(nil), values...)}
+		v.series[key] = s
+	}
+	return s
+}
+
+func (v *vec) write(w io.Writer) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.header(w)
+	keys := make([]string, 0, len(v.series))
+	for k := range v.series {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		s := v.series[k]
+		fmt.Fprintf(w, "%s%s %s\n", v.n, formatLabels(v.labels, s.values), formatFloat(s.v))
+	}
+}
+
+// ---- Counter ----
+
+// CounterVec は増えるだけの値です
+type CounterVec struct{ vec }
+
+// NewCounterVec は Default に counter を登録します
+func NewCounterVec(name, help string, labels ...string) *CounterVec {
+	c := &CounterVec{vec{desc: desc{name, help, "counter", labels}, series: map[string]*series{}}}
+	Default.register(c)
+	return c
+}
+
+// Add は値を増やします（負の値は無視）
+func (c *CounterVec) Add(delta float64, labelValues ...string) {
+	if delta < 0 {
+		return
+	}
+	c.mu.Lock()
+	c.get(labelValues).v += delta
+	c.mu.Unlock()
+}
+
+// Inc は1増やします
+func (c *CounterVec) Inc(labelValues ...string) { c.Add(1, labelValues...) }
+
+// ---- Gauge ----
+
+// GaugeVec は増減する値です
+type GaugeVec struct{ vec }
+
+// NewGaugeVec は Default に gauge を登録します
+func NewGaugeVec(name, help string, labels ...string) *GaugeVec {
+	g := &GaugeVec{vec{desc: desc{name, help, "gauge", labels}, series: map[string]*series{}}}
+	Default.register(g)
+	return g
+}
+
+func (g *GaugeVec) Set(v float64, labelValues ...string) {
+	g.mu.Lock()
+	g.get(labelValues).v = v
+	g.mu.Unlock()
+}
+
+func (g *GaugeVec) Add(delta float64, labelValues ...string) {
+	g.mu.Lock()
+	g.get(labelValues).v += delta
+	g.mu.Unlock()
+}
+
+// Sample は GaugeFunc が返す1系列分の値です
+type Sample struct {
+	Labels []string
+	Value  float64
+}
+
+// GaugeFunc は出力のたびに fn を呼んで値を作る gauge です（fps など時間で変わる値用）
+type GaugeFunc struct {
+	desc
+	mu sync.Mutex
+	fn func() []Sample
+}
+
+// NewGaugeFunc は Default に gauge を登録します。fn は後から SetFunc で差し替えられます
+func NewGaugeFunc(name, help string, labels []string, fn func() []Sample) *GaugeFunc {
+	g := &GaugeFunc{desc: desc{name, help, "gauge", labels}, fn: fn}
+	Default.register(g)
+	return g
+}
+
+// SetFunc は値を作る関数を差し替えます
+func (g *GaugeFunc) SetFunc(fn func() []Sample) {
+	g.mu.Lock()
+	g.fn = fn
+	g.mu.Unlock()
+}
+
+func (g *GaugeFunc) write(w io.Writer) {
+	g.mu.Lock()
+	fn := g.fn
+	g.mu.Unlock()
+	g.header(w)
+	if fn == nil {
+		return
+	}
+	for _, s := range fn() {
+		if len(s.Labels) != len(g.labels) {
+			continue
+		}
+		fmt.Fprintf(w, "%s%s %s\n", g.n, formatLabels(g.labels, s.Labels), formatFloat(s.Value))
+	}
+}
+
+// ---- Histogram ----
+
+// HistogramVec はバケットごとの件数と合計です
+type HistogramVec struct {
+	desc
+	buckets []float64
+	mu      sync.Mutex
+	series  map[string]*histSeries
+}
+
+type histSeries struct {
+	values []string
+	counts []uint64 // buckets ごと（累積ではない）
+	count  uint64
+	sum    float64
+}
+
+// DefaultDurationBuckets は処理時間 [s] 用のバケットです
+var DefaultDurationBuckets = []float64{0.001, 0.0025, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5}
+
+// NewHistogramVec は Default に histogram を登録します（buckets は昇順）
+func NewHistogramVec(name, help string, buckets []float64, labels ...string) *HistogramVec {
+	h := &HistogramVec{
+		desc:    desc{name, help, "histogram", labels},
+		buckets: append([]float64(nil), buckets...),
+		series:  map[string]*histSeries{},
+	}
+	sort.Float64s(h.buckets)
+	Default.register(h)
+	return h
+}
+
+// Observe は値を1つ記録します
+func (h *HistogramVec) Observe(v float64, labelValues ...string) {
+	key := h.labelKey(labelValues)
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	s := h.series[key]
+	if s == nil {
+		s = &histSeries{values: append([]string(nil), labelValues...), counts: make([]uint64, len(h.buckets))}
+		h.series[key] = s
+	}
+	i := sort.SearchFloat64s(h.buckets, v)
+	if i < len(h.buckets) {
+		s.counts[i]++
+	}
+	s.count++
+	s.sum += v
+}
+
+func (h *HistogramVec) write(w io.Writer) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.header(w)
+	keys := make([]string, 0, len(h.series))
+	for k := range h.series {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		s := h.series[k]
+		var cum uint64
+		for i, b := range h.buckets {
+			cum += s.counts[i]
+			fmt.Fprintf(w, "%s_bucket%s %d\n", h.n, formatLabels(h.labels, s.values, "le", formatFloat(b)), cum)
+		}
+		fmt.Fprintf(w, "%s_bucket%s %d\n", h.n, formatLabels(h.labels, s.values, "le", "+Inf"), s.count)
+		fmt.Fprintf(w, "%s_sum%s %s\n", h.n, formatLabels(h.labels, s.values), formatFloat(s.sum))
+		fmt.Fprintf(w, "%s_count%s %d\n", h.n, formatLabels(h.labels, s.values), s.count)
+	}
+}
@@ -0,0 +1,184 @@
+// internal/params/params.go
+package params
+
+// paramsはROSにもginにも依存しないように書く（rcl_interfaces との変換は robot パッケージ側）
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+)
+
+// ErrInvalidValue は値が型に合わない場合に返ります
+var ErrInvalidValue = errors.New("invalid parameter value")
+
+// 型の名前（rcl_interfaces/msg/ParameterType の PARAMETER_xxx を小文字にしたもの）
+const (
+	TypeNotSet       = "not_set"
+	TypeBool         = "bool"
+	TypeInteger      = "integer"
+	TypeDouble       = "double"
+	TypeString       = "string"
+	TypeByteArray    = "byte_array"
+	TypeBoolArray    = "bool_array"
+	TypeIntegerArray = "integer_array"
+	TypeDoubleArray  = "double_array"
+	TypeStringArray  = "string_array"
+)
+
+// Types は ParameterType の値の順に並べた型の名前です（添字が ROS の値）
+var Types = []string{
+	TypeNotSet, TypeBool, TypeInteger, TypeDouble, TypeString,
+	TypeByteArray, TypeBoolArray, TypeIntegerArray, TypeDoubleArray, TypeStringArray,
+}
+
+// TypeName は ROS の ParameterType の値を名前にします
+func TypeName(t uint8) string {
+	if int(t) < len(Types) {
+		return Types[t]
+	}
+	return TypeNotSet
+}
+
+// TypeID は名前を ROS の ParameterType の値にします
+func TypeID(name string) (uint8, bool) {
+	for i, t := range Types {
+		if t == name {
+			return uint8(i), true
+		}
+	}
+	return 0, false
+}
+
+// Value は型付きのパラメータの値です
+// Value の Go の型は Type に応じて bool / int64 / float64 / string / []byte / []bool / []int64 / []float64 / []string
+type Value struct {
+	Type  string `json:"type"`
+	Value any    `json:"value,omitempty"`
+}
+
+// Parameter は名前と値・説明の組です
+type Parameter struct {
+	Name       string      `json:"name"`
+	Value      Value       `json:"value"`
+	Descriptor *Descriptor `json:"descriptor,omitempty"`
+}
+
+// Descriptor はパラメータの説明と制約です（rcl_interfaces/msg/ParameterDescriptor）
+type Descriptor struct {
+	Type                  string `json:"type"`
+	Description           string `json:"description,omitempty"`
+	AdditionalConstraints string `json:"additional_constraints,omitempty"`
+	ReadOnly              bool   `json:"read_only"`
+	DynamicTyping         bool   `json:"dynamic_typing"`
+	FloatRange            *Range `json:"floating_point_range,omitempty"`
+	IntegerRange          *Range `json:"integer_range,omitempty"`
+}
+
+// Range は値の範囲です（step が0なら連続）
+type Range struct {
+	From float64 `json:"from"`
+	To   float64 `json:"to"`
+	Step float64 `json:"step"`
+}
+
+// SetResult は1つのパラメータの変更結果です（アトミックな変更では全体で1つ）
+type SetResult struct {
+	Name       string `json:"name,omitempty"`
+	Successful bool   `json:"successful"`
+	Reason     string `json:"reason,omitempty"`
+}
+
+// Change は変更したパラメータの前後の値です（監査ログに残す）
+type Change struct {
+	Name string `json:"name"`
+	Old  Value  `json:"old"`
+	New  Value  `json:"new"`
+}
+
+// Update は変更の要求です。Type を省略すると現在の型に合わせる
+type Update struct {
+	Name  string          `json:"name" binding:"required"`
+	Type  string          `json:"type"`
+	Value json.RawMessage `json:"value"`
+}
+
+// Parse は JSON の値を typ の値にします（整数の型に 1.5 を渡すなどはエラー）
+func Parse(typ string, raw json.RawMessage) (Value, error) {
+	v := Value{Type: typ}
+	var err error
+	switch typ {
+	case TypeNotSet:
+		// 未設定に戻す（ノードが許していれば削除になる）
+	case TypeBool:
+		v.Value, err = decode[bool](raw)
+	case TypeInteger:
+		v.Value, err = decodeInt(raw)
+	case TypeDouble:
+		v.Value, err = decode[float64](raw)
+	case TypeString:
+		v.Value, err = decode[string](raw)
+	case TypeByteArray:
+		var ints []int64
+		if ints, err = decodeInts(raw); err == nil {
+			b := make([]byte, len(ints))
+			for i, n := range ints {
+				if n < 0 || n > 255 {
+					return v, fmt.Errorf("%w: byte_array[%d] = %d is out of 0..255", ErrInvalidValue, i, n)
+				}
+				b[i] = byte(n)
+			}
+			v.Value = b
+		}
+	case TypeBoolArray:
+		v.Value, err = decode[[]bool](raw)
+	case TypeIntegerArray:
+		v.Value, err = decodeInts(raw)
+	case TypeDoubleArray:
+		v.Value, err = decode[[]float64](raw)
+	case TypeStringArray:
+		v.Value, err = decode[[]string](raw)
+	default:
+		return v, fmt.Errorf("%w: unknown type %q", ErrInvalidValue, typ)
+	}
+	if err != nil {
+		return v, fmt.Errorf("%w: %s: %v", ErrInvalidValue, typ, err)
+	}
+	return v, nil
+}
+
+func decode[T any](raw json.RawMessage) (T, error) {
+	var v T
+	if len(raw) == 0 {
+		return v, errors.New("value is missing")
+	}
+	err := json.Unmarshal(raw, &v)
+	return v, err
+}
+
+// decodeInt は 3 や 3.0 は受け付け、3.5 は拒否します
+func decodeInt(raw json.RawMessage) (int64, error) {
+	f, err := decode[float64](raw)
+	if err != nil {
+		return 0, err
+	}
+	if f != math.Trunc(f) || math.Abs(f) > 1<<53 {
+		return 0, fmt.Errorf("%v is not an integer", f)
+	}
+	return int64(f), nil
+}
+
+func decodeInts(raw json.RawMessage) ([]int64, error) {
+	fs, err := decode[[]float64](raw)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]int64, len(fs))
+	for i, f := range fs {
+		if f != math.Trunc(f) || math.Abs(f) > 1<<53 {
+			return nil, fmt.Errorf("[%d] = %v is not an integer", i, f)
+		}
+		out[i] = int64(f)
+	}
+	return out, nil
+}
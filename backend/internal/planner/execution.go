@@ -0,0 +1,148 @@
+// internal/planner/execution.go
+package planner
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// 実行の状態
+const (
+	ExecIdle    = "idle"    // 計画を読み込んでいない
+	ExecWaiting = "waiting" // オペレーターの確認（次へ）を待っている
+	ExecRunning = "running" // 1手を実行している
+	ExecDone    = "done"    // 全ての手を終えた
+	ExecAborted = "aborted" // 途中でやめた
+)
+
+// エラー
+var (
+	// ErrNoPlan は計画を読み込んでいない（または終わった）のに次へ進もうとした場合に返ります
+	ErrNoPlan = errors.New("no plan to execute")
+	// ErrBusy は1手を実行している間に次へ進もうとしたり、計画を読み込もうとした場合に返ります
+	ErrBusy = errors.New("plan step in progress")
+	// ErrAborted は実行中の手がやめられた場合に返ります
+	ErrAborted = errors.New("plan aborted")
+)
+
+// ExecStatus は計画の実行の状態です
+type ExecStatus struct {
+	State     string    `json:"state"`
+	Plan      *Plan     `json:"plan,omitempty"`
+	Next      int       `json:"next,omitempty"` // 次に実行する手の番号（1始まり）
+	Completed int       `json:"completed"`
+	Picked    bool      `json:"picked,omitempty"` // 次の手のワークはつかみ終えている（やり直すと置きに行くところから）
+	Error     string    `json:"error,omitempty"`  // 最後に失敗した手の理由（次へでやり直せる）
+	Updated   time.Time `json:"updated"`
+}
+
+// Execution は計画を1手ずつ、オペレーターの確認を挟んで進めるための状態です（Publish は呼び出し側）
+type Execution struct {
+	mu  sync.Mutex
+	st  ExecStatus
+	now func() time.Time
+}
+
+// NewExecution は Execution を作ります
+func NewExecution() *Execution {
+	e := &Execution{now: time.Now}
+	e.st = ExecStatus{State: ExecIdle, Updated: e.now()}
+	return e
+}
+
+// Load は計画を読み込んで最初の手の確認を待ちます。手を実行している間は ErrBusy
+func (e *Execution) Load(p Plan) (ExecStatus, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.st.State == ExecRunning {
+		return e.st, ErrBusy
+	}
+	if len(p.Steps) == 0 {
+		return e.st, fmt.Errorf("%w: plan has no steps", ErrInvalidPlan)
+	}
+	e.st = ExecStatus{State: ExecWaiting, Plan: &p, Next: 1, Updated: e.now()}
+	return e.st, nil
+}
+
+// Begin は次の手を始めます（確認を待っているときだけ）。手と、その手を含む計画を返す
+// picked は前回この手のワークをつかんだ後で失敗したことを表し、呼び出し側は置きに行くところから続ける
+func (e *Execution) Begin() (plan Plan, step Step, picked bool, err error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	switch e.st.State {
+	case ExecWaiting:
+	case ExecRunning:
+		return Plan{}, Step{}, false, ErrBusy
+	default:
+		return Plan{}, Step{}, false, fmt.Errorf("%w: execution is %s", ErrNoPlan, e.st.State)
+	}
+	e.st.State = ExecRunning
+	e.st.Updated = e.now()
+	return *e.st.Plan, e.st.Plan.Steps[e.st.Next-1], e.st.Picked, nil
+}
+
+// Pick は実行中の手のワークをつかんだことを記録します（この後に失敗してもつかみ直さない）
+func (e *Execution) Pick() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.st.State == ExecRunning {
+		e.st.Picked = true
+	}
+}
+
+// Finish は Begin した手を終えます。err があれば同じ手の確認を待ち直す（やめられていればそのまま）
+// つかんだ後の失敗なら Picked は残し、次の Begin で置きに行くところから続けさせる
+func (e *Execution) Finish(err error) ExecStatus {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.st.State != ExecRunning {
+		return e.st
+	}
+	e.st.Updated = e.now()
+	if err != nil {
+		e.st.State = ExecWaiting
+		e.st.Error = err.Error()
+		return e.st
+	}
+	e.st.Error = ""
+	e.st.Picked = false
+	e.st.Completed++
+	if e.st.Next >= len(e.st.Plan.Steps) {
+		e.st.State = ExecDone
+		e.st.Next = 0
+		return e.st
+	}
+	e.st.Next++
+	e.st.State = ExecWaiting
+	return e.st
+}
+
+// Abort は実行をやめます。実行中の手は Aborted を見て止めること
+func (e *Execution) Abort() (ExecStatus, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.st.State != ExecWaiting && e.st.State != ExecRunning {
+		return e.st, fmt.Errorf("%w: execution is %s", ErrNoPlan, e.st.State)
+	}
+	e.st.State = ExecAborted
+	e.st.Next = 0
+	e.st.Picked = false
+	e.st.Updated = e.now()
+	return e.st, nil
+}
+
+// Aborted は実行をやめたかを返します（実行中の手が途中で確かめる）
+func (e *Execution) Aborted() bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.st.State == ExecAborted
+}
+
+// Status は実行の状態を返します
+func (e *Execution) Status() ExecStatus {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.st
+}
@@ -0,0 +1,127 @@
+// internal/planner/execution_test.go
+package planner
+
+import (
+	"errors"
+	"testing"
+)
+
+func testPlan(steps int) Plan {
+	p := Plan{Side: SideBlue}
+	for i := 1; i <= steps; i++ {
+		p.Steps = append(p.Steps, Step{N: i, Place: true})
+	}
+	return p
+}
+
+func TestExecutionLoad(t *testing.T) {
+	e := NewExecution()
+	if _, err := e.Load(testPlan(0)); !errors.Is(err, ErrInvalidPlan) {
+		t.Fatalf("Load(empty) error = %v, want ErrInvalidPlan", err)
+	}
+	st, err := e.Load(testPlan(2))
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if st.State != ExecWaiting || st.Next != 1 || st.Completed != 0 {
+		t.Fatalf("Load() status = %+v, want waiting at step 1", st)
+	}
+	if _, _, _, err := e.Begin(); err != nil {
+		t.Fatalf("Begin() error = %v", err)
+	}
+	if _, err := e.Load(testPlan(1)); !errors.Is(err, ErrBusy) {
+		t.Fatalf("Load() while running error = %v, want ErrBusy", err)
+	}
+}
+
+func TestExecutionTransitions(t *testing.T) {
+	errMove := errors.New("move failed")
+	// op: begin / pick / ok / fail / abort
+	tests := []struct {
+		name       string
+		steps      int
+		ops        []string
+		wantState  string
+		wantNext   int
+		wantDone   int
+		wantPicked bool
+		wantErr    bool
+	}{
+		{"begin runs", 2, []string{"begin"}, ExecRunning, 1, 0, false, false},
+		{"ok advances", 2, []string{"begin", "ok"}, ExecWaiting, 2, 1, false, false},
+		{"last ok finishes", 2, []string{"begin", "ok", "begin", "ok"}, ExecDone, 0, 2, false, false},
+		{"fail retries same step", 2, []string{"begin", "fail"}, ExecWaiting, 1, 0, false, true},
+		{"fail after pick resumes placing", 2, []string{"begin", "pick", "fail"}, ExecWaiting, 1, 0, true, true},
+		{"retry after pick clears picked", 2, []string{"begin", "pick", "fail", "begin", "ok"}, ExecWaiting, 2, 1, false, false},
+		{"abort while waiting", 2, []string{"abort"}, ExecAborted, 0, 0, false, false},
+		{"abort while running", 2, []string{"begin", "pick", "abort"}, ExecAborted, 0, 0, false, false},
+		{"finish after abort stays aborted", 2, []string{"begin", "abort", "ok"}, ExecAborted, 0, 0, false, false},
+		{"pick while waiting is ignored", 2, []string{"pick"}, ExecWaiting, 1, 0, false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := NewExecution()
+			if _, err := e.Load(testPlan(tt.steps)); err != nil {
+				t.Fatalf("Load() error = %v", err)
+			}
+			for _, op := range tt.ops {
+				switch op {
+				case "begin":
+					if _, _, _, err := e.Begin(); err != nil {
+						t.Fatalf("Begin() error = %v", err)
+					}
+				case "pick":
+					e.Pick()
+				case "ok":
+					e.Finish(nil)
+				case "fail":
+					e.Finish(errMove)
+				case "abort":
+					if _, err := e.Abort(); err != nil {
+						t.Fatalf("Abort() error = %v", err)
+					}
+				}
+			}
+			st := e.Status()
+			if st.State != tt.wantState || st.Next != tt.wantNext || st.Completed != tt.wantDone || st.Picked != tt.wantPicked {
+				t.Errorf("status = {state:%s next:%d completed:%d picked:%t}, want {state:%s next:%d completed:%d picked:%t}",
+					st.State, st.Next, st.Completed, st.Picked, tt.wantState, tt.wantNext, tt.wantDone, tt.wantPicked)
+			}
+			if (st.Error != "") != tt.wantErr {
+				t.Errorf("error = %q, want set %t", st.Error, tt.wantErr)
+			}
+			if e.Aborted() != (tt.wantState == ExecAborted) {
+				t.Errorf("Aborted() = %t", e.Aborted())
+			}
+		})
+	}
+}
+
+func TestExecutionBegin(t *testing.T) {
+	e := NewExecution()
+	if _, _, _, err := e.Begin(); !errors.Is(err, ErrNoPlan) {
+		t.Fatalf("Begin() before Load error = %v, want ErrNoPlan", err)
+	}
+	if _, err := e.Abort(); !errors.Is(err, ErrNoPlan) {
+		t.Fatalf("Abort() before Load error = %v, want ErrNoPlan", err)
+	}
+	if _, err := e.Load(testPlan(1)); err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	_, step, picked, err := e.Begin()
+	if err != nil || step.N != 1 || picked {
+		t.Fatalf("Begin() = step %d picked %t err %v, want step 1", step.N, picked, err)
+	}
+	if _, _, _, err := e.Begin(); !errors.Is(err, ErrBusy) {
+		t.Fatalf("Begin() while running error = %v, want ErrBusy", err)
+	}
+	e.Pick()
+	e.Finish(errors.New("release failed"))
+	if _, step, picked, err = e.Begin(); err != nil || step.N != 1 || !picked {
+		t.Fatalf("Begin() after failed release = step %d picked %t err %v, want step 1 picked", step.N, picked, err)
+	}
+	e.Finish(nil)
+	if _, _, _, err := e.Begin(); !errors.Is(err, ErrNoPlan) {
+		t.Fatalf("Begin() after done error = %v, want ErrNoPlan", err)
+	}
+}
@@ -0,0 +1,375 @@
+// internal/planner/planner.go
+package planner
+
+// plannerはROSにもginにも依存しないように書く（モーションの Publish は robot、HTTP は api パッケージ側）
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+
+	"catchrobo_app/internal/geom"
+)
+
+// ErrInvalidPlan はセル番号・サイド・ワークの指定が正しくない場合に返ります
+var ErrInvalidPlan = errors.New("invalid plan request")
+
+// サイド（score と同じ値）
+const (
+	SideBlue = "blue"
+	SideRed  = "red"
+)
+
+// maxImprovePasses は局所探索で並びを直す回数の上限です
+const maxImprovePasses = 50
+
+// Field はフィールドのセルの座標（指令フレーム、青基準）です。赤は x を反転し、列の並びも逆にする
+// セル番号は 1 始まりで 行*列数+列+1（画面のブロックの番号と同じ）
+type Field struct {
+	RowsX       []float64 `yaml:"rows_x" json:"rows_x"` // 行ごとの x [m]
+	ColsY       []float64 `yaml:"cols_y" json:"cols_y"` // 列ごとの y [m]
+	Z           float64   `yaml:"z" json:"z"`
+	Release     geom.Vec3 `yaml:"release" json:"release"`           // 置き場所（シューティングボックス）の上
+	SharedCells []int     `yaml:"shared_cells" json:"shared_cells"` // 共通エリアのセル（先に取る）
+}
+
+// DefaultField は画面のフィールドと同じ 10行×4列 の座標です
+func DefaultField() Field {
+	return Field{
+		RowsX:   []float64{0.55, 0.45, 0.30, 0.20, 0.05, -0.05, -0.20, -0.30, -0.45, -0.55},
+		ColsY:   []float64{0.497, 0.397, 0.297, 0.197},
+		Release: geom.Vec3{X: 0.4825, Y: -0.153},
+	}
+}
+
+// Cells はセルの数です
+func (f Field) Cells() int {
+	return len(f.RowsX) * len(f.ColsY)
+}
+
+// CellPosition はサイドのセル番号の座標を返します
+func (f Field) CellPosition(side string, cell int) (geom.Vec3, error) {
+	if err := checkSide(side); err != nil {
+		return geom.Vec3{}, err
+	}
+	if cell < 1 || cell > f.Cells() {
+		return geom.Vec3{}, fmt.Errorf("%w: cell %d is out of 1..%d", ErrInvalidPlan, cell, f.Cells())
+	}
+	row, col := (cell-1)/len(f.ColsY), (cell-1)%len(f.ColsY)
+	if side == SideRed {
+		return geom.Vec3{X: -f.RowsX[row], Y: f.ColsY[len(f.ColsY)-1-col], Z: f.Z}, nil
+	}
+	return geom.Vec3{X: f.RowsX[row], Y: f.ColsY[col], Z: f.Z}, nil
+}
+
+// ReleasePosition はサイドの置き場所の座標を返します
+func (f Field) ReleasePosition(side string) geom.Vec3 {
+	if side == SideRed {
+		return geom.Vec3{X: -f.Release.X, Y: f.Release.Y, Z: f.Release.Z}
+	}
+	return f.Release
+}
+
+func checkSide(side string) error {
+	if side != SideBlue && side != SideRed {
+		return fmt.Errorf("%w: side must be %s or %s", ErrInvalidPlan, SideBlue, SideRed)
+	}
+	return nil
+}
+
+// Config はアームの速さの仮定と、実行するときの待ち方です
+type Config struct {
+	SpeedMps         float64 `yaml:"speed_mps" json:"speed_mps"`                   // 手先が直線で動く速さ
+	PickS            float64 `yaml:"pick_s" json:"pick_s"`                         // 1個つかむ（catch モーション）のにかかる時間
+	PlaceS           float64 `yaml:"place_s" json:"place_s"`                       // 置く（release モーション）のにかかる時間
+	Capacity         int     `yaml:"capacity" json:"capacity"`                     // 置きに行くまでに持てる数
+	ArriveToleranceM float64 `yaml:"arrive_tolerance_m" json:"arrive_tolerance_m"` // 実行時、計測姿勢がこの距離に入ったら着いたとみなす
+	ArriveTimeoutS   float64 `yaml:"arrive_timeout_s" json:"arrive_timeout_s"`     // 実行時、着くのを待つ上限（見積もりの時間に足す）
+}
+
+// DefaultConfig は既定の設定です
+func DefaultConfig() Config {
+	return Config{SpeedMps: 0.5, PickS: 1.5, PlaceS: 1.0, Capacity: 1, ArriveToleranceM: 0.01, ArriveTimeoutS: 3}
+}
+
+// Detection は認識したワークの座標（フィールドの座標に変換済み）です
+type Detection struct {
+	ID      string  `json:"id"`
+	X       float64 `json:"x"`
+	Y       float64 `json:"y"`
+	Z       float64 `json:"z"`
+	FrameId string  `json:"frame_id"` // 空なら指令のフレーム（フィールドの座標）。Build の前に呼び出し側で変換しておく
+	Shared  bool    `json:"shared"`   // 共通エリアにある
+}
+
+// Request は計画の条件です
+type Request struct {
+	Side        string      `json:"side"`
+	Cells       []int       `json:"cells"`        // 残っているワークのセル
+	Detections  []Detection `json:"detections"`   // セルの代わりに認識した座標で渡すワーク
+	Release     *geom.Vec3  `json:"release"`      // nil ならフィールドの置き場所
+	Start       *geom.Vec3  `json:"start"`        // 今の手先の位置（nil なら置き場所）
+	SharedFirst *bool       `json:"shared_first"` // 共通エリアを先に取る（既定 true。共通エリアが分からなければ、明示したときはエラー）
+}
+
+// Item は取りに行くワークです
+type Item struct {
+	ID       string    `json:"id"` // セルならその番号、認識なら detection の id
+	Cell     int       `json:"cell,omitempty"`
+	Position geom.Vec3 `json:"position"`
+	Shared   bool      `json:"shared"`
+}
+
+// Step は計画の1手（1個取る。持てる数に達したか最後なら、続けて置きに行く）です
+type Step struct {
+	N       int     `json:"n"` // 1始まり
+	Item    Item    `json:"item"`
+	Trip    int     `json:"trip"`               // 何回目に置きに行く分か（1始まり）
+	TravelS float64 `json:"travel_s"`           // 前の位置からワークまでの移動時間
+	PickedS float64 `json:"picked_s"`           // 計画の始めからつかみ終わるまでの時間
+	Place   bool    `json:"place"`              // この後に置きに行く
+	ReturnS float64 `json:"return_s,omitempty"` // 置き場所までの移動時間（place のとき）
+	PlacedS float64 `json:"placed_s,omitempty"` // 計画の始めから置き終わるまでの時間（place のとき）
+}
+
+// Plan は取る順番と見積もりです
+type Plan struct {
+	Side      string    `json:"side"`
+	Start     geom.Vec3 `json:"start"`
+	Release   geom.Vec3 `json:"release"`
+	Config    Config    `json:"config"`
+	Steps     []Step    `json:"steps"`
+	Trips     int       `json:"trips"`
+	DistanceM float64   `json:"distance_m"`
+	TotalS    float64   `json:"total_s"`
+	Warnings  []string  `json:"warnings,omitempty"` // 計画はできたが、条件が効いていないもの
+}
+
+// Build は移動時間の合計が短くなるように取る順番を決めます
+// 共通エリアのワーク（shared_first のとき）を全て置き終えてから残りを取る。各まとまりの中は
+// 最も近いものから順に並べた後、区間の反転と1個の移動で見積もりが縮まなくなるまで直す
+func Build(field Field, cfg Config, req Request) (Plan, error) {
+	if err := checkSide(req.Side); err != nil {
+		return Plan{}, err
+	}
+	if cfg.SpeedMps <= 0 {
+		return Plan{}, fmt.Errorf("%w: speed_mps must be positive", ErrInvalidPlan)
+	}
+	if cfg.Capacity < 1 {
+		cfg.Capacity = 1
+	}
+	items, err := requestItems(field, req)
+	if err != nil {
+		return Plan{}, err
+	}
+	release := field.ReleasePosition(req.Side)
+	if req.Release != nil {
+		release = *req.Release
+	}
+	start := release
+	if req.Start != nil {
+		start = *req.Start
+	}
+	sharedFirst := req.SharedFirst == nil || *req.SharedFirst
+	var warnings []string
+	if sharedFirst && len(field.SharedCells) == 0 && !slices.ContainsFunc(req.Detections, func(d Detection) bool { return d.Shared }) {
+		// 共通エリアが分からなければ先に取りようがないので、明示されたときは断り、既定のときは計画に書いておく
+		const msg = "shared_first has no effect: field.shared_cells is empty and no detection is marked shared"
+		if req.SharedFirst != nil {
+			return Plan{}, fmt.Errorf("%w: %s", ErrInvalidPlan, msg)
+		}
+		warnings = append(warnings, msg)
+	}
+
+	var groups [][]Item
+	if sharedFirst {
+		var shared, rest []Item
+		for _, it := range items {
+			if it.Shared {
+				shared = append(shared, it)
+			} else {
+				rest = append(rest, it)
+			}
+		}
+		groups = [][]Item{shared, rest}
+	} else {
+		groups = [][]Item{items}
+	}
+
+	m := model{cfg: cfg, release: release}
+	plan := Plan{Side: req.Side, Start: start, Release: release, Config: cfg, Steps: []Step{}, Warnings: warnings}
+	pos := start
+	for _, g := range groups {
+		if len(g) == 0 {
+			continue
+		}
+		seq := m.improve(m.nearestFirst(g, pos), pos)
+		pos = m.appendSteps(&plan, seq, pos)
+	}
+	return plan, nil
+}
+
+// requestItems はセルと認識したワークを Item にします（重複はエラー）
+func requestItems(field Field, req Request) ([]Item, error) {
+	if len(req.Cells) == 0 && len(req.Detections) == 0 {
+		return nil, fmt.Errorf("%w: no cells or detections", ErrInvalidPlan)
+	}
+	// 並びを直すのはワークの数の3乗以上かかるので、フィールドに置ける数を超える認識は受け付けない
+	if len(req.Detections) > field.Cells() {
+		return nil, fmt.Errorf("%w: %d detections exceed the %d field cells", ErrInvalidPlan, len(req.Detections), field.Cells())
+	}
+	seen := map[string]bool{}
+	items := make([]Item, 0, len(req.Cells)+len(req.Detections))
+	for _, cell := range req.Cells {
+		p, err := field.CellPosition(req.Side, cell)
+		if err != nil {
+			return nil, err
+		}
+		id := strconv.Itoa(cell)
+		if seen[id] {
+			return nil, fmt.Errorf("%w: duplicate cell %d", ErrInvalidPlan, cell)
+		}
+		seen[id] = true
+		items = append(items, Item{ID: id, Cell: cell, Position: p, Shared: slices.Contains(field.SharedCells, cell)})
+	}
+	for i, d := range req.Detections {
+		id := d.ID
+		if id == "" {
+			id = "d" + strconv.Itoa(i+1)
+		}
+		p := geom.Vec3{X: d.X, Y: d.Y, Z: d.Z}
+		if !p.IsFinite() {
+			return nil, fmt.Errorf("%w: detection %s is not finite", ErrInvalidPlan, id)
+		}
+		if seen[id] {
+			return nil, fmt.Errorf("%w: duplicate id %s", ErrInvalidPlan, id)
+		}
+		seen[id] = true
+		items = append(items, Item{ID: id, Position: p, Shared: d.Shared})
+	}
+	return items, nil
+}
+
+// model は移動時間の見積もりです（手先が一定の速さで直線に動き、つかむ・置くのに決まった時間がかかる）
+type model struct {
+	cfg     Config
+	release geom.Vec3
+}
+
+func (m model) travel(a, b geom.Vec3) float64 {
+	return b.Sub(a).Norm() / m.cfg.SpeedMps
+}
+
+// cost は pos から seq の順に取り、capacity 個ごとと最後に置きに行く時間の合計です
+func (m model) cost(seq []Item, pos geom.Vec3) float64 {
+	t := 0.0
+	for i, it := range seq {
+		t += m.travel(pos, it.Position) + m.cfg.PickS
+		pos = it.Position
+		if (i+1)%m.cfg.Capacity == 0 || i == len(seq)-1 {
+			t += m.travel(pos, m.release) + m.cfg.PlaceS
+			pos = m.release
+		}
+	}
+	return t
+}
+
+// nearestFirst は今の位置から最も近いものを順に選んだ並びを返します（置きに行った後は置き場所から選ぶ）
+func (m model) nearestFirst(items []Item, pos geom.Vec3) []Item {
+	rest := slices.Clone(items)
+	// 同じ距離なら ID 順にして結果を決まったものにする
+	slices.SortFunc(rest, func(a, b Item) int { return compareID(a, b) })
+	seq := make([]Item, 0, len(rest))
+	for len(rest) > 0 {
+		best := 0
+		for i := 1; i < len(rest); i++ {
+			if m.travel(pos, rest[i].Position) < m.travel(pos, rest[best].Position) {
+				best = i
+			}
+		}
+		seq = append(seq, rest[best])
+		pos = rest[best].Position
+		rest = slices.Delete(rest, best, best+1)
+		if len(seq)%m.cfg.Capacity == 0 {
+			pos = m.release
+		}
+	}
+	return seq
+}
+
+// improve は区間の反転（2-opt）と1個の移動で見積もりが縮む限り並びを直します
+func (m model) improve(seq []Item, pos geom.Vec3) []Item {
+	best := m.cost(seq, pos)
+	for pass := 0; pass < maxImprovePasses; pass++ {
+		improved := false
+		for i := 0; i < len(seq)-1; i++ {
+			for j := i + 1; j < len(seq); j++ {
+				cand := slices.Clone(seq)
+				slices.Reverse(cand[i : j+1])
+				if c := m.cost(cand, pos); c < best-1e-9 {
+					seq, best, improved = cand, c, true
+				}
+			}
+		}
+		for i := range seq {
+			for j := range seq {
+				if i == j {
+					continue
+				}
+				cand := slices.Clone(seq)
+				it := cand[i]
+				cand = slices.Insert(slices.Delete(cand, i, i+1), j, it)
+				if c := m.cost(cand, pos); c < best-1e-9 {
+					seq, best, improved = cand, c, true
+				}
+			}
+		}
+		if !improved {
+			break
+		}
+	}
+	return seq
+}
+
+// appendSteps は seq を計画の手にして追加し、最後の位置（置き場所）を返します
+func (m model) appendSteps(plan *Plan, seq []Item, pos geom.Vec3) geom.Vec3 {
+	for i, it := range seq {
+		if i%m.cfg.Capacity == 0 {
+			plan.Trips++
+		}
+		step := Step{N: len(plan.Steps) + 1, Item: it, Trip: plan.Trips, TravelS: m.travel(pos, it.Position)}
+		plan.DistanceM += it.Position.Sub(pos).Norm()
+		plan.TotalS += step.TravelS + m.cfg.PickS
+		step.PickedS = plan.TotalS
+		pos = it.Position
+		if (i+1)%m.cfg.Capacity == 0 || i == len(seq)-1 {
+			step.Place = true
+			step.ReturnS = m.travel(pos, m.release)
+			plan.DistanceM += m.release.Sub(pos).Norm()
+			plan.TotalS += step.ReturnS + m.cfg.PlaceS
+			step.PlacedS = plan.TotalS
+			pos = m.release
+		}
+		plan.Steps = append(plan.Steps, step)
+	}
+	return pos
+}
+
+// compareID はセルなら番号順、認識したものはセルの後に ID 順で並べます
+func compareID(a, b Item) int {
+	switch {
+	case a.Cell != 0 && b.Cell != 0:
+		return a.Cell - b.Cell
+	case a.Cell != 0:
+		return -1
+	case b.Cell != 0:
+		return 1
+	}
+	switch {
+	case a.ID < b.ID:
+		return -1
+	case a.ID > b.ID:
+		return 1
+	}
+	return 0
+}
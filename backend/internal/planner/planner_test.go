@@ -0,0 +1,205 @@
+// internal/planner/planner_test.go
+package planner
+
+import (
+	"errors"
+	"math"
+	"strconv"
+	"testing"
+
+	"catchrobo_app/internal/geom"
+)
+
+func ptr[T any](v T) *T { return &v }
+
+// lineConfig は距離がそのまま時間になる（つかむ・置く時間なし）設定です
+func lineConfig(capacity int) Config {
+	return Config{SpeedMps: 1, Capacity: capacity}
+}
+
+func TestBuildRejectsInvalidRequests(t *testing.T) {
+	field := DefaultField()
+	tooMany := make([]Detection, field.Cells()+1)
+	for i := range tooMany {
+		tooMany[i] = Detection{ID: strconv.Itoa(i), X: float64(i)}
+	}
+	tests := []struct {
+		name string
+		cfg  Config
+		req  Request
+	}{
+		{"bad side", DefaultConfig(), Request{Side: "green", Cells: []int{1}}},
+		{"no items", DefaultConfig(), Request{Side: SideBlue}},
+		{"cell out of range", DefaultConfig(), Request{Side: SideBlue, Cells: []int{field.Cells() + 1}}},
+		{"cell zero", DefaultConfig(), Request{Side: SideBlue, Cells: []int{0}}},
+		{"duplicate cell", DefaultConfig(), Request{Side: SideBlue, Cells: []int{3, 3}}},
+		{"duplicate detection id", DefaultConfig(), Request{Side: SideBlue, Detections: []Detection{{ID: "a"}, {ID: "a"}}}},
+		{"non finite detection", DefaultConfig(), Request{Side: SideBlue, Detections: []Detection{{ID: "a", X: math.NaN()}}}},
+		{"too many detections", DefaultConfig(), Request{Side: SideBlue, Detections: tooMany}},
+		{"zero speed", Config{Capacity: 1}, Request{Side: SideBlue, Cells: []int{1}}},
+		{"explicit shared_first without shared cells", DefaultConfig(), Request{Side: SideBlue, Cells: []int{1}, SharedFirst: ptr(true)}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Build(field, tt.cfg, tt.req); !errors.Is(err, ErrInvalidPlan) {
+				t.Fatalf("Build() error = %v, want ErrInvalidPlan", err)
+			}
+		})
+	}
+}
+
+func TestBuildSharedFirst(t *testing.T) {
+	field := DefaultField()
+	field.SharedCells = []int{40}
+	tests := []struct {
+		name         string
+		field        Field
+		req          Request
+		wantFirst    string
+		wantWarnings int
+	}{
+		// 置き場所に近いセル1より、遠い共通エリアのセル40を先に取る
+		{"shared cell first", field, Request{Side: SideBlue, Cells: []int{1, 40}}, "40", 0},
+		{"shared_first disabled", field, Request{Side: SideBlue, Cells: []int{1, 40}, SharedFirst: ptr(false)}, "1", 0},
+		{"shared detection first", DefaultField(), Request{Side: SideBlue, Cells: []int{1}, Detections: []Detection{{ID: "s", X: -0.5, Y: 0.2, Shared: true}}}, "s", 0},
+		{"default warns without shared cells", DefaultField(), Request{Side: SideBlue, Cells: []int{1, 40}}, "1", 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plan, err := Build(tt.field, DefaultConfig(), tt.req)
+			if err != nil {
+				t.Fatalf("Build() error = %v", err)
+			}
+			if got := plan.Steps[0].Item.ID; got != tt.wantFirst {
+				t.Errorf("first item = %s, want %s", got, tt.wantFirst)
+			}
+			if len(plan.Warnings) != tt.wantWarnings {
+				t.Errorf("warnings = %v, want %d", plan.Warnings, tt.wantWarnings)
+			}
+		})
+	}
+}
+
+func TestBuildCapacityTrips(t *testing.T) {
+	tests := []struct {
+		capacity  int
+		cells     []int
+		wantTrips int
+		wantPlace []bool
+	}{
+		{1, []int{1, 2, 3}, 3, []bool{true, true, true}},
+		{2, []int{1, 2, 3, 4, 5}, 3, []bool{false, true, false, true, true}},
+		{3, []int{1, 2, 3}, 1, []bool{false, false, true}},
+		{5, []int{1, 2}, 1, []bool{false, true}},
+		// 0 以下は1として扱う
+		{0, []int{1, 2}, 2, []bool{true, true}},
+	}
+	for _, tt := range tests {
+		t.Run(strconv.Itoa(tt.capacity)+"/"+strconv.Itoa(len(tt.cells)), func(t *testing.T) {
+			cfg := DefaultConfig()
+			cfg.Capacity = tt.capacity
+			plan, err := Build(DefaultField(), cfg, Request{Side: SideBlue, Cells: tt.cells, SharedFirst: ptr(false)})
+			if err != nil {
+				t.Fatalf("Build() error = %v", err)
+			}
+			if plan.Trips != tt.wantTrips {
+				t.Errorf("trips = %d, want %d", plan.Trips, tt.wantTrips)
+			}
+			if len(plan.Steps) != len(tt.wantPlace) {
+				t.Fatalf("steps = %d, want %d", len(plan.Steps), len(tt.wantPlace))
+			}
+			for i, s := range plan.Steps {
+				if s.N != i+1 {
+					t.Errorf("step %d: n = %d", i, s.N)
+				}
+				if s.Place != tt.wantPlace[i] {
+					t.Errorf("step %d: place = %t, want %t", s.N, s.Place, tt.wantPlace[i])
+				}
+				if s.Place && s.PlacedS < s.PickedS {
+					t.Errorf("step %d: placed_s %.3f before picked_s %.3f", s.N, s.PlacedS, s.PickedS)
+				}
+			}
+			last := plan.Steps[len(plan.Steps)-1]
+			if math.Abs(last.PlacedS-plan.TotalS) > 1e-9 {
+				t.Errorf("total_s = %.3f, want last placed_s %.3f", plan.TotalS, last.PlacedS)
+			}
+		})
+	}
+}
+
+func TestBuildTotalMatchesCost(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Capacity = 2
+	plan, err := Build(DefaultField(), cfg, Request{Side: SideRed, Cells: []int{2, 9, 17, 26, 33, 38}, SharedFirst: ptr(false)})
+	if err != nil {
+		t.Fatalf("Build() error = %v", err)
+	}
+	seq := make([]Item, len(plan.Steps))
+	for i, s := range plan.Steps {
+		seq[i] = s.Item
+	}
+	m := model{cfg: cfg, release: plan.Release}
+	if got := m.cost(seq, plan.Start); math.Abs(got-plan.TotalS) > 1e-9 {
+		t.Errorf("total_s = %.6f, want cost %.6f", plan.TotalS, got)
+	}
+}
+
+func TestImprove(t *testing.T) {
+	at := func(id string, x float64) Item { return Item{ID: id, Position: geom.Vec3{X: x}} }
+	tests := []struct {
+		name     string
+		capacity int
+		items    []Item
+		want     float64
+	}{
+		// 最も近い順（1 → -1.5 → 3 → 戻る）は 11。区間 [-1.5, 3] を往復する 9 が最短
+		{"nearest first trap", 10, []Item{at("a", 1), at("b", -1.5), at("c", 3)}, 9},
+		// 同じ側のものは遠い方へ並べて1往復にする
+		{"one side", 10, []Item{at("a", 2), at("b", 1), at("c", 3)}, 6},
+		// 1個ずつ置きに行くなら並びによらず往復の合計
+		{"capacity one", 1, []Item{at("a", 1), at("b", -1.5), at("c", 3)}, 11},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := model{cfg: lineConfig(tt.capacity)}
+			start := geom.Vec3{}
+			nearest := m.nearestFirst(tt.items, start)
+			seq := m.improve(nearest, start)
+			if len(seq) != len(tt.items) {
+				t.Fatalf("improve() returned %d items, want %d", len(seq), len(tt.items))
+			}
+			got := m.cost(seq, start)
+			if math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("cost = %.3f, want %.3f (order %v)", got, tt.want, ids(seq))
+			}
+			if got > m.cost(nearest, start)+1e-9 {
+				t.Errorf("improve() made the order worse: %.3f > %.3f", got, m.cost(nearest, start))
+			}
+		})
+	}
+}
+
+func ids(seq []Item) []string {
+	out := make([]string, len(seq))
+	for i, it := range seq {
+		out[i] = it.ID
+	}
+	return out
+}
+
+func TestCellPositionMirrorsRed(t *testing.T) {
+	field := DefaultField()
+	for _, cell := range []int{1, 4, 21, 40} {
+		blue, err := field.CellPosition(SideBlue, cell)
+		if err != nil {
+			t.Fatalf("CellPosition(blue, %d) error = %v", cell, err)
+		}
+		red, err := field.CellPosition(SideRed, cell)
+		if err != nil {
+			t.Fatalf("CellPosition(red, %d) error = %v", cell, err)
+		}
+		if red.X != -blue.X {
+			t.Errorf("cell %d: red x = %.3f, want %.3f", cell, red.X, -blue.X)
+		}
+	}
+}
@@ -0,0 +1,142 @@
+// internal/ratelimit/ratelimit.go
+package ratelimit
+
+// ratelimitはROSにもginにも依存しないように書く（エンドポイント用のミドルウェアは api パッケージ側）
+import (
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Rule はキー（エンドポイントやトピック）ごとの制限です。0 の項目は制限しません
+type Rule struct {
+	Rate       float64 `yaml:"rate" json:"rate"`               // 1秒あたりに受け付ける回数（トークンバケット）
+	Burst      int     `yaml:"burst" json:"burst"`             // まとめて受け付けられる回数（0なら1）
+	DebounceMs int     `yaml:"debounce_ms" json:"debounce_ms"` // 前回受け付けてからこの時間内は捨てる（連打対策）
+}
+
+// LimitedError は制限を超えた場合に返ります。RetryAfter 後なら受け付けられます
+type LimitedError struct {
+	Key        string
+	Reason     string // rate / debounce
+	RetryAfter time.Duration
+}
+
+func (e *LimitedError) Error() string {
+	return fmt.Sprintf("rate limited (%s): %s, retry after %s", e.Reason, e.Key, e.RetryAfter.Round(time.Millisecond))
+}
+
+// 制限した理由
+const (
+	ReasonRate     = "rate"
+	ReasonDebounce = "debounce"
+)
+
+// Stat は /api/admin/ratelimit で返すキーごとの設定と回数です
+type Stat struct {
+	Key          string    `json:"key"`
+	Rule         Rule      `json:"rule"`
+	Allowed      uint64    `json:"allowed"`
+	Limited      uint64    `json:"limited"`   // Rate で捨てた回数
+	Debounced    uint64    `json:"debounced"` // DebounceMs で捨てた回数
+	LastAccepted time.Time `json:"last_accepted"`
+	LastLimited  time.Time `json:"last_limited"`
+}
+
+type bucket struct {
+	tokens       float64
+	refilled     time.Time
+	lastAccepted time.Time
+	lastLimited  time.Time
+	allowed      uint64
+	limited      uint64
+	debounced    uint64
+}
+
+// Limiter はキーごとのトークンバケットとデバウンスです。ルールの無いキーは常に受け付けます
+type Limiter struct {
+	mu      sync.Mutex
+	rules   map[string]Rule
+	buckets map[string]*bucket
+	now     func() time.Time
+}
+
+// New は rules の Limiter を作ります
+func New(rules map[string]Rule) *Limiter {
+	l := &Limiter{rules: map[string]Rule{}, buckets: map[string]*bucket{}, now: time.Now}
+	for k, r := range rules {
+		l.rules[k] = r
+	}
+	return l
+}
+
+// Allow は key の1回分を受け付けられるか判定し、受け付けられなければ *LimitedError を返します
+func (l *Limiter) Allow(key string) error {
+	if l == nil {
+		return nil
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	r, ok := l.rules[key]
+	if !ok {
+		return nil
+	}
+	now := l.now()
+	b := l.buckets[key]
+	if b == nil {
+		b = &bucket{tokens: float64(burst(r)), refilled: now}
+		l.buckets[key] = b
+	}
+
+	if r.DebounceMs > 0 && !b.lastAccepted.IsZero() {
+		window := time.Duration(r.DebounceMs) * time.Millisecond
+		if elapsed := now.Sub(b.lastAccepted); elapsed < window {
+			b.debounced++
+			b.lastLimited = now
+			return &LimitedError{Key: key, Reason: ReasonDebounce, RetryAfter: window - elapsed}
+		}
+	}
+	if r.Rate > 0 {
+		b.tokens = math.Min(float64(burst(r)), b.tokens+now.Sub(b.refilled).Seconds()*r.Rate)
+		b.refilled = now
+		if b.tokens < 1 {
+			b.limited++
+			b.lastLimited = now
+			wait := time.Duration((1 - b.tokens) / r.Rate * float64(time.Second))
+			return &LimitedError{Key: key, Reason: ReasonRate, RetryAfter: wait}
+		}
+		b.tokens--
+	}
+	b.allowed++
+	b.lastAccepted = now
+	return nil
+}
+
+// Stats はルールのあるキーの設定と回数をキー順に返します
+func (l *Limiter) Stats() []Stat {
+	if l == nil {
+		return nil
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	out := make([]Stat, 0, len(l.rules))
+	for k, r := range l.rules {
+		st := Stat{Key: k, Rule: r}
+		if b := l.buckets[k]; b != nil {
+			st.Allowed, st.Limited, st.Debounced = b.allowed, b.limited, b.debounced
+			st.LastAccepted, st.LastLimited = b.lastAccepted, b.lastLimited
+		}
+		out = append(out, st)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Key < out[j].Key })
+	return out
+}
+
+func burst(r Rule) int {
+	if r.Burst <= 0 {
+		return 1
+	}
+	return r.Burst
+}
@@ -9,16 +9,30 @@ import (
 	"image"
 	"image/color"
 	"image/jpeg"
-	"os/signal"
+	"log/slog"
 	"sync"
 	"sync/atomic"
-	"syscall"
 	"time"
 
+	"catchrobo_app/internal/config"
+	"catchrobo_app/internal/diagnostics"
+	"catchrobo_app/internal/geom"
+	"catchrobo_app/internal/jog"
+	"catchrobo_app/internal/joint"
+	"catchrobo_app/internal/joy"
+	"catchrobo_app/internal/match"
+	"catchrobo_app/internal/planner"
+	"catchrobo_app/internal/ratelimit"
+	"catchrobo_app/internal/rosout"
+	"catchrobo_app/internal/score"
+	"catchrobo_app/internal/tf"
+
 	builtin_interfaces "msgs/builtin_interfaces/msg"
 	geometry_msgs "msgs/geometry_msgs/msg"
+	rcl_interfaces_srv "msgs/rcl_interfaces/srv"
 	sensor_msgs_msg "msgs/sensor_msgs/msg"
 	std_msgs "msgs/std_msgs/msg"
+	tf2_msgs "msgs/tf2_msgs/msg"
 
 	"github.com/tiiuae/rclgo/pkg/rclgo"
 )
@@ -37,6 +51,25 @@ type RobotController struct {
 	addUpMotionPub   *rclgo.Publisher
 	middleMotionPub  *rclgo.Publisher
 	jointAnglesPub *rclgo.Publisher
+	jogPub         *rclgo.Publisher
+
+	// 速度ジョグ（一定周期の TwistStamped とデッドマン）
+	jogger *jog.Jogger
+
+	// トピックごとの送信制限（連打で arm 側に指令が溜まらないように）
+	topicLimits *ratelimit.Limiter
+
+	// ゲームパッド（joy.go）。joyCell はセル送りで最後に送ったセル（0なら未選択）
+	joy       *joy.Mapper
+	joySub    *rclgo.Subscription
+	joySide   string
+	joyCells  config.JoyCellsConfig
+	joyCellMu sync.Mutex
+	joyCell   int
+
+	// 非常停止のラッチ（estop.go）
+	estopMu sync.Mutex
+	estop   EStopStatus
 
 	// Camera subscriptions (任意: raw / compressed のどちらかが来れば最新JPEGを更新)
 	rawImageSub        *rclgo.Subscription
@@ -45,16 +78,93 @@ type RobotController struct {
 	latestJPEG   []byte
 	latestJPEGMu sync.RWMutex
 	frameSeq     uint64 // 新規: フレーム更新ごとに++（MJPEGで重複送信を避けるため）
+	cameras      map[string]*cameraStat // カメラのトピックごとの受信状況（latestJPEGMu で保護、metrics.go / health.go）
 
 	// 現在の目標(累積)位置
-	currentX float64
-	currentY float64
-	currentZ float64
+	target *TargetState
+
+	// アームから報告される計測姿勢（目標位置の初期化に使う）
+	measuredPoseSub *rclgo.Subscription
+	measuredMu      sync.RWMutex
+	measured        *MeasuredPose
+
+	// /tf と /tf_static から作る変換木（他フレームの目標を指令フレームへ変換する）
+	tfBuffer    *tf.Buffer
+	tfSub       *rclgo.Subscription
+	tfStaticSub *rclgo.Subscription
+
+	// 関節モデルと最後に送った関節角度（1回あたりの変化量チェック用）
+	joints        *joint.Model
+	lastJointsMu  sync.Mutex
+	lastJointsCmd []float64
+
+	// URDF と順・逆運動学（model.go / ik.go）
+	ik              config.IKConfig
+	urdfCfg         config.URDFConfig
+	model           armModel
+	urdfSub         *rclgo.Subscription
+	urdfParamClient *rcl_interfaces_srv.GetParametersClient
+	jointStatesSub  *rclgo.Subscription
+	jointStatesMu   sync.RWMutex
+	jointStates     JointStates
 
 	// spin制御
 	spinCancel context.CancelFunc
+	spinDone   chan struct{}
+
+	// 停止処理（lifecycle.go）
+	shutdownOnce sync.Once
+	shutdownCh   chan struct{}
+	park         *config.ParkConfig
+
+	// /healthz・/readyz の閾値（health.go）
+	health config.HealthConfig
+
+	// 全ノードのログ（rosout.go）
+	rosout    *rosout.Buffer
+	rosoutSub *rclgo.Subscription
+
+	// モータードライバーやカメラの診断（diagnostics.go）
+	diagnostics    *diagnostics.Aggregator
+	diagnosticsSub *rclgo.Subscription
+
+	// 他ノードのサービス（パラメータなど）を呼ぶクライアント（services.go）
+	services       serviceClients
+	serviceTimeout time.Duration
+
+	// ros2_control の controller_manager のノード名（controllers.go）
+	controllerManager string
+
+	// 試合の状態と残り時間。状態によって指令を拒否する（match.go）
+	match *match.Match
+	// 試技の記録と得点（scores.go）
+	scores *score.Tracker
+
+	// 取る順番の計画と、その1手ずつの実行（planner.go）
+	field      planner.Field
+	plannerCfg planner.Config
+	plan       *planner.Execution
 }
 
+// 目標位置の永続化先と計測姿勢のトピック（環境に合わせて変更してください）
+const (
+	targetStateFile   = "data/target_state.json"
+	measuredPoseTopic = "/arm_move/current_pose"
+)
+
+// MeasuredPose はアームから最後に受信した計測姿勢です
+type MeasuredPose struct {
+	X           float64         `json:"x"`
+	Y           float64         `json:"y"`
+	Z           float64         `json:"z"`
+	Orientation geom.Quaternion `json:"orientation"`
+	FrameId     string          `json:"frame_id"`
+	ReceivedAt  time.Time       `json:"received_at"`
+}
+
+// Position は計測位置をベクトルで返します
+func (m MeasuredPose) Position() geom.Vec3 { return geom.Vec3{X: m.X, Y: m.Y, Z: m.Z} }
+
 func rosNow() builtin_interfaces.Time {
 	t := time.Now()
 	return builtin_interfaces.Time{
@@ -64,7 +174,8 @@ func rosNow() builtin_interfaces.Time {
 }
 
 // NewController はROSノードとPublisher/Subscriberを初期化します
-func NewController(_ context.Context) (*RobotController, error) {
+// ctx が終わると spin も止まる（通常は Close で止める）
+func NewController(ctx context.Context, cfg *config.Config) (*RobotController, error) {
 	// nodeを初期化
 	node, err := rclgo.NewNode("web_app_backend", "")
 	if err != nil {
@@ -118,6 +229,11 @@ func NewController(_ context.Context) (*RobotController, error) {
 		return nil, err
 	}
 
+	jogPub, err := node.NewPublisher(cfg.Jog.Topic, geometry_msgs.TwistStampedTypeSupport, nil)
+	if err != nil {
+		return nil, err
+	}
+
 	rc := &RobotController{
 		node:           node,
 		positionPub:    posPub,
@@ -131,9 +247,96 @@ func NewController(_ context.Context) (*RobotController, error) {
 		addUpMotionPub:   addUpMotionPub,
 		middleMotionPub:  middleMotionPub,
 		jointAnglesPub: jointAnglesPub,
-		currentX:       0,
-		currentY:       0,
-		currentZ:       0,
+		jogPub:         jogPub,
+		joints:         cfg.JointModel(),
+		ik:             cfg.IK,
+		topicLimits:    ratelimit.New(cfg.RateLimit.Topics),
+		shutdownCh:     make(chan struct{}),
+		cameras:        map[string]*cameraStat{},
+		health:         cfg.Health,
+		park:           cfg.Shutdown.Park,
+		serviceTimeout: cfg.Services.Timeout(),
+		controllerManager: NormalizeNodeName(cfg.ControllerManager.Node),
+		field:             cfg.Field,
+		plannerCfg:        cfg.Planner,
+		plan:              planner.NewExecution(),
+	}
+	rc.followLogLevel()
+	rc.jogger = jog.New(cfg.Jog, rc.publishTwist, func(reason string) {
+		rc.log(context.Background(), slog.LevelWarn, "jog stopped", "reason", reason)
+	})
+	rc.match = match.New(cfg.Match, func(reason string) {
+		rc.jogger.Stop("", reason)
+	})
+	rc.scores = score.NewTracker(cfg.Score.Dir, cfg.Score.Rules, cfg.Score.Side, func(err error) {
+		rc.warn("match record", err)
+	})
+	rc.tfBuffer = tf.NewBuffer(tf.DefaultCacheTime)
+	rc.target = NewTargetState(targetStateFile, func(err error) {
+		rc.warn("target state", err)
+	})
+	if snap := rc.target.Snapshot(); snap.Initialized {
+		rc.log(context.Background(), slog.LevelInfo, "Restored target", "x", snap.X, "y", snap.Y, "z", snap.Z, "version", snap.Version)
+	}
+
+	// 計測姿勢（目標位置が未初期化なら最初の受信値で初期化する）
+	measuredSub, err := node.NewSubscription(
+		measuredPoseTopic,
+		geometry_msgs.PoseStampedTypeSupport,
+		nil,
+		func(sub *rclgo.Subscription) {
+			var msg geometry_msgs.PoseStamped
+			if _, err := sub.TakeMessage(&msg); err != nil {
+				rc.warn("failed to take measured pose", err)
+				return
+			}
+			rc.setMeasuredPose(&msg)
+		},
+	)
+	if err == nil {
+		rc.measuredPoseSub = measuredSub
+	} else {
+		rc.warn("failed to subscribe measured pose", err)
+	}
+
+	// ---- URDF / JointState ----
+	rc.setupModel(cfg)
+
+	// ---- Joy ----
+	rc.setupJoy(cfg)
+
+	// ---- /rosout ----
+	rc.setupRosout(cfg)
+
+	// ---- /diagnostics ----
+	rc.setupDiagnostics(cfg)
+
+	// ---- TF Subscriptions（tf2_ros の TransformListener と同じ QoS） ----
+	tfQos := rclgo.NewDefaultQosProfile()
+	tfQos.Depth = 100
+	tfOpts := rclgo.NewDefaultSubscriptionOptions()
+	tfOpts.Qos = tfQos
+	tfSub, err := node.NewSubscription("/tf", tf2_msgs.TFMessageTypeSupport, tfOpts, func(sub *rclgo.Subscription) {
+		rc.takeTF(sub, false)
+	})
+	if err == nil {
+		rc.tfSub = tfSub
+	} else {
+		rc.warn("failed to subscribe /tf", err)
+	}
+
+	staticQos := rclgo.NewDefaultQosProfile()
+	staticQos.Durability = rclgo.DurabilityTransientLocal
+	staticQos.Depth = 100
+	staticOpts := rclgo.NewDefaultSubscriptionOptions()
+	staticOpts.Qos = staticQos
+	tfStaticSub, err := node.NewSubscription("/tf_static", tf2_msgs.TFMessageTypeSupport, staticOpts, func(sub *rclgo.Subscription) {
+		rc.takeTF(sub, true)
+	})
+	if err == nil {
+		rc.tfStaticSub = tfStaticSub
+	} else {
+		rc.warn("failed to subscribe /tf_static", err)
 	}
 
 	// ---- Camera Subscriptions (任意のトピック名に合わせて変更してください) ----
@@ -156,18 +359,23 @@ func NewController(_ context.Context) (*RobotController, error) {
 		func(sub *rclgo.Subscription) {
 			var msg sensor_msgs_msg.Image
 			if _, err := sub.TakeMessage(&msg); err != nil {
-				_ = rc.node.Logger().Warn("failed to take raw image: ", err)
+				rc.warn("failed to take raw image", err)
 				return
 			}
-			if jpegData, err := encodeSensorImageToJPEG(&msg); err == nil {
+			rc.markCameraFrame(rawTopic)
+			start := time.Now()
+			jpegData, err := encodeSensorImageToJPEG(&msg)
+			observeEncode(rawTopic, time.Since(start), len(jpegData), err)
+			if err == nil {
 				rc.setLatestJPEG(jpegData)
 			}
 		},
 	)
 	if err == nil {
 		rc.rawImageSub = rawSub
+		rc.cameras[rawTopic] = &cameraStat{}
 	} else {
-		_ = node.Logger().Warn("failed to subscribe raw image: ", err)
+		rc.warn("failed to subscribe raw image", err)
 	}
 
 	// Compressed image（JPEG想定）
@@ -179,32 +387,116 @@ func NewController(_ context.Context) (*RobotController, error) {
 		func(sub *rclgo.Subscription) {
 			var msg sensor_msgs_msg.CompressedImage
 			if _, err := sub.TakeMessage(&msg); err != nil {
-				_ = rc.node.Logger().Warn("failed to take compressed image: ", err)
+				rc.warn("failed to take compressed image", err)
 				return
 			}
+			rc.markCameraFrame(compTopic)
+			metricCameraJPEGBytes.Observe(float64(len(msg.Data)), compTopic)
 			dataCopy := append([]byte(nil), msg.Data...)
 			rc.setLatestJPEG(dataCopy)
 		},
 	)
 	if err == nil {
 		rc.compressedImageSub = compSub
+		rc.cameras[compTopic] = &cameraStat{}
 	} else {
-		_ = node.Logger().Warn("failed to subscribe compressed image: ", err)
+		rc.warn("failed to subscribe compressed image", err)
 	}
 	// ---------------------------------------------------------------
 
 	// Spin をバックグラウンドで開始（Executor は不要）
-	spinCtx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	// シグナルは main が受けて停止処理の順序を決める（駐機姿勢を送る間は spin を止めない）
+	spinCtx, cancel := context.WithCancel(ctx)
 	rc.spinCancel = cancel
+	rc.spinDone = make(chan struct{})
 	go func() {
-		if err := rc.node.Spin(spinCtx); err != nil {
-			_ = rc.node.Logger().Error("node spin stopped: ", err)
+		defer close(rc.spinDone)
+		if err := rc.node.Spin(spinCtx); err != nil && spinCtx.Err() == nil {
+			rc.log(context.Background(), slog.LevelError, "node spin stopped", "error", err)
 		}
 	}()
+	go rc.fetchURDFParam(spinCtx)
+	go rc.jogger.Run(spinCtx)
+	metricCameraFPS.SetFunc(rc.cameraFPS)
+	go rc.joy.Run(spinCtx)
+	go rc.diagnostics.Run(spinCtx)
+	go rc.match.Run(spinCtx)
+	go rc.followMatch(spinCtx)
 
 	return rc, nil
 }
 
+// takeTF は TFMessage を受け取り変換木に追加します
+func (rc *RobotController) takeTF(sub *rclgo.Subscription, static bool) {
+	var msg tf2_msgs.TFMessage
+	if _, err := sub.TakeMessage(&msg); err != nil {
+		rc.warn("failed to take tf", err)
+		return
+	}
+	for i := range msg.Transforms {
+		ts := &msg.Transforms[i]
+		t, r := ts.Transform.Translation, ts.Transform.Rotation
+		st := tf.Stamped{
+			Parent: ts.Header.FrameId,
+			Child:  ts.ChildFrameId,
+			Stamp:  time.Unix(int64(ts.Header.Stamp.Sec), int64(ts.Header.Stamp.Nanosec)),
+			Transform: geom.Transform{
+				Translation: geom.Vec3{X: t.X, Y: t.Y, Z: t.Z},
+				Rotation:    geom.Quaternion{X: r.X, Y: r.Y, Z: r.Z, W: r.W},
+			},
+		}
+		if err := rc.tfBuffer.Set(st, static); err != nil {
+			rc.warn("ignored tf", err)
+		}
+	}
+}
+
+// LookupTransform は source フレームの点を target フレームへ写す変換を返します（at がゼロ値なら最新）
+func (rc *RobotController) LookupTransform(target, source string, at time.Time) (geom.Transform, error) {
+	return rc.tfBuffer.Lookup(target, source, at)
+}
+
+// Frames は TF バッファが保持しているフレーム一覧を返します
+func (rc *RobotController) Frames() map[string]tf.FrameInfo {
+	return rc.tfBuffer.Frames()
+}
+
+// goalToCommandFrame は他フレームで指定された目標姿勢を指令フレームへ変換します
+func (rc *RobotController) goalToCommandFrame(goal PoseGoal) (PoseGoal, error) {
+	if goal.FrameId == "" || goal.FrameId == CommandFrameId {
+		return goal, nil
+	}
+	t, err := rc.tfBuffer.Lookup(CommandFrameId, goal.FrameId, time.Time{})
+	if err != nil {
+		return goal, fmt.Errorf("%w: %s -> %s: %v", ErrTransformUnavailable, goal.FrameId, CommandFrameId, err)
+	}
+	goal.Position = t.Apply(goal.Position)
+	if goal.Orientation != nil {
+		q := t.Rotation.Mul(*goal.Orientation)
+		goal.Orientation = &q
+	}
+	goal.FrameId = CommandFrameId
+	return goal, nil
+}
+
+// deltaToCommandFrame は他フレームで指定された相対変位を指令フレームの向きに回します
+func (rc *RobotController) deltaToCommandFrame(delta PoseDelta) (PoseDelta, error) {
+	if delta.FrameId == "" || delta.FrameId == CommandFrameId {
+		return delta, nil
+	}
+	t, err := rc.tfBuffer.Lookup(CommandFrameId, delta.FrameId, time.Time{})
+	if err != nil {
+		return delta, fmt.Errorf("%w: %s -> %s: %v", ErrTransformUnavailable, delta.FrameId, CommandFrameId, err)
+	}
+	// 変位ベクトルは回転だけ、回転増分は共役で指令フレームの軸に直す
+	delta.Translation = t.Rotation.Rotate(delta.Translation)
+	if !delta.Rotation.IsZero() {
+		delta.Rotation = t.Rotation.Mul(delta.Rotation).Mul(t.Rotation.Conj())
+	}
+	delta.FrameId = CommandFrameId
+	return delta, nil
+}
+
 // 最新JPEGの保存（フレーム連番をインクリメント）
 func (rc *RobotController) setLatestJPEG(b []byte) {
 	rc.latestJPEGMu.Lock()
@@ -284,156 +576,241 @@ func encodeSensorImageToJPEG(img *sensor_msgs_msg.Image) ([]byte, error) {
 	}
 }
 
-func (rc *RobotController) PublishPosition(x, y, z float64) error {
+// PublishPosition は目標姿勢を絶対値で更新してPublishします
+// ifMatch が nil でなければ目標位置の版数が一致した場合のみ更新します
+func (rc *RobotController) PublishPosition(ctx context.Context, goal PoseGoal, ifMatch *uint64) (TargetSnapshot, error) {
 	if rc == nil || rc.node == nil {
-		return fmt.Errorf("node not initialized")
+		return TargetSnapshot{}, fmt.Errorf("node not initialized")
 	}
 	if rc.positionPub == nil {
-		return fmt.Errorf("position publisher not initialized")
+		return TargetSnapshot{}, fmt.Errorf("position publisher not initialized")
+	}
+	if err := rc.admit(rc.positionPub.TopicName); err != nil {
+		return rc.target.Snapshot(), err
 	}
-	rc.currentX, rc.currentY, rc.currentZ = x, y, z
+	goal, err := rc.goalToCommandFrame(goal)
+	if err != nil {
+		return rc.target.Snapshot(), err
+	}
+	return rc.target.Set(goal, ifMatch, func(next TargetSnapshot) error {
+		// ログを出力し、メッセージをパブリッシュ
+		rc.log(ctx, slog.LevelInfo, "Publishing position",
+			"x", next.X, "y", next.Y, "z", next.Z, "frame_id", next.FrameId, "version", next.Version)
+		return rc.sendTarget(ctx, next)
+	})
+}
+
+// publishTarget は目標位置を PoseStamped としてPublishします
+func (rc *RobotController) publishTarget(t TargetSnapshot) error {
 	rosMsg := geometry_msgs.PoseStamped{
-		Header: std_msgs.Header{Stamp: rosNow(), FrameId: "base_link"},
+		Header: std_msgs.Header{Stamp: rosNow(), FrameId: t.FrameId},
 		Pose: geometry_msgs.Pose{
-			Position:    geometry_msgs.Point{X: x, Y: y, Z: z},
-			Orientation: geometry_msgs.Quaternion{X: 0, Y: 0, Z: 0, W: 1},
+			Position: geometry_msgs.Point{X: t.X, Y: t.Y, Z: t.Z},
+			Orientation: geometry_msgs.Quaternion{
+				X: t.Orientation.X, Y: t.Orientation.Y, Z: t.Orientation.Z, W: t.Orientation.W,
+			},
 		},
 	}
+	return rc.publish(rc.positionPub, &rosMsg)
+}
+
+// Target は現在の目標位置を返します
+func (rc *RobotController) Target() TargetSnapshot {
+	return rc.target.Snapshot()
+}
+
+// MeasuredPose は最後に受信した計測姿勢を返します（未受信なら ok=false）
+func (rc *RobotController) MeasuredPose() (MeasuredPose, bool) {
+	rc.measuredMu.RLock()
+	defer rc.measuredMu.RUnlock()
+	if rc.measured == nil {
+		return MeasuredPose{}, false
+	}
+	return *rc.measured, true
+}
+
+func (rc *RobotController) setMeasuredPose(msg *geometry_msgs.PoseStamped) {
+	p, o := msg.Pose.Position, msg.Pose.Orientation
+	m := MeasuredPose{
+		X: p.X, Y: p.Y, Z: p.Z,
+		Orientation: geom.Quaternion{X: o.X, Y: o.Y, Z: o.Z, W: o.W},
+		FrameId:     msg.Header.FrameId,
+		ReceivedAt:  time.Now(),
+	}
+	rc.measuredMu.Lock()
+	rc.measured = &m
+	rc.measuredMu.Unlock()
 
-	// ログを出力し、メッセージをパブリッシュ
-	_ = rc.node.Logger().Infof("Publishing position: (%.2f, %.2f, %.2f)", x, y, z)
-	return rc.positionPub.Publish(&rosMsg)
+	// 目標位置は指令フレームで持つので、変換できるまでは初期化しない
+	goal, err := rc.goalToCommandFrame(PoseGoal{Position: m.Position(), Orientation: &m.Orientation, FrameId: m.FrameId})
+	if err != nil {
+		return
+	}
+	m.X, m.Y, m.Z = goal.Position.X, goal.Position.Y, goal.Position.Z
+	m.Orientation = *goal.Orientation
+	m.FrameId = CommandFrameId
+	if rc.target.InitFromMeasured(m) {
+		rc.log(context.Background(), slog.LevelInfo, "Initialized target from measured pose", "x", m.X, "y", m.Y, "z", m.Z, "frame_id", m.FrameId)
+	}
 }
 
-func (rc *RobotController) PublishStartMotion() error {
+func (rc *RobotController) PublishStartMotion(ctx context.Context) error {
 	if rc == nil || rc.node == nil {
 		return fmt.Errorf("node not initialized")
 	}
 	if rc.startPub == nil {
 		return fmt.Errorf("start publisher not initialized")
 	}
+	if err := rc.admit(rc.startPub.TopicName); err != nil {
+		return err
+	}
 	rosMsg := std_msgs.Empty{}
-	_ = rc.node.Logger().Infoln("Publishing start motion command")
-	return rc.startPub.Publish(&rosMsg)
+	rc.log(ctx, slog.LevelInfo, "Publishing start motion command", "topic", rc.startPub.TopicName)
+	return rc.publish(rc.startPub, &rosMsg)
 }
 
-func (rc *RobotController) PublishUpMotion() error {
+func (rc *RobotController) PublishUpMotion(ctx context.Context) error {
 	if rc == nil || rc.node == nil {
 		return fmt.Errorf("node not initialized")
 	}
 	if rc.upMotionPub == nil {
 		return fmt.Errorf("up motion publisher not initialized")
 	}
+	if err := rc.admit(rc.upMotionPub.TopicName); err != nil {
+		return err
+	}
 	rosMsg := std_msgs.Empty{}
-	_ = rc.node.Logger().Infoln("Publishing up motion command")
-	return rc.upMotionPub.Publish(&rosMsg)
+	rc.log(ctx, slog.LevelInfo, "Publishing up motion command", "topic", rc.upMotionPub.TopicName)
+	return rc.publish(rc.upMotionPub, &rosMsg)
 }
 
-func (rc *RobotController) PublishDownMotion() error {
+func (rc *RobotController) PublishDownMotion(ctx context.Context) error {
 	if rc == nil || rc.node == nil {
 		return fmt.Errorf("node not initialized")
 	}
 	if rc.downMotionPub == nil {
 		return fmt.Errorf("down motion publisher not initialized")
 	}
+	if err := rc.admit(rc.downMotionPub.TopicName); err != nil {
+		return err
+	}
 	rosMsg := std_msgs.Empty{}
-	_ = rc.node.Logger().Infoln("Publishing down motion command")
-	return rc.downMotionPub.Publish(&rosMsg)
+	rc.log(ctx, slog.LevelInfo, "Publishing down motion command", "topic", rc.downMotionPub.TopicName)
+	return rc.publish(rc.downMotionPub, &rosMsg)
 }
 
-func (rc *RobotController) PublishAddDownMotion() error {
+func (rc *RobotController) PublishAddDownMotion(ctx context.Context) error {
 	if rc == nil || rc.node == nil {
 		return fmt.Errorf("node not initialized")
 	}
 	if rc.addDownMotionPub == nil {
 		return fmt.Errorf("add down motion publisher not initialized")
 	}
+	if err := rc.admit(rc.addDownMotionPub.TopicName); err != nil {
+		return err
+	}
 	rosMsg := std_msgs.Empty{}
-	_ = rc.node.Logger().Infoln("Publishing add down motion command")
-	return rc.addDownMotionPub.Publish(&rosMsg)
+	rc.log(ctx, slog.LevelInfo, "Publishing add down motion command", "topic", rc.addDownMotionPub.TopicName)
+	return rc.publish(rc.addDownMotionPub, &rosMsg)
 }
 
-func (rc *RobotController) PublishAddUpMotion() error {
+func (rc *RobotController) PublishAddUpMotion(ctx context.Context) error {
 	if rc == nil || rc.node == nil {
 		return fmt.Errorf("node not initialized")
 	}
 	if rc.addUpMotionPub == nil {
 		return fmt.Errorf("add up motion publisher not initialized")
 	}
+	if err := rc.admit(rc.addUpMotionPub.TopicName); err != nil {
+		return err
+	}
 	rosMsg := std_msgs.Empty{}
-	_ = rc.node.Logger().Infoln("Publishing add up motion command")
-	return rc.addUpMotionPub.Publish(&rosMsg)
+	rc.log(ctx, slog.LevelInfo, "Publishing add up motion command", "topic", rc.addUpMotionPub.TopicName)
+	return rc.publish(rc.addUpMotionPub, &rosMsg)
 }
 
-func (rc *RobotController) PublishMiddleMotion() error {
+func (rc *RobotController) PublishMiddleMotion(ctx context.Context) error {
 	if rc == nil || rc.node == nil {
 		return fmt.Errorf("node not initialized")
 	}
 	if rc.middleMotionPub == nil {
 		return fmt.Errorf("middle motion publisher not initialized")
 	}
+	if err := rc.admit(rc.middleMotionPub.TopicName); err != nil {
+		return err
+	}
 	rosMsg := std_msgs.Empty{}
-	_ = rc.node.Logger().Infoln("Publishing middle motion command")
-	return rc.middleMotionPub.Publish(&rosMsg)
+	rc.log(ctx, slog.LevelInfo, "Publishing middle motion command", "topic", rc.middleMotionPub.TopicName)
+	return rc.publish(rc.middleMotionPub, &rosMsg)
 }
 
-func (rc *RobotController) PublishCatchMotion() error {
+func (rc *RobotController) PublishCatchMotion(ctx context.Context) error {
 	if rc == nil || rc.node == nil {
 		return fmt.Errorf("node not initialized")
 	}
 	if rc.catchMotionPub == nil {
 		return fmt.Errorf("catch motion publisher not initialized")
 	}
+	if err := rc.admit(rc.catchMotionPub.TopicName); err != nil {
+		return err
+	}
 	rosMsg := std_msgs.Empty{}
-	_ = rc.node.Logger().Infoln("Publishing catch motion command")
-	return rc.catchMotionPub.Publish(&rosMsg)
+	rc.log(ctx, slog.LevelInfo, "Publishing catch motion command", "topic", rc.catchMotionPub.TopicName)
+	return rc.publish(rc.catchMotionPub, &rosMsg)
 }
 
-func (rc *RobotController) PublishReleaseMotion() error {
+func (rc *RobotController) PublishReleaseMotion(ctx context.Context) error {
 	if rc == nil || rc.node == nil {
 		return fmt.Errorf("node not initialized")
 	}
 	if rc.releaseMotionPub == nil {
 		return fmt.Errorf("release motion publisher not initialized")
 	}
+	if err := rc.admit(rc.releaseMotionPub.TopicName); err != nil {
+		return err
+	}
 	rosMsg := std_msgs.Empty{}
-	_ = rc.node.Logger().Infoln("Publishing release motion command")
-	return rc.releaseMotionPub.Publish(&rosMsg)
+	rc.log(ctx, slog.LevelInfo, "Publishing release motion command", "topic", rc.releaseMotionPub.TopicName)
+	return rc.publish(rc.releaseMotionPub, &rosMsg)
 }
 
-func (rc *RobotController) PublishResetMotion() error {
+func (rc *RobotController) PublishResetMotion(ctx context.Context) error {
 	if rc == nil || rc.node == nil {
 		return fmt.Errorf("node not initialized")
 	}
 	if rc.resetPub == nil {
 		return fmt.Errorf("reset publisher not initialized")
 	}
+	if err := rc.admit(rc.resetPub.TopicName); err != nil {
+		return err
+	}
 	rosMsg := std_msgs.Empty{}
-	_ = rc.node.Logger().Infoln("Publishing reset motion command")
-	return rc.resetPub.Publish(&rosMsg)
+	rc.log(ctx, slog.LevelInfo, "Publishing reset motion command", "topic", rc.resetPub.TopicName)
+	return rc.publish(rc.resetPub, &rosMsg)
 }
 
 // 相対変位を受け取り、内部に累積した目標絶対位置を更新してPublish
-func (rc *RobotController) PublishDisplacement(dx, dy, dz float64) error {
+// 目標位置が未初期化（永続化も計測姿勢も無い）場合は原点へ飛ばないよう ErrTargetUninitialized を返す
+func (rc *RobotController) PublishDisplacement(ctx context.Context, delta PoseDelta, ifMatch *uint64) (TargetSnapshot, error) {
 	if rc == nil || rc.node == nil {
-		return fmt.Errorf("node not initialized")
+		return TargetSnapshot{}, fmt.Errorf("node not initialized")
 	}
 	if rc.positionPub == nil {
-		return fmt.Errorf("position publisher not initialized")
+		return TargetSnapshot{}, fmt.Errorf("position publisher not initialized")
 	}
-	// 累積
-	rc.currentX += dx
-	rc.currentY += dy
-	rc.currentZ += dz
-	rosMsg := geometry_msgs.PoseStamped{
-		Header: std_msgs.Header{Stamp: rosNow(), FrameId: "base_link"},
-		Pose: geometry_msgs.Pose{
-			Position:    geometry_msgs.Point{X: rc.currentX, Y: rc.currentY, Z: rc.currentZ},
-			Orientation: geometry_msgs.Quaternion{X: 0, Y: 0, Z: 0, W: 1},
-		},
+	if err := rc.admit(rc.positionPub.TopicName); err != nil {
+		return rc.target.Snapshot(), err
 	}
-	_ = rc.node.Logger().Infof("Publishing displacement accumulated -> (%.3f, %.3f, %.3f)", rc.currentX, rc.currentY, rc.currentZ)
-	return rc.positionPub.Publish(&rosMsg)
+	delta, err := rc.deltaToCommandFrame(delta)
+	if err != nil {
+		return rc.target.Snapshot(), err
+	}
+	// 累積
+	return rc.target.Add(delta, ifMatch, func(next TargetSnapshot) error {
+		rc.log(ctx, slog.LevelInfo, "Publishing displacement accumulated",
+			"x", next.X, "y", next.Y, "z", next.Z, "version", next.Version)
+		return rc.sendTarget(ctx, next)
+	})
 }
 
 func (rc *RobotController) SubscribeTopics() ([]string, error) {
@@ -451,11 +828,23 @@ func (rc *RobotController) SubscribeTopics() ([]string, error) {
 	return topicNames, nil
 }
 
+// Close は spin を止めてから Subscriber・Client・Publisher・ノードの順に閉じます
+// 先に BeginShutdown（と必要なら Park）を呼んでおくこと。呼ばれていなければここで呼ぶ
 func (rc *RobotController) Close() {
-	// spin 停止
+	rc.BeginShutdown()
+
+	// spin 停止（コールバックが閉じた Subscription を触らないよう終わるまで待つ）
 	if rc.spinCancel != nil {
 		rc.spinCancel()
 	}
+	if rc.spinDone != nil {
+		select {
+		case <-rc.spinDone:
+		case <-time.After(spinStopTimeout):
+			rc.log(context.Background(), slog.LevelWarn, "spin did not stop in time", "timeout", spinStopTimeout)
+		}
+	}
+	rc.closeServiceClients()
 
 	if rc.rawImageSub != nil {
 		rc.rawImageSub.Close()
@@ -463,6 +852,33 @@ func (rc *RobotController) Close() {
 	if rc.compressedImageSub != nil {
 		rc.compressedImageSub.Close()
 	}
+	if rc.measuredPoseSub != nil {
+		rc.measuredPoseSub.Close()
+	}
+	if rc.urdfSub != nil {
+		rc.urdfSub.Close()
+	}
+	if rc.urdfParamClient != nil {
+		rc.urdfParamClient.Close()
+	}
+	if rc.jointStatesSub != nil {
+		rc.jointStatesSub.Close()
+	}
+	if rc.joySub != nil {
+		rc.joySub.Close()
+	}
+	if rc.rosoutSub != nil {
+		rc.rosoutSub.Close()
+	}
+	if rc.diagnosticsSub != nil {
+		rc.diagnosticsSub.Close()
+	}
+	if rc.tfSub != nil {
+		rc.tfSub.Close()
+	}
+	if rc.tfStaticSub != nil {
+		rc.tfStaticSub.Close()
+	}
 
 	if rc.positionPub != nil {
 		rc.positionPub.Close()
@@ -470,6 +886,9 @@ func (rc *RobotController) Close() {
 	if rc.jointAnglesPub != nil {
 		rc.jointAnglesPub.Close()
 	}
+	if rc.jogPub != nil {
+		rc.jogPub.Close()
+	}
 	if rc.startPub != nil {
 		rc.startPub.Close()
 	}
@@ -526,19 +945,127 @@ func (rc *RobotController) Get(topic_name string) (*rclgo.Publisher, error) {
 		return rc.middleMotionPub, nil
 	case "/joint_angles":
 		return rc.jointAnglesPub, nil
+	case "/jog_twist":
+		return rc.jogPub, nil
 	default:
 		return nil, fmt.Errorf("unknown topic: %s", topic_name)
 	}
 }
 
-func (rc *RobotController) PublishJointAngles(angles []float32) error {
+// PublishJointAngles は関節モデルで検証してから関節角度をPublishします
+// 制限違反は *joint.ValidationError で返ります。max_step は計測関節角度との差で見るので、未受信なら ErrNoJointData
+func (rc *RobotController) PublishJointAngles(ctx context.Context, angles []float32) error {
+	return rc.publishJointAngles(ctx, angles, true)
+}
+
+// publishJointAngles は admit が false なら停止処理中・送信制限の確認を省きます
+// （目標姿勢を関節角度で送る場合は goal_pose 側で確認済み）
+func (rc *RobotController) publishJointAngles(ctx context.Context, angles []float32, admit bool) error {
 	if rc == nil || rc.node == nil {
 		return fmt.Errorf("node not initialized")
 	}
 	if rc.jointAnglesPub == nil {
 		return fmt.Errorf("joint angles publisher not initialized")
 	}
+	values := make([]float64, len(angles))
+	for i, a := range angles {
+		values[i] = float64(a)
+	}
+
+	rc.lastJointsMu.Lock()
+	defer rc.lastJointsMu.Unlock()
+	prev, err := rc.stepReference()
+	if err != nil {
+		return err
+	}
+	if err := rc.joints.Validate(values, prev); err != nil {
+		return err
+	}
+	if admit {
+		if err := rc.admit(rc.jointAnglesPub.TopicName); err != nil {
+			return err
+		}
+	}
 	rosMsg := std_msgs.Float32MultiArray{Data: angles}
-	_ = rc.node.Logger().Infoln("Publishing joint angles")
-	return rc.jointAnglesPub.Publish(&rosMsg)
+	rc.log(ctx, slog.LevelInfo, "Publishing joint angles", "angles", angles)
+	if err := rc.publish(rc.jointAnglesPub, &rosMsg); err != nil {
+		return err
+	}
+	rc.lastJointsCmd = values
+	return nil
+}
+
+// stepReference は max_step の基準にする今の関節角度（最後に受信した /joint_states）を返します
+// max_step を設定していなければ nil。再起動直後の1回目の指令でも大きく跳ばないよう、未受信なら ErrNoJointData
+func (rc *RobotController) stepReference() ([]float64, error) {
+	if !rc.joints.HasStepLimit() {
+		return nil, nil
+	}
+	js, ok := rc.JointStates()
+	if !ok {
+		return nil, fmt.Errorf("%w: no joint states received to check max_step", ErrNoJointData)
+	}
+	prev, err := rc.joints.Ordered(js.Positions)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrNoJointData, err)
+	}
+	return prev, nil
+}
+
+// publishTwist は速度ジョグの TwistStamped をPublishします（Jogger から一定周期で呼ばれる）
+func (rc *RobotController) publishTwist(t jog.Twist) error {
+	if rc == nil || rc.node == nil {
+		return fmt.Errorf("node not initialized")
+	}
+	if rc.jogPub == nil {
+		return fmt.Errorf("jog publisher not initialized")
+	}
+	// 止める指令（ゼロ速度）は停止処理中・非常停止・試合の状態によらず送る
+	// 停止処理に入った後の速度は、駐機姿勢へ動かしている最中にジョグが再開しないよう admit と同じく拒否する
+	if !t.IsZero() {
+		select {
+		case <-rc.shutdownCh:
+			metricPublishRejected.Inc(rc.jogPub.TopicName, "shutting_down")
+			return ErrShuttingDown
+		default:
+		}
+		if rc.EStopStatus().Engaged {
+			metricPublishRejected.Inc(rc.jogPub.TopicName, "estop")
+			return ErrEStopped
+		}
+		if err := rc.match.Allow(); err != nil {
+			metricPublishRejected.Inc(rc.jogPub.TopicName, "match")
+			return err
+		}
+	}
+	rosMsg := geometry_msgs.TwistStamped{
+		Header: std_msgs.Header{Stamp: rosNow(), FrameId: rc.jogger.Config().FrameId},
+		Twist: geometry_msgs.Twist{
+			Linear:  geometry_msgs.Vector3{X: t.Linear.X, Y: t.Linear.Y, Z: t.Linear.Z},
+			Angular: geometry_msgs.Vector3{X: t.Angular.X, Y: t.Angular.Y, Z: t.Angular.Z},
+		},
+	}
+	return rc.publish(rc.jogPub, &rosMsg)
+}
+
+// TopicLimits はトピックごとの送信制限の設定と回数を返します
+func (rc *RobotController) TopicLimits() []ratelimit.Stat {
+	return rc.topicLimits.Stats()
+}
+
+// Jogger は速度ジョグを返します
+func (rc *RobotController) Jogger() *jog.Jogger {
+	return rc.jogger
+}
+
+// JointModel は関節の並び順と制限を返します
+func (rc *RobotController) JointModel() *joint.Model {
+	return rc.joints
+}
+
+// LastJointCommand は最後に送った関節角度を返します（未送信なら nil）
+func (rc *RobotController) LastJointCommand() []float64 {
+	rc.lastJointsMu.Lock()
+	defer rc.lastJointsMu.Unlock()
+	return append([]float64(nil), rc.lastJointsCmd...)
 }
@@ -0,0 +1,159 @@
+// internal/robot/controllers.go
+package robot
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"catchrobo_app/internal/controllers"
+
+	builtin_interfaces "msgs/builtin_interfaces/msg"
+	controller_manager_msgs_msg "msgs/controller_manager_msgs/msg"
+	controller_manager_msgs_srv "msgs/controller_manager_msgs/srv"
+)
+
+// switchTimeoutMargin は切り替えの timeout_s に足して応答を待つ時間です
+const switchTimeoutMargin = 2 * time.Second
+
+// ControllerManager は controller_manager のノード名を返します
+func (rc *RobotController) ControllerManager() string {
+	return rc.controllerManager
+}
+
+// ListControllers は controller_manager に読み込まれたコントローラーと状態・確保しているインターフェースを返します
+func (rc *RobotController) ListControllers(ctx context.Context) ([]controllers.Controller, error) {
+	service, err := rc.nodeService(rc.controllerManager, "list_controllers")
+	if err != nil {
+		return nil, err
+	}
+	resp, err := rc.callService(ctx, service, controller_manager_msgs_srv.ListControllersTypeSupport, &controller_manager_msgs_srv.ListControllers_Request{})
+	if err != nil {
+		return nil, err
+	}
+	states := resp.(*controller_manager_msgs_srv.ListControllers_Response).Controller
+	out := make([]controllers.Controller, len(states))
+	for i, s := range states {
+		out[i] = controllers.Controller{
+			Name:                      s.Name,
+			State:                     s.State,
+			Type:                      s.Type,
+			ClaimedInterfaces:         nonNil(s.ClaimedInterfaces),
+			RequiredCommandInterfaces: nonNil(s.RequiredCommandInterfaces),
+			RequiredStateInterfaces:   nonNil(s.RequiredStateInterfaces),
+			IsChainable:               s.IsChainable,
+			IsChained:                 s.IsChained,
+		}
+	}
+	return out, nil
+}
+
+// ListHardwareComponents は ros2_control のハードウェアコンポーネントと状態・インターフェースを返します
+func (rc *RobotController) ListHardwareComponents(ctx context.Context) ([]controllers.HardwareComponent, error) {
+	service, err := rc.nodeService(rc.controllerManager, "list_hardware_components")
+	if err != nil {
+		return nil, err
+	}
+	resp, err := rc.callService(ctx, service, controller_manager_msgs_srv.ListHardwareComponentsTypeSupport, &controller_manager_msgs_srv.ListHardwareComponents_Request{})
+	if err != nil {
+		return nil, err
+	}
+	comps := resp.(*controller_manager_msgs_srv.ListHardwareComponents_Response).Component
+	out := make([]controllers.HardwareComponent, len(comps))
+	for i, c := range comps {
+		out[i] = controllers.HardwareComponent{
+			Name:              c.Name,
+			Type:              c.Type,
+			ClassType:         c.ClassType,
+			State:             c.State.Label,
+			CommandInterfaces: fromHardwareInterfaces(c.CommandInterfaces),
+			StateInterfaces:   fromHardwareInterfaces(c.StateInterfaces),
+		}
+	}
+	return out, nil
+}
+
+// LoadController はコントローラーを読み込みます（種類などは controller_manager のパラメータで決まる）
+func (rc *RobotController) LoadController(ctx context.Context, name string) error {
+	service, err := rc.nodeService(rc.controllerManager, "load_controller")
+	if err != nil {
+		return err
+	}
+	resp, err := rc.callService(ctx, service, controller_manager_msgs_srv.LoadControllerTypeSupport, &controller_manager_msgs_srv.LoadController_Request{Name: name})
+	if err != nil {
+		return err
+	}
+	if !resp.(*controller_manager_msgs_srv.LoadController_Response).Ok {
+		return fmt.Errorf("%w: load %s", controllers.ErrRejected, name)
+	}
+	rc.log(ctx, slog.LevelInfo, "controller loaded", "controller", name)
+	return nil
+}
+
+// ConfigureController は読み込んだコントローラーを configure して inactive にします
+func (rc *RobotController) ConfigureController(ctx context.Context, name string) error {
+	service, err := rc.nodeService(rc.controllerManager, "configure_controller")
+	if err != nil {
+		return err
+	}
+	resp, err := rc.callService(ctx, service, controller_manager_msgs_srv.ConfigureControllerTypeSupport, &controller_manager_msgs_srv.ConfigureController_Request{Name: name})
+	if err != nil {
+		return err
+	}
+	if !resp.(*controller_manager_msgs_srv.ConfigureController_Response).Ok {
+		return fmt.Errorf("%w: configure %s", controllers.ErrRejected, name)
+	}
+	rc.log(ctx, slog.LevelInfo, "controller configured", "controller", name)
+	return nil
+}
+
+// SwitchControllers はコントローラーを有効・無効にします（sw は Normalize 済み）
+func (rc *RobotController) SwitchControllers(ctx context.Context, sw controllers.Switch) error {
+	service, err := rc.nodeService(rc.controllerManager, "switch_controller")
+	if err != nil {
+		return err
+	}
+	strictness := controller_manager_msgs_srv.SwitchController_Request_STRICT
+	if sw.Strictness == controllers.BestEffort {
+		strictness = controller_manager_msgs_srv.SwitchController_Request_BEST_EFFORT
+	}
+	timeout := sw.Timeout()
+	req := &controller_manager_msgs_srv.SwitchController_Request{
+		ActivateControllers:   sw.Activate,
+		DeactivateControllers: sw.Deactivate,
+		Strictness:            strictness,
+		ActivateAsap:          sw.ActivateAsap,
+		Timeout:               builtin_interfaces.Duration{Sec: int32(timeout / time.Second), Nanosec: uint32(timeout % time.Second)},
+	}
+	// controller_manager が切り替えを待つ間は応答が来ないので、その分だけ長く待つ
+	wait := rc.serviceTimeout
+	if timeout > 0 && timeout+switchTimeoutMargin > wait {
+		wait = timeout + switchTimeoutMargin
+	}
+	resp, err := rc.callServiceTimeout(ctx, service, controller_manager_msgs_srv.SwitchControllerTypeSupport, req, wait)
+	if err != nil {
+		return err
+	}
+	if !resp.(*controller_manager_msgs_srv.SwitchController_Response).Ok {
+		return fmt.Errorf("%w: switch (activate %v, deactivate %v, %s)", controllers.ErrRejected, sw.Activate, sw.Deactivate, sw.Strictness)
+	}
+	rc.log(ctx, slog.LevelInfo, "controllers switched", "activate", sw.Activate, "deactivate", sw.Deactivate, "strictness", sw.Strictness)
+	return nil
+}
+
+func fromHardwareInterfaces(ifs []controller_manager_msgs_msg.HardwareInterface) []controllers.Interface {
+	out := make([]controllers.Interface, len(ifs))
+	for i, f := range ifs {
+		out[i] = controllers.Interface{Name: f.Name, Available: f.IsAvailable, Claimed: f.IsClaimed}
+	}
+	return out
+}
+
+// nonNil は JSON で null にならないように nil を空のスライスにします
+func nonNil(s []string) []string {
+	if s == nil {
+		return []string{}
+	}
+	return s
+}
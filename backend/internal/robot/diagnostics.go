@@ -0,0 +1,53 @@
+// internal/robot/diagnostics.go
+package robot
+
+import (
+	"catchrobo_app/internal/config"
+	"catchrobo_app/internal/diagnostics"
+
+	diagnostic_msgs "msgs/diagnostic_msgs/msg"
+
+	"github.com/tiiuae/rclgo/pkg/rclgo"
+)
+
+// setupDiagnostics は /diagnostics（diagnostic_msgs/DiagnosticArray）を購読して項目ごとにまとめます。spin 開始前に呼ぶ
+func (rc *RobotController) setupDiagnostics(cfg *config.Config) {
+	rc.diagnostics = diagnostics.New(cfg.Diagnostics.StaleAfter())
+	if cfg.Diagnostics.Topic == "" {
+		return
+	}
+	sub, err := rc.node.NewSubscription(cfg.Diagnostics.Topic, diagnostic_msgs.DiagnosticArrayTypeSupport, nil, func(sub *rclgo.Subscription) {
+		var msg diagnostic_msgs.DiagnosticArray
+		if _, err := sub.TakeMessage(&msg); err != nil {
+			rc.warn("failed to take diagnostics", err)
+			return
+		}
+		statuses := make([]diagnostics.Status, 0, len(msg.Status))
+		for _, s := range msg.Status {
+			st := diagnostics.Status{
+				Name:       s.Name,
+				HardwareID: s.HardwareId,
+				Level:      int(s.Level),
+				Message:    s.Message,
+			}
+			if len(s.Values) > 0 {
+				st.Values = make(map[string]string, len(s.Values))
+				for _, kv := range s.Values {
+					st.Values[kv.Key] = kv.Value
+				}
+			}
+			statuses = append(statuses, st)
+		}
+		rc.diagnostics.Update(statuses)
+	})
+	if err == nil {
+		rc.diagnosticsSub = sub
+	} else {
+		rc.warn("failed to subscribe diagnostics", err)
+	}
+}
+
+// Diagnostics は /diagnostics をまとめたものを返します
+func (rc *RobotController) Diagnostics() *diagnostics.Aggregator {
+	return rc.diagnostics
+}
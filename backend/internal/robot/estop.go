@@ -0,0 +1,161 @@
+// internal/robot/estop.go
+package robot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	std_msgs "msgs/std_msgs/msg"
+)
+
+// ErrEStopped は非常停止が掛かっている間に指令を送ろうとした場合に返ります
+var ErrEStopped = errors.New("emergency stop is engaged")
+
+// ErrNoHoldReference は非常停止で腕を止めるのに使える新しい計測（関節角度・姿勢）が無い場合に返ります
+var ErrNoHoldReference = errors.New("no recent joint states or measured pose to hold at")
+
+// holdMaxAge はこれより古い計測では腕を止めません（古い位置へ戻してしまわないように）
+const holdMaxAge = time.Second
+
+// 非常停止を掛けた経路
+const (
+	EStopSourceJoy = "joy"
+	EStopSourceAPI = "api"
+)
+
+// EStopStatus は非常停止の状態です
+type EStopStatus struct {
+	Engaged bool      `json:"engaged"`
+	Source  string    `json:"source,omitempty"` // joy / api
+	Reason  string    `json:"reason,omitempty"`
+	Since   time.Time `json:"since"`
+	Count   uint64    `json:"count"` // 起動してから掛けた回数
+	// 動いている腕を止めるため、今の位置を目標として送った指令のトピック。送れなかったときは HoldError に理由
+	Hold      []string `json:"hold,omitempty"`
+	HoldError string   `json:"hold_error,omitempty"`
+}
+
+// EStop は非常停止を掛けます。解除（ClearEStop）するまでゲームパッド・HTTP・WebSocket の指令を全て拒否する
+// 速度ジョグを止め、実行中の計画もやめ、既に送った goal_pose / joint_angles の動きは今の位置を目標に送り直して止める
+// 既に掛かっていても止める処理はもう一度行う
+func (rc *RobotController) EStop(ctx context.Context, source, reason string) EStopStatus {
+	rc.estopMu.Lock()
+	if !rc.estop.Engaged {
+		rc.estop = EStopStatus{Engaged: true, Source: source, Reason: reason, Since: time.Now(), Count: rc.estop.Count + 1}
+	}
+	rc.estopMu.Unlock()
+
+	rc.jogger.Stop("", "estop")
+	_, _ = rc.plan.Abort()
+	held, err := rc.hold(ctx)
+
+	rc.estopMu.Lock()
+	rc.estop.Hold, rc.estop.HoldError = held, ""
+	if err != nil {
+		rc.estop.HoldError = err.Error()
+	}
+	st := rc.estop
+	rc.estopMu.Unlock()
+	if err != nil {
+		rc.log(ctx, slog.LevelError, "Emergency stop engaged but the arm could not be held", "source", source, "reason", reason, "error", err)
+	} else {
+		rc.log(ctx, slog.LevelWarn, "Emergency stop engaged", "source", source, "reason", reason, "hold", held)
+	}
+	return st
+}
+
+// hold は腕を今の位置に留める指令を送り、送ったトピックを返します
+// 新しい /joint_states があれば joint_angles に、計測姿勢があれば goal_pose に、それぞれ今の値を送る
+// （どちらの経路で動いていても止まるように両方送る）。非常停止中なので admit（試合の状態・送信制限）は通さない
+func (rc *RobotController) hold(ctx context.Context) ([]string, error) {
+	var held []string
+	var errs []error
+	if js, ok := rc.JointStates(); ok && time.Since(js.ReceivedAt) <= holdMaxAge && len(rc.joints.Joints) > 0 {
+		if err := rc.holdJoints(ctx, js); err != nil {
+			errs = append(errs, fmt.Errorf("hold joints: %w", err))
+		} else {
+			held = append(held, rc.jointAnglesPub.TopicName)
+		}
+	}
+	if m, ok := rc.MeasuredPose(); ok && time.Since(m.ReceivedAt) <= holdMaxAge {
+		if err := rc.holdPose(ctx, m); err != nil {
+			errs = append(errs, fmt.Errorf("hold pose: %w", err))
+		} else {
+			held = append(held, rc.positionPub.TopicName)
+		}
+	}
+	if len(held) == 0 && len(errs) == 0 {
+		return nil, ErrNoHoldReference
+	}
+	return held, errors.Join(errs...)
+}
+
+// holdJoints は計測関節角度をそのまま joint_angles で送ります（今の値なので関節の制限は確かめない）
+func (rc *RobotController) holdJoints(ctx context.Context, js JointStates) error {
+	values, err := rc.joints.Ordered(js.Positions)
+	if err != nil {
+		return err
+	}
+	angles := make([]float32, len(values))
+	for i, v := range values {
+		angles[i] = float32(v)
+	}
+	rc.lastJointsMu.Lock()
+	defer rc.lastJointsMu.Unlock()
+	rc.log(ctx, slog.LevelWarn, "Holding joint angles for emergency stop", "angles", angles)
+	if err := rc.publish(rc.jointAnglesPub, &std_msgs.Float32MultiArray{Data: angles}); err != nil {
+		return err
+	}
+	rc.lastJointsCmd = values
+	return nil
+}
+
+// holdPose は計測姿勢を目標位置にして goal_pose で送ります（IK の確認は通さない）
+func (rc *RobotController) holdPose(ctx context.Context, m MeasuredPose) error {
+	goal, err := rc.goalToCommandFrame(PoseGoal{Position: m.Position(), Orientation: &m.Orientation, FrameId: m.FrameId})
+	if err != nil {
+		return err
+	}
+	next, err := rc.target.Set(goal, nil, rc.publishTarget)
+	if err != nil {
+		return err
+	}
+	rc.log(ctx, slog.LevelWarn, "Holding measured pose for emergency stop", "x", next.X, "y", next.Y, "z", next.Z, "version", next.Version)
+	return nil
+}
+
+// ClearEStop は非常停止を解除します（掛かっていなければ何もしない）
+func (rc *RobotController) ClearEStop(ctx context.Context) EStopStatus {
+	rc.estopMu.Lock()
+	was := rc.estop
+	rc.estop.Engaged = false
+	st := rc.estop
+	rc.estopMu.Unlock()
+	if was.Engaged {
+		rc.log(ctx, slog.LevelWarn, "Emergency stop cleared", "source", was.Source, "since", was.Since)
+	}
+	return st
+}
+
+// EStopStatus は非常停止の状態を返します
+func (rc *RobotController) EStopStatus() EStopStatus {
+	rc.estopMu.Lock()
+	defer rc.estopMu.Unlock()
+	return rc.estop
+}
+
+// AllowMotion は今モーション指令を受け付けるかを返します（停止処理中、非常停止、試合の状態の順）
+func (rc *RobotController) AllowMotion() error {
+	select {
+	case <-rc.shutdownCh:
+		return ErrShuttingDown
+	default:
+	}
+	if rc.EStopStatus().Engaged {
+		return ErrEStopped
+	}
+	return rc.match.Allow()
+}
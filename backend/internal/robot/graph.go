@@ -0,0 +1,45 @@
+// internal/robot/graph.go
+package robot
+
+import (
+	"catchrobo_app/internal/graph"
+)
+
+// Graph は ROS グラフ（ノードごとの Publisher・Subscriber・サービス）を問い合わせてまとめます
+// includeHidden なら /rosout やパラメータのサービスなど全てのノードが持つものも含める
+func (rc *RobotController) Graph(includeHidden bool) (*graph.Graph, error) {
+	nodes, err := rc.NodeNames()
+	if err != nil {
+		return nil, err
+	}
+	b := graph.NewBuilder(includeHidden)
+	for _, node := range nodes {
+		b.AddNode(node)
+		name, namespace := splitNodeName(node)
+		// 問い合わせの間にノードが終了した場合は、取れた分だけ使う
+		if pubs, err := rc.node.GetPublisherNamesAndTypesByNode(true, name, namespace); err == nil {
+			for topic, types := range pubs {
+				b.AddPublisher(node, topic, types)
+			}
+		}
+		if subs, err := rc.node.GetSubscriberNamesAndTypesByNode(true, name, namespace); err == nil {
+			for topic, types := range subs {
+				b.AddSubscriber(node, topic, types)
+			}
+		}
+		if services, err := rc.node.GetServiceNamesAndTypesByNode(name, namespace); err == nil {
+			for service, types := range services {
+				b.AddServer(node, service, types)
+			}
+		}
+		if clients, err := rc.node.GetClientNamesAndTypesByNode(name, namespace); err == nil {
+			for service, types := range clients {
+				b.AddClient(node, service, types)
+			}
+		}
+	}
+	for _, p := range rc.commandPublishers() {
+		b.MarkCommand(p.TopicName)
+	}
+	return b.Build(), nil
+}
@@ -0,0 +1,183 @@
+// internal/robot/health.go
+package robot
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/tiiuae/rclgo/pkg/rclgo"
+)
+
+// チェックの結果
+const (
+	HealthOK   = "ok"
+	HealthWarn = "warn" // 準備完了の判定には使わない項目の異常
+	HealthFail = "fail"
+)
+
+// HealthCheck は1項目の結果です
+type HealthCheck struct {
+	Name     string   `json:"name"`
+	Status   string   `json:"status"`
+	Required bool     `json:"required"`
+	Detail   string   `json:"detail,omitempty"`
+	AgeS     *float64 `json:"age_s,omitempty"`       // 最終受信からの経過時間
+	Count    *int     `json:"subscribers,omitempty"` // Publisher に接続している Subscriber 数
+}
+
+// HealthReport は /healthz・/readyz の応答です。必須の項目が全て ok なら Status は ok
+type HealthReport struct {
+	Status string        `json:"status"`
+	Time   time.Time     `json:"time"`
+	Checks []HealthCheck `json:"checks"`
+}
+
+// Liveness はプロセスが生きているか（spin が回っているか）を返します
+func (rc *RobotController) Liveness() HealthReport {
+	return newReport([]HealthCheck{rc.spinCheck()})
+}
+
+// Readiness は試合に使える状態か（アームのノードが指令を聞いていて、カメラと関節角度が届いているか）を返します
+func (rc *RobotController) Readiness() HealthReport {
+	checks := []HealthCheck{rc.spinCheck(), rc.shutdownCheck()}
+	checks = append(checks, rc.publisherChecks()...)
+	checks = append(checks, rc.cameraChecks()...)
+	checks = append(checks, rc.jointStateCheck())
+	return newReport(checks)
+}
+
+func newReport(checks []HealthCheck) HealthReport {
+	status := HealthOK
+	for _, c := range checks {
+		if c.Required && c.Status == HealthFail {
+			status = HealthFail
+		}
+	}
+	return HealthReport{Status: status, Time: time.Now(), Checks: checks}
+}
+
+func (rc *RobotController) spinCheck() HealthCheck {
+	c := HealthCheck{Name: "spin", Status: HealthOK, Required: true}
+	select {
+	case <-rc.spinDone:
+		c.Status, c.Detail = HealthFail, "spin goroutine has stopped"
+	default:
+	}
+	return c
+}
+
+func (rc *RobotController) shutdownCheck() HealthCheck {
+	c := HealthCheck{Name: "accepting_commands", Status: HealthOK, Required: true}
+	select {
+	case <-rc.shutdownCh:
+		c.Status, c.Detail = HealthFail, ErrShuttingDown.Error()
+	default:
+	}
+	return c
+}
+
+// commandPublishers は指令を送る Publisher の一覧です
+func (rc *RobotController) commandPublishers() []*rclgo.Publisher {
+	pubs := []*rclgo.Publisher{
+		rc.positionPub, rc.startPub, rc.resetPub, rc.catchMotionPub, rc.releaseMotionPub,
+		rc.upMotionPub, rc.downMotionPub, rc.addDownMotionPub, rc.addUpMotionPub, rc.middleMotionPub,
+		rc.jointAnglesPub, rc.jogPub,
+	}
+	out := pubs[:0]
+	for _, p := range pubs {
+		if p != nil {
+			out = append(out, p)
+		}
+	}
+	return out
+}
+
+// publisherChecks は指令トピックごとに Subscriber が居るか（アームのノードが聞いているか）を調べます
+func (rc *RobotController) publisherChecks() []HealthCheck {
+	optional := map[string]bool{}
+	for _, t := range rc.health.OptionalTopics {
+		optional[t] = true
+	}
+	var out []HealthCheck
+	for _, p := range rc.commandPublishers() {
+		c := HealthCheck{Name: "publisher:" + p.TopicName, Status: HealthOK, Required: !optional[p.TopicName]}
+		n, err := p.GetSubscriptionCount()
+		switch {
+		case err != nil:
+			c.Status, c.Detail = HealthFail, err.Error()
+		case n == 0:
+			c.Status, c.Detail = HealthFail, "no subscribers"
+		}
+		if err == nil {
+			c.Count = &n
+		}
+		out = append(out, downgrade(c))
+	}
+	return out
+}
+
+// cameraChecks はカメラのトピックごとの最終フレームの経過時間を調べます
+// require_camera なら、いずれか1つのカメラが新しければよい
+func (rc *RobotController) cameraChecks() []HealthCheck {
+	maxAge := time.Duration(rc.health.MaxCameraAgeS * float64(time.Second))
+	rc.latestJPEGMu.RLock()
+	topics := make([]string, 0, len(rc.cameras))
+	for t := range rc.cameras {
+		topics = append(topics, t)
+	}
+	sort.Strings(topics)
+	now := time.Now()
+	var out []HealthCheck
+	anyFresh := false
+	for _, t := range topics {
+		c := ageCheck("camera:"+t, rc.cameras[t].last, now, maxAge)
+		if c.Status == HealthOK {
+			anyFresh = true
+		}
+		out = append(out, c)
+	}
+	rc.latestJPEGMu.RUnlock()
+
+	summary := HealthCheck{Name: "camera", Status: HealthOK, Required: rc.health.RequireCamera}
+	if !anyFresh {
+		summary.Status, summary.Detail = HealthFail, fmt.Sprintf("no camera frame within %s", maxAge)
+	}
+	for i := range out {
+		out[i] = downgrade(out[i])
+	}
+	return append([]HealthCheck{downgrade(summary)}, out...)
+}
+
+func (rc *RobotController) jointStateCheck() HealthCheck {
+	maxAge := time.Duration(rc.health.MaxJointStateAgeS * float64(time.Second))
+	rc.jointStatesMu.RLock()
+	at := rc.jointStates.ReceivedAt
+	rc.jointStatesMu.RUnlock()
+	c := ageCheck("joint_states", at, time.Now(), maxAge)
+	c.Required = rc.health.RequireJointStates
+	return downgrade(c)
+}
+
+// ageCheck は最終受信時刻が maxAge 以内かを調べます（未受信なら fail）
+func ageCheck(name string, at, now time.Time, maxAge time.Duration) HealthCheck {
+	c := HealthCheck{Name: name, Status: HealthOK}
+	if at.IsZero() {
+		c.Status, c.Detail = HealthFail, "nothing received yet"
+		return c
+	}
+	age := now.Sub(at).Seconds()
+	c.AgeS = &age
+	if maxAge > 0 && now.Sub(at) > maxAge {
+		c.Status, c.Detail = HealthFail, fmt.Sprintf("older than %s", maxAge)
+	}
+	return c
+}
+
+// downgrade は必須でない項目の fail を warn にします
+func downgrade(c HealthCheck) HealthCheck {
+	if !c.Required && c.Status == HealthFail {
+		c.Status = HealthWarn
+	}
+	return c
+}
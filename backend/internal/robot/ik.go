@@ -0,0 +1,156 @@
+// internal/robot/ik.go
+package robot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"catchrobo_app/internal/geom"
+	"catchrobo_app/internal/kinematics"
+)
+
+// UnreachableError は目標姿勢に逆運動学の解が無い場合に返ります
+type UnreachableError struct {
+	Result kinematics.IKResult
+}
+
+func (e *UnreachableError) Error() string {
+	return fmt.Sprintf("target unreachable (position error %.4f m, orientation error %.4f rad)",
+		e.Result.PositionError, e.Result.OrientationError)
+}
+
+// IKSolution は逆運動学の結果を関節名・関節モデルの並び順で表したものです
+type IKSolution struct {
+	Reachable        bool               `json:"reachable"`
+	Joints           map[string]float64 `json:"joints"`
+	Angles           []float64          `json:"angles,omitempty"` // 関節モデルの並び順（対応しない関節があれば省略）
+	Iterations       int                `json:"iterations"`
+	PositionError    float64            `json:"position_error"`
+	OrientationError float64            `json:"orientation_error"`
+	Target           geom.Transform     `json:"target"` // チェーンの base から見た目標姿勢
+	Base             string             `json:"base"`
+	Tip              string             `json:"tip"`
+}
+
+// IKOptions は設定された逆運動学のオプションを返します
+func (rc *RobotController) IKOptions() kinematics.IKOptions {
+	return rc.ik.Options
+}
+
+// SolveIK は目標姿勢の逆運動学を解きます（プレビュー用。Publish はしない）
+// seed は関節モデルの並び順の初期値（nil なら最後の指令値・計測値から作る）、opts が nil なら設定値を使います
+func (rc *RobotController) SolveIK(goal PoseGoal, seed []float64, opts *kinematics.IKOptions) (IKSolution, error) {
+	goal, err := rc.goalToCommandFrame(goal)
+	if err != nil {
+		return IKSolution{}, err
+	}
+	target := rc.target.Snapshot()
+	if goal.Orientation == nil {
+		goal.Orientation = &target.Orientation
+	}
+	if goal.FrameId == "" {
+		goal.FrameId = target.FrameId
+	}
+	o := rc.ik.Options
+	if opts != nil {
+		o = *opts
+	}
+	var seedByName map[string]float64
+	if seed != nil {
+		seedByName = rc.joints.ByName(seed)
+	}
+	return rc.solveIK(geom.Transform{Translation: goal.Position, Rotation: *goal.Orientation}, goal.FrameId, seedByName, o)
+}
+
+// solveIK は frame で表した目標姿勢をチェーンの base に直してから解きます
+func (rc *RobotController) solveIK(pose geom.Transform, frame string, seed map[string]float64, opts kinematics.IKOptions) (IKSolution, error) {
+	chain, err := rc.Chain()
+	if err != nil {
+		return IKSolution{}, err
+	}
+	if frame != chain.Base {
+		t, err := rc.tfBuffer.Lookup(chain.Base, frame, time.Time{})
+		if err != nil {
+			return IKSolution{}, fmt.Errorf("%w: %s -> %s: %v", ErrTransformUnavailable, frame, chain.Base, err)
+		}
+		pose = t.Mul(pose)
+	}
+
+	var q0 []float64
+	if seed == nil {
+		if cmd, ok := rc.commandedJoints(); ok {
+			seed = cmd
+		} else if js, ok := rc.JointStates(); ok {
+			seed = js.Positions
+		}
+	}
+	if seed != nil {
+		if q, err := chain.Positions(seed); err == nil {
+			q0 = q
+		}
+	}
+
+	res, err := chain.IK(pose, q0, opts)
+	if err != nil {
+		return IKSolution{}, err
+	}
+	sol := IKSolution{
+		Reachable:        res.Converged,
+		Joints:           map[string]float64{},
+		Iterations:       res.Iterations,
+		PositionError:    res.PositionError,
+		OrientationError: res.OrientationError,
+		Target:           pose,
+		Base:             chain.Base,
+		Tip:              chain.Tip,
+	}
+	for i, name := range chain.Movable() {
+		sol.Joints[name] = res.Q[i]
+	}
+	angles := make([]float64, 0, len(rc.joints.Joints))
+	for _, j := range rc.joints.Joints {
+		v, ok := sol.Joints[j.Name]
+		if !ok {
+			angles = nil
+			break
+		}
+		angles = append(angles, v)
+	}
+	sol.Angles = angles
+	if !res.Converged {
+		return sol, &UnreachableError{Result: res}
+	}
+	return sol, nil
+}
+
+// sendTarget は目標姿勢を送ります。設定に応じて送る前に到達可能性を確かめ、関節角度に変換して送ります
+// target のロックを保持したまま呼ばれる
+func (rc *RobotController) sendTarget(ctx context.Context, next TargetSnapshot) error {
+	if !rc.ik.CheckReachability && !rc.ik.SendAsJoints {
+		return rc.publishTarget(next)
+	}
+	pose := geom.Transform{Translation: next.Position(), Rotation: next.Orientation}
+	sol, err := rc.solveIK(pose, next.FrameId, nil, rc.ik.Options)
+	if err != nil {
+		if errors.Is(err, ErrModelUnavailable) && !rc.ik.SendAsJoints {
+			// モデルが無ければチェックできないので、そのまま送る
+			return rc.publishTarget(next)
+		}
+		return err
+	}
+	if !rc.ik.SendAsJoints {
+		return rc.publishTarget(next)
+	}
+	if sol.Angles == nil {
+		return fmt.Errorf("ik solution does not cover all configured joints")
+	}
+	angles := make([]float32, len(sol.Angles))
+	for i, a := range sol.Angles {
+		angles[i] = float32(a)
+	}
+	rc.log(ctx, slog.LevelInfo, "Sending target as joint angles", "version", next.Version)
+	return rc.publishJointAngles(ctx, angles, false)
+}
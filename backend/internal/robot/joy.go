@@ -0,0 +1,121 @@
+// internal/robot/joy.go
+package robot
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"catchrobo_app/internal/config"
+	"catchrobo_app/internal/geom"
+	"catchrobo_app/internal/joy"
+
+	sensor_msgs_msg "msgs/sensor_msgs/msg"
+
+	"github.com/tiiuae/rclgo/pkg/rclgo"
+)
+
+// joyActionNames はゲームパッドのボタンに割り当てられるアクションです（/api/joy の一覧に出す順）
+var joyActionNames = []string{
+	"start", "reset", "catch", "release", "up", "down", "add_up", "add_down", "middle", "jog_stop", "next_cell", "prev_cell",
+}
+
+// joyActions はアクション名と実行する処理の対応を返します
+func (rc *RobotController) joyActions() map[string]func() error {
+	// ボタンを押すたびに joy-<ID> のリクエストIDを付ける
+	motion := func(publish func(context.Context) error) func() error {
+		return func() error { return publish(commandContext("joy")) }
+	}
+	return map[string]func() error{
+		"start":    motion(rc.PublishStartMotion),
+		"reset":    motion(rc.PublishResetMotion),
+		"catch":    motion(rc.PublishCatchMotion),
+		"release":  motion(rc.PublishReleaseMotion),
+		"up":       motion(rc.PublishUpMotion),
+		"down":     motion(rc.PublishDownMotion),
+		"add_up":   motion(rc.PublishAddUpMotion),
+		"add_down": motion(rc.PublishAddDownMotion),
+		"middle":   motion(rc.PublishMiddleMotion),
+		"jog_stop": func() error {
+			rc.jogger.Stop("", "joy jog_stop")
+			return nil
+		},
+		"next_cell": func() error { return rc.stepJoyCell(commandContext("joy"), 1) },
+		"prev_cell": func() error { return rc.stepJoyCell(commandContext("joy"), -1) },
+		// estop はマッパー側で入力を無視する状態にした上で、コントローラーにも非常停止を掛ける
+		// （resume はゲームパッドの入力を戻すだけで、指令の拒否は /api/admin/estop/clear で解除する）
+		joy.ActionEStop: func() error {
+			rc.EStop(commandContext("joy"), EStopSourceJoy, "joy estop button")
+			return nil
+		},
+	}
+}
+
+// stepJoyCell はセルを step だけ進めて（端で折り返す）、そのセルの上へ目標位置を送ります
+// サイドとセルの座標は設定の joy.side / joy.cells。送れなかったらセルは進めない
+func (rc *RobotController) stepJoyCell(ctx context.Context, step int) error {
+	n := rc.joyCells.Count()
+	if n == 0 {
+		return fmt.Errorf("joy.cells has no cells")
+	}
+	rc.joyCellMu.Lock()
+	defer rc.joyCellMu.Unlock()
+	cell := rc.joyCell + step
+	switch {
+	case cell < 1:
+		cell = n
+	case cell > n:
+		cell = 1
+	}
+	pos, err := rc.joyCells.Position(rc.joySide, cell)
+	if err != nil {
+		return err
+	}
+	if _, err := rc.PublishPosition(ctx, PoseGoal{Position: pos, FrameId: CommandFrameId}, nil); err != nil {
+		return fmt.Errorf("cell %d: %w", cell, err)
+	}
+	rc.joyCell = cell
+	rc.log(ctx, slog.LevelInfo, "Joy moved to cell", "side", rc.joySide, "cell", cell)
+	return nil
+}
+
+// setupJoy はゲームパッドの割り当てを読み込み sensor_msgs/Joy を購読します。spin 開始前に呼ぶ
+// ゲームパッドはロボットの PC につながった物理的な入力なので、HTTP の操作権とは別の特権的な入力として扱う
+// （操作権は確認しない。非常停止・試合の状態・送信制限は Publish 側でかかる）
+func (rc *RobotController) setupJoy(cfg *config.Config) {
+	rc.joySide, rc.joyCells = cfg.Joy.Side, cfg.Joy.Cells
+	rc.joy = joy.New(cfg.Joy.MappingFile, joyActionNames, rc.joyActions(), rc.moveByJoy, func(err error) {
+		rc.warn("joy mapping", err)
+	})
+	if cfg.Joy.Topic == "" {
+		return
+	}
+	sub, err := rc.node.NewSubscription(cfg.Joy.Topic, sensor_msgs_msg.JoyTypeSupport, nil, func(sub *rclgo.Subscription) {
+		var msg sensor_msgs_msg.Joy
+		if _, err := sub.TakeMessage(&msg); err != nil {
+			rc.warn("failed to take joy", err)
+			return
+		}
+		rc.joy.Update(msg.Axes, msg.Buttons)
+	})
+	if err == nil {
+		rc.joySub = sub
+	} else {
+		rc.warn("failed to subscribe joy", err)
+	}
+}
+
+// moveByJoy は軸から作った相対移動を /api/move と同じ経路で送ります
+func (rc *RobotController) moveByJoy(d joy.Delta) error {
+	delta := PoseDelta{Translation: d.Translation, FrameId: d.FrameId}
+	if d.RPYDeg != (geom.Vec3{}) {
+		delta.Rotation = geom.FromRPY(geom.Deg2Rad(d.RPYDeg.X), geom.Deg2Rad(d.RPYDeg.Y), geom.Deg2Rad(d.RPYDeg.Z))
+	}
+	_, err := rc.PublishDisplacement(commandContext("joy"), delta, nil)
+	return err
+}
+
+// Joy はゲームパッド入力の状態を返します
+func (rc *RobotController) Joy() joy.Status {
+	return rc.joy.Status()
+}
@@ -0,0 +1,95 @@
+// internal/robot/lifecycle.go
+package robot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"catchrobo_app/internal/geom"
+	"catchrobo_app/internal/ratelimit"
+)
+
+// ErrShuttingDown は停止処理に入った後に指令を送ろうとした場合に返ります
+var ErrShuttingDown = errors.New("shutting down")
+
+// spinStopTimeout は Close で spin の終了を待つ上限です
+const spinStopTimeout = 2 * time.Second
+
+// BeginShutdown は停止処理に入ります。以降の指令は ErrShuttingDown で拒否し、速度ジョグも止めます
+// MJPEG や WebSocket は ShuttingDown() が閉じたら接続を終えること
+func (rc *RobotController) BeginShutdown() {
+	rc.shutdownOnce.Do(func() {
+		close(rc.shutdownCh)
+		if rc.jogger != nil {
+			rc.jogger.Stop("", "shutdown")
+		}
+		rc.log(context.Background(), slog.LevelInfo, "Shutting down: rejecting new commands")
+	})
+}
+
+// ShuttingDown は停止処理に入ると閉じるチャネルを返します
+func (rc *RobotController) ShuttingDown() <-chan struct{} {
+	return rc.shutdownCh
+}
+
+// admit は指令を送ってよいか確認します（停止処理中と試合の状態が許さないときは拒否し、トピックごとの送信制限をかける）
+func (rc *RobotController) admit(topic string) error {
+	select {
+	case <-rc.shutdownCh:
+		metricPublishRejected.Inc(topic, "shutting_down")
+		return ErrShuttingDown
+	default:
+	}
+	if rc.EStopStatus().Engaged {
+		metricPublishRejected.Inc(topic, "estop")
+		return ErrEStopped
+	}
+	if err := rc.match.Allow(); err != nil {
+		metricPublishRejected.Inc(topic, "match")
+		return err
+	}
+	err := rc.topicLimits.Allow(topic)
+	var limited *ratelimit.LimitedError
+	if errors.As(err, &limited) {
+		metricPublishRejected.Inc(topic, limited.Reason)
+	}
+	return err
+}
+
+// Park は設定された駐機姿勢を送り、届くまで settle_ms 待ちます。設定が無ければ何もしません
+// 停止処理中でも送れるように admit を通さない（IK による到達可能性チェックはかける）
+func (rc *RobotController) Park() error {
+	if rc.park == nil {
+		return nil
+	}
+	// 非常停止が掛かっていれば腕を動かさない
+	if st := rc.EStopStatus(); st.Engaged {
+		rc.log(context.Background(), slog.LevelWarn, "Skipping park pose: emergency stop is engaged", "source", st.Source)
+		return nil
+	}
+	goal := PoseGoal{Position: rc.park.Position, FrameId: rc.park.FrameId}
+	if goal.FrameId == "" {
+		goal.FrameId = CommandFrameId
+	}
+	if r := rc.park.RPYDeg; r != nil {
+		q := geom.FromRPY(geom.Deg2Rad(r.X), geom.Deg2Rad(r.Y), geom.Deg2Rad(r.Z))
+		goal.Orientation = &q
+	}
+	goal, err := rc.goalToCommandFrame(goal)
+	if err != nil {
+		return fmt.Errorf("park: %w", err)
+	}
+	ctx := commandContext("park")
+	next, err := rc.target.Set(goal, nil, func(next TargetSnapshot) error {
+		return rc.sendTarget(ctx, next)
+	})
+	if err != nil {
+		return fmt.Errorf("park: %w", err)
+	}
+	rc.log(ctx, slog.LevelInfo, "Sent park pose", "x", next.X, "y", next.Y, "z", next.Z, "version", next.Version)
+	time.Sleep(time.Duration(rc.park.SettleMs) * time.Millisecond)
+	return nil
+}
@@ -0,0 +1,120 @@
+// internal/robot/lifecycle_nodes.go
+package robot
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+
+	"catchrobo_app/internal/lifecycle"
+
+	lifecycle_msgs_msg "msgs/lifecycle_msgs/msg"
+	lifecycle_msgs_srv "msgs/lifecycle_msgs/srv"
+)
+
+// getStateServiceType は管理対象のノードを見分けるのに使うサービスの型です
+const getStateServiceType = "lifecycle_msgs/srv/GetState"
+
+// LifecycleNodes は <node>/get_state を提供しているノードと、その状態・行える遷移を返します
+// 状態を取得できなかったノードは Error に理由を入れて返す
+func (rc *RobotController) LifecycleNodes(ctx context.Context) ([]lifecycle.Node, error) {
+	names, err := rc.nodesWithService("get_state", getStateServiceType)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]lifecycle.Node, len(names))
+	var wg sync.WaitGroup
+	for i, name := range names {
+		wg.Add(1)
+		go func(i int, name string) {
+			defer wg.Done()
+			n := lifecycle.Node{Name: name}
+			state, err := rc.LifecycleState(ctx, name)
+			if err == nil {
+				n.State = &state
+				n.Transitions, err = rc.LifecycleTransitions(ctx, name)
+			}
+			if err != nil {
+				n.Error = err.Error()
+			}
+			out[i] = n
+		}(i, name)
+	}
+	wg.Wait()
+	return out, nil
+}
+
+// LifecycleState はノードの今の状態を返します
+func (rc *RobotController) LifecycleState(ctx context.Context, node string) (lifecycle.State, error) {
+	service, err := rc.nodeService(node, "get_state")
+	if err != nil {
+		return lifecycle.State{}, err
+	}
+	resp, err := rc.callService(ctx, service, lifecycle_msgs_srv.GetStateTypeSupport, &lifecycle_msgs_srv.GetState_Request{})
+	if err != nil {
+		return lifecycle.State{}, err
+	}
+	return fromLifecycleState(resp.(*lifecycle_msgs_srv.GetState_Response).CurrentState), nil
+}
+
+// LifecycleTransitions はノードが今の状態から行える遷移を返します
+func (rc *RobotController) LifecycleTransitions(ctx context.Context, node string) ([]lifecycle.Transition, error) {
+	service, err := rc.nodeService(node, "get_available_transitions")
+	if err != nil {
+		return nil, err
+	}
+	resp, err := rc.callService(ctx, service, lifecycle_msgs_srv.GetAvailableTransitionsTypeSupport, &lifecycle_msgs_srv.GetAvailableTransitions_Request{})
+	if err != nil {
+		return nil, err
+	}
+	descs := resp.(*lifecycle_msgs_srv.GetAvailableTransitions_Response).AvailableTransitions
+	out := make([]lifecycle.Transition, len(descs))
+	for i, d := range descs {
+		out[i] = lifecycle.Transition{ID: d.Transition.Id, Label: d.Transition.Label, Goal: fromLifecycleState(d.GoalState)}
+	}
+	return out, nil
+}
+
+// ChangeLifecycleState はノードに遷移（"activate" などの名前か ID）を行わせ、遷移後の状態を返します
+// 今の状態から行えない遷移なら送らずに lifecycle.ErrTransitionUnavailable を返す
+// ノードが遷移に失敗した場合は Success が false の結果を返す（エラーにはしない）
+func (rc *RobotController) ChangeLifecycleState(ctx context.Context, node, transition string) (*lifecycle.Result, error) {
+	node = NormalizeNodeName(node)
+	from, err := rc.LifecycleState(ctx, node)
+	if err != nil {
+		return nil, err
+	}
+	available, err := rc.LifecycleTransitions(ctx, node)
+	if err != nil {
+		return nil, err
+	}
+	t, err := lifecycle.Resolve(available, transition)
+	if err != nil {
+		return nil, err
+	}
+	service, err := rc.nodeService(node, "change_state")
+	if err != nil {
+		return nil, err
+	}
+	req := &lifecycle_msgs_srv.ChangeState_Request{Transition: lifecycle_msgs_msg.Transition{Id: t.ID, Label: t.Label}}
+	resp, err := rc.callService(ctx, service, lifecycle_msgs_srv.ChangeStateTypeSupport, req)
+	if err != nil {
+		return nil, err
+	}
+	res := &lifecycle.Result{Node: node, Transition: t, Success: resp.(*lifecycle_msgs_srv.ChangeState_Response).Success, From: from}
+	if to, err := rc.LifecycleState(ctx, node); err == nil {
+		res.To = &to
+	} else {
+		rc.log(ctx, slog.LevelWarn, "failed to read lifecycle state after transition", "node", node, "error", err)
+	}
+	level := slog.LevelInfo
+	if !res.Success {
+		level = slog.LevelWarn
+	}
+	rc.log(ctx, level, "lifecycle transition", "node", node, "transition", t.Label, "from", from.Label, "success", res.Success)
+	return res, nil
+}
+
+func fromLifecycleState(s lifecycle_msgs_msg.State) lifecycle.State {
+	return lifecycle.State{ID: s.Id, Label: s.Label}
+}
@@ -0,0 +1,66 @@
+// internal/robot/log.go
+package robot
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"catchrobo_app/internal/logging"
+
+	"github.com/tiiuae/rclgo/pkg/rclgo"
+)
+
+var logger = logging.For(logging.ComponentRobot)
+
+// log は JSON のログと ROS のロガー（/rosout）の両方に出力します
+// ROS 側は "msg key=value ... request_id=..." の1行にして、HTTP のアクセスログと突き合わせられるようにする
+func (rc *RobotController) log(ctx context.Context, level slog.Level, msg string, args ...any) {
+	if !logger.Enabled(ctx, level) {
+		return
+	}
+	logger.Log(ctx, level, msg, args...)
+	if rc == nil || rc.node == nil {
+		return
+	}
+	var b strings.Builder
+	b.WriteString(msg)
+	for i := 0; i+1 < len(args); i += 2 {
+		fmt.Fprintf(&b, " %v=%v", args[i], args[i+1])
+	}
+	if id := logging.RequestID(ctx); id != "" {
+		b.WriteString(" request_id=")
+		b.WriteString(id)
+	}
+	_ = rc.node.Logger().Log(rosSeverity(level), b.String())
+}
+
+// warn はリクエストに紐付かない警告（購読の失敗など）を出力します
+func (rc *RobotController) warn(msg string, err error) {
+	rc.log(context.Background(), slog.LevelWarn, msg, "error", err)
+}
+
+// followLogLevel は robot のログレベルを変えたときに ROS のロガーのレベルも合わせます
+func (rc *RobotController) followLogLevel() {
+	logging.OnLevelChange(logging.ComponentRobot, func(l slog.Level) {
+		_ = rc.node.Logger().SetLevel(rosSeverity(l))
+	})
+}
+
+func rosSeverity(l slog.Level) rclgo.LogSeverity {
+	switch {
+	case l < slog.LevelInfo:
+		return rclgo.LogSeverityDebug
+	case l < slog.LevelWarn:
+		return rclgo.LogSeverityInfo
+	case l < slog.LevelError:
+		return rclgo.LogSeverityWarn
+	}
+	return rclgo.LogSeverityError
+}
+
+// commandContext はリクエストに紐付かない指令（ゲームパッド・停止処理）用に source を付けたリクエストIDを作ります
+func commandContext(source string) context.Context {
+	return logging.WithRequestID(context.Background(), source+"-"+logging.NewRequestID())
+}
@@ -0,0 +1,12 @@
+// internal/robot/match.go
+package robot
+
+import (
+	"catchrobo_app/internal/match"
+)
+
+// Match は試合の状態と残り時間を返します
+// setting / running 以外（allow_idle なら idle も除く）と時間切れ後はモーション指令を拒否する
+func (rc *RobotController) Match() *match.Match {
+	return rc.match
+}
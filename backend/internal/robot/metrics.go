@@ -0,0 +1,93 @@
+// internal/robot/metrics.go
+package robot
+
+import (
+	"math"
+	"time"
+
+	"catchrobo_app/internal/metrics"
+
+	"github.com/tiiuae/rclgo/pkg/rclgo"
+	"github.com/tiiuae/rclgo/pkg/rclgo/types"
+)
+
+var (
+	metricPublishTotal    = metrics.NewCounterVec("catchrobo_publish_total", "Messages published per command topic.", "topic")
+	metricPublishErrors   = metrics.NewCounterVec("catchrobo_publish_errors_total", "Publish calls that returned an error per command topic.", "topic")
+	metricPublishRejected = metrics.NewCounterVec("catchrobo_publish_rejected_total", "Commands rejected before publishing (rate limit, debounce, shutdown).", "topic", "reason")
+
+	metricCameraFrames       = metrics.NewCounterVec("catchrobo_camera_frames_total", "Camera frames received per camera topic.", "camera")
+	metricCameraEncodeErrors = metrics.NewCounterVec("catchrobo_camera_encode_errors_total", "Raw frames that could not be encoded to JPEG (dropped).", "camera")
+	metricCameraEncode       = metrics.NewHistogramVec("catchrobo_camera_encode_seconds", "Time to encode a raw frame to JPEG.",
+		[]float64{0.001, 0.0025, 0.005, 0.01, 0.02, 0.033, 0.05, 0.1, 0.25}, "camera")
+	metricCameraJPEGBytes = metrics.NewHistogramVec("catchrobo_camera_jpeg_bytes", "JPEG size per frame.",
+		[]float64{10e3, 25e3, 50e3, 100e3, 200e3, 400e3, 800e3, 1.6e6}, "camera")
+	metricCameraFPS = metrics.NewGaugeFunc("catchrobo_camera_fps", "Effective frame rate per camera (0 when frames stopped).", []string{"camera"}, nil)
+)
+
+// fpsSmoothing はフレーム間隔の指数移動平均の重みです
+const fpsSmoothing = 0.1
+
+// cameraStat はカメラごとの最終受信時刻とフレーム間隔の移動平均です
+type cameraStat struct {
+	last     time.Time
+	interval float64 // [s]
+}
+
+// publish は Publish してトピックごとの回数とエラーを数えます
+func (rc *RobotController) publish(pub *rclgo.Publisher, msg types.Message) error {
+	if err := pub.Publish(msg); err != nil {
+		metricPublishErrors.Inc(pub.TopicName)
+		return err
+	}
+	metricPublishTotal.Inc(pub.TopicName)
+	return nil
+}
+
+// markCameraFrame はカメラのトピックごとの最終受信時刻とフレーム間隔を記録します
+func (rc *RobotController) markCameraFrame(topic string) {
+	metricCameraFrames.Inc(topic)
+	now := time.Now()
+	rc.latestJPEGMu.Lock()
+	defer rc.latestJPEGMu.Unlock()
+	st := rc.cameras[topic]
+	if st == nil {
+		st = &cameraStat{}
+		rc.cameras[topic] = st
+	}
+	if !st.last.IsZero() {
+		dt := now.Sub(st.last).Seconds()
+		if st.interval == 0 {
+			st.interval = dt
+		} else {
+			st.interval += fpsSmoothing * (dt - st.interval)
+		}
+	}
+	st.last = now
+}
+
+// observeEncode は raw 画像の JPEG 変換の時間とサイズを記録します（失敗したフレームは捨てられる）
+func observeEncode(topic string, d time.Duration, size int, err error) {
+	if err != nil {
+		metricCameraEncodeErrors.Inc(topic)
+		return
+	}
+	metricCameraEncode.Observe(d.Seconds(), topic)
+	metricCameraJPEGBytes.Observe(float64(size), topic)
+}
+
+// cameraFPS はカメラごとの実効フレームレートです。間隔の3倍（最低1秒）届かなければ0とみなす
+func (rc *RobotController) cameraFPS() []metrics.Sample {
+	rc.latestJPEGMu.RLock()
+	defer rc.latestJPEGMu.RUnlock()
+	now := time.Now()
+	out := make([]metrics.Sample, 0, len(rc.cameras))
+	for topic, st := range rc.cameras {
+		fps := 0.0
+		if st.interval > 0 && now.Sub(st.last).Seconds() < math.Max(1, 3*st.interval) {
+			fps = 1 / st.interval
+		}
+		out = append(out, metrics.Sample{Labels: []string{topic}, Value: fps})
+	}
+	return out
+}
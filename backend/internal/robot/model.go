@@ -0,0 +1,339 @@
+// internal/robot/model.go
+package robot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+
+	"catchrobo_app/internal/config"
+	"catchrobo_app/internal/geom"
+	"catchrobo_app/internal/kinematics"
+	"catchrobo_app/internal/urdf"
+
+	rcl_interfaces_msg "msgs/rcl_interfaces/msg"
+	rcl_interfaces_srv "msgs/rcl_interfaces/srv"
+	sensor_msgs_msg "msgs/sensor_msgs/msg"
+	std_msgs "msgs/std_msgs/msg"
+
+	"github.com/tiiuae/rclgo/pkg/rclgo"
+)
+
+var (
+	// ErrModelUnavailable は URDF をまだ読み込めていない場合に返ります
+	ErrModelUnavailable = errors.New("robot model not loaded")
+	// ErrNoJointData は順運動学に使う関節角度がまだ無い場合に返ります
+	ErrNoJointData = errors.New("no joint data")
+)
+
+// 順運動学に使う関節角度の出どころ
+const (
+	JointSourceMeasured  = "measured"
+	JointSourceCommanded = "commanded"
+)
+
+// ModelInfo は読み込んだ URDF の概要です
+type ModelInfo struct {
+	Name     string            `json:"name"`
+	Source   string            `json:"source"`
+	LoadedAt time.Time         `json:"loaded_at"`
+	Tree     *urdf.TreeNode    `json:"tree"`
+	Chain    *kinematics.Chain `json:"chain"`
+}
+
+// ToolPose は順運動学で求めた手先姿勢です
+type ToolPose struct {
+	Base   string                    `json:"base"`
+	Tip    string                    `json:"tip"`
+	Source string                    `json:"source"`
+	Pose   geom.Transform            `json:"pose"`
+	RPYDeg geom.Vec3                 `json:"rpy_deg"`
+	Joints map[string]float64        `json:"joints"`
+	Links  map[string]geom.Transform `json:"links"`
+}
+
+// JointStates は最後に受信した計測関節角度です
+type JointStates struct {
+	Positions  map[string]float64 `json:"positions"`
+	ReceivedAt time.Time          `json:"received_at"`
+}
+
+// armModel は URDF と順運動学用のチェーンを保持します（topic などから差し替わることがある）
+type armModel struct {
+	mu       sync.RWMutex
+	robot    *urdf.Robot
+	chain    *kinematics.Chain
+	source   string
+	loadedAt time.Time
+
+	chainFromConfig bool // 設定のチェーンを使う場合は URDF で上書きしない
+}
+
+// setupModel は URDF の読み込み元と /joint_states の購読を準備します。spin 開始前に呼ぶこと
+func (rc *RobotController) setupModel(cfg *config.Config) {
+	rc.urdfCfg = cfg.URDF
+
+	if len(cfg.Chain.Joints) > 0 {
+		base := cfg.Chain.Base
+		if base == "" {
+			base = CommandFrameId
+		}
+		chain, err := kinematics.FromSpecs(base, cfg.Chain.Tip, cfg.Chain.Joints)
+		if err != nil {
+			rc.warn("invalid chain in config", err)
+		} else {
+			rc.model.chain, rc.model.chainFromConfig = chain, true
+			rc.model.source, rc.model.loadedAt = "config", time.Now()
+		}
+	}
+
+	if cfg.URDF.File != "" {
+		if b, err := os.ReadFile(cfg.URDF.File); err != nil {
+			rc.warn("failed to read urdf file", err)
+		} else if err := rc.loadURDF(b, "file:"+cfg.URDF.File); err != nil {
+			rc.warn("failed to load urdf file", err)
+		}
+	}
+
+	if cfg.URDF.Topic != "" {
+		// robot_state_publisher は transient local で1回だけ publish する
+		qos := rclgo.NewDefaultQosProfile()
+		qos.Durability = rclgo.DurabilityTransientLocal
+		qos.Depth = 1
+		opts := rclgo.NewDefaultSubscriptionOptions()
+		opts.Qos = qos
+		sub, err := rc.node.NewSubscription(cfg.URDF.Topic, std_msgs.StringTypeSupport, opts, func(sub *rclgo.Subscription) {
+			var msg std_msgs.String
+			if _, err := sub.TakeMessage(&msg); err != nil {
+				rc.warn("failed to take robot description", err)
+				return
+			}
+			if cfg.URDF.File != "" {
+				return // ファイル指定を優先する
+			}
+			if err := rc.loadURDF([]byte(msg.Data), "topic:"+cfg.URDF.Topic); err != nil {
+				rc.warn("failed to load robot description", err)
+			}
+		})
+		if err == nil {
+			rc.urdfSub = sub
+		} else {
+			rc.warn("failed to subscribe robot description", err)
+		}
+	}
+
+	if cfg.URDF.ParamNode != "" && cfg.URDF.File == "" {
+		client, err := rcl_interfaces_srv.NewGetParametersClient(rc.node, cfg.URDF.ParamNode+"/get_parameters", nil)
+		if err == nil {
+			rc.urdfParamClient = client
+		} else {
+			rc.warn("failed to create get_parameters client", err)
+		}
+	}
+
+	if cfg.JointStatesTopic != "" {
+		sub, err := rc.node.NewSubscription(cfg.JointStatesTopic, sensor_msgs_msg.JointStateTypeSupport, nil, func(sub *rclgo.Subscription) {
+			var msg sensor_msgs_msg.JointState
+			if _, err := sub.TakeMessage(&msg); err != nil {
+				rc.warn("failed to take joint states", err)
+				return
+			}
+			rc.setJointStates(&msg)
+		})
+		if err == nil {
+			rc.jointStatesSub = sub
+		} else {
+			rc.warn("failed to subscribe joint states", err)
+		}
+	}
+}
+
+// fetchURDFParam は robot_description パラメータを取得します。spin 開始後に goroutine で呼ぶ
+// topic から先に届いた場合や ctx が終わった場合はあきらめる
+func (rc *RobotController) fetchURDFParam(ctx context.Context) {
+	if rc.urdfParamClient == nil {
+		return
+	}
+	for attempt := 0; attempt < 12; attempt++ {
+		rc.model.mu.RLock()
+		loaded := rc.model.robot != nil
+		rc.model.mu.RUnlock()
+		if loaded {
+			return
+		}
+		reqCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+		resp, _, err := rc.urdfParamClient.Send(reqCtx, &rcl_interfaces_srv.GetParameters_Request{Names: []string{"robot_description"}})
+		cancel()
+		if ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			continue
+		}
+		if len(resp.Values) == 1 && resp.Values[0].Type == rcl_interfaces_msg.ParameterType_PARAMETER_STRING {
+			if err := rc.loadURDF([]byte(resp.Values[0].StringValue), "param:"+rc.urdfCfg.ParamNode); err != nil {
+				rc.warn("failed to load robot_description parameter", err)
+			}
+			return
+		}
+		rc.log(context.Background(), slog.LevelWarn, "robot_description parameter is not set", "node", rc.urdfCfg.ParamNode)
+		return
+	}
+}
+
+// loadURDF は URDF を解析してチェーンを作り、成功したら差し替えます
+func (rc *RobotController) loadURDF(data []byte, source string) error {
+	r, err := urdf.Parse(data)
+	if err != nil {
+		return err
+	}
+	chain, err := kinematics.FromURDF(r, rc.urdfCfg.BaseLink, rc.urdfCfg.TipLink)
+	if err != nil {
+		return err
+	}
+	rc.model.mu.Lock()
+	rc.model.robot = r
+	if !rc.model.chainFromConfig {
+		rc.model.chain = chain
+		rc.model.source, rc.model.loadedAt = source, time.Now()
+	}
+	rc.model.mu.Unlock()
+	rc.log(context.Background(), slog.LevelInfo, "Loaded URDF",
+		"name", r.Name, "source", source, "base", chain.Base, "tip", chain.Tip, "dof", chain.DOF())
+	return nil
+}
+
+// RobotModel は読み込んだ URDF の概要を返します
+func (rc *RobotController) RobotModel() (ModelInfo, error) {
+	rc.model.mu.RLock()
+	defer rc.model.mu.RUnlock()
+	if rc.model.chain == nil {
+		return ModelInfo{}, ErrModelUnavailable
+	}
+	info := ModelInfo{
+		Source:   rc.model.source,
+		LoadedAt: rc.model.loadedAt,
+		Chain:    rc.model.chain,
+	}
+	if rc.model.robot != nil {
+		info.Name = rc.model.robot.Name
+		info.Tree = rc.model.robot.Tree()
+	}
+	return info, nil
+}
+
+// Chain は順運動学用のチェーンを返します
+func (rc *RobotController) Chain() (*kinematics.Chain, error) {
+	rc.model.mu.RLock()
+	defer rc.model.mu.RUnlock()
+	if rc.model.chain == nil {
+		return nil, ErrModelUnavailable
+	}
+	return rc.model.chain, nil
+}
+
+func (rc *RobotController) setJointStates(msg *sensor_msgs_msg.JointState) {
+	rc.jointStatesMu.Lock()
+	defer rc.jointStatesMu.Unlock()
+	if rc.jointStates.Positions == nil {
+		rc.jointStates.Positions = map[string]float64{}
+	}
+	// 関節ごとに別メッセージで来ることもあるので上書きでマージする
+	for i, name := range msg.Name {
+		if i < len(msg.Position) {
+			rc.jointStates.Positions[name] = msg.Position[i]
+		}
+	}
+	rc.jointStates.ReceivedAt = time.Now()
+}
+
+// JointStates は最後に受信した計測関節角度を返します（未受信なら ok=false）
+func (rc *RobotController) JointStates() (JointStates, bool) {
+	rc.jointStatesMu.RLock()
+	defer rc.jointStatesMu.RUnlock()
+	if rc.jointStates.ReceivedAt.IsZero() {
+		return JointStates{}, false
+	}
+	pos := make(map[string]float64, len(rc.jointStates.Positions))
+	for k, v := range rc.jointStates.Positions {
+		pos[k] = v
+	}
+	return JointStates{Positions: pos, ReceivedAt: rc.jointStates.ReceivedAt}, true
+}
+
+// commandedJoints は最後に送った関節角度を関節名 → 角度で返します
+func (rc *RobotController) commandedJoints() (map[string]float64, bool) {
+	last := rc.LastJointCommand()
+	if len(last) == 0 {
+		return nil, false
+	}
+	return rc.joints.ByName(last), true
+}
+
+// ForwardKinematics は計測（measured）または指令（commanded）の関節角度から手先姿勢を求めます
+func (rc *RobotController) ForwardKinematics(source string) (ToolPose, error) {
+	var joints map[string]float64
+	switch source {
+	case JointSourceMeasured:
+		js, ok := rc.JointStates()
+		if !ok {
+			return ToolPose{}, fmt.Errorf("%w: no joint states received", ErrNoJointData)
+		}
+		joints = js.Positions
+	case JointSourceCommanded:
+		cmd, ok := rc.commandedJoints()
+		if !ok {
+			return ToolPose{}, fmt.Errorf("%w: no joint command sent yet", ErrNoJointData)
+		}
+		joints = cmd
+	default:
+		return ToolPose{}, fmt.Errorf("unknown joint source %q", source)
+	}
+	return rc.forwardKinematics(joints, source)
+}
+
+// ToolPoseFor は任意の関節角度（関節モデルの並び順）から手先姿勢を求めます
+func (rc *RobotController) ToolPoseFor(angles []float64) (ToolPose, error) {
+	return rc.forwardKinematics(rc.joints.ByName(angles), JointSourceCommanded)
+}
+
+func (rc *RobotController) forwardKinematics(joints map[string]float64, source string) (ToolPose, error) {
+	chain, err := rc.Chain()
+	if err != nil {
+		return ToolPose{}, err
+	}
+	q, err := chain.Positions(joints)
+	if err != nil {
+		return ToolPose{}, fmt.Errorf("%w: %v", ErrNoJointData, err)
+	}
+	frames, err := chain.Frames(q)
+	if err != nil {
+		return ToolPose{}, err
+	}
+	pose := geom.IdentityTransform()
+	if len(frames) > 0 {
+		pose = frames[len(frames)-1]
+	}
+	links := make(map[string]geom.Transform, len(frames))
+	for i, j := range chain.Joints {
+		links[j.Child] = frames[i]
+	}
+	used := make(map[string]float64, len(q))
+	for i, name := range chain.Movable() {
+		used[name] = q[i]
+	}
+	roll, pitch, yaw := pose.Rotation.RPY()
+	return ToolPose{
+		Base:   chain.Base,
+		Tip:    chain.Tip,
+		Source: source,
+		Pose:   pose,
+		RPYDeg: geom.Vec3{X: geom.Rad2Deg(roll), Y: geom.Rad2Deg(pitch), Z: geom.Rad2Deg(yaw)},
+		Joints: used,
+		Links:  links,
+	}, nil
+}
@@ -0,0 +1,266 @@
+// internal/robot/params.go
+package robot
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sort"
+
+	"catchrobo_app/internal/params"
+
+	rcl_interfaces_msg "msgs/rcl_interfaces/msg"
+	rcl_interfaces_srv "msgs/rcl_interfaces/srv"
+)
+
+// ParamsUpdate は SetParameters の結果です
+type ParamsUpdate struct {
+	Atomic  bool               `json:"atomic"`
+	Results []params.SetResult `json:"results"`
+	Changes []params.Change    `json:"changes"` // 設定に成功したものだけ（new は読み直した値）
+}
+
+// ListParameters は node のパラメータ名を prefix 以下で全て返します（prefix が空なら全て）
+func (rc *RobotController) ListParameters(ctx context.Context, node, prefix string) ([]string, error) {
+	service, err := rc.nodeService(node, "list_parameters")
+	if err != nil {
+		return nil, err
+	}
+	req := &rcl_interfaces_srv.ListParameters_Request{Depth: rcl_interfaces_srv.ListParameters_Request_DEPTH_RECURSIVE}
+	if prefix != "" {
+		req.Prefixes = []string{prefix}
+	}
+	resp, err := rc.callService(ctx, service, rcl_interfaces_srv.ListParametersTypeSupport, req)
+	if err != nil {
+		return nil, err
+	}
+	names := append([]string(nil), resp.(*rcl_interfaces_srv.ListParameters_Response).Result.Names...)
+	sort.Strings(names)
+	return names, nil
+}
+
+// GetParameters は node のパラメータの値を names の順に返します（無いものは not_set）
+func (rc *RobotController) GetParameters(ctx context.Context, node string, names []string) ([]params.Value, error) {
+	service, err := rc.nodeService(node, "get_parameters")
+	if err != nil {
+		return nil, err
+	}
+	resp, err := rc.callService(ctx, service, rcl_interfaces_srv.GetParametersTypeSupport, &rcl_interfaces_srv.GetParameters_Request{Names: names})
+	if err != nil {
+		return nil, err
+	}
+	values := resp.(*rcl_interfaces_srv.GetParameters_Response).Values
+	if len(values) != len(names) {
+		return nil, fmt.Errorf("get_parameters returned %d values for %d names", len(values), len(names))
+	}
+	out := make([]params.Value, len(values))
+	for i := range values {
+		out[i] = fromParameterValue(&values[i])
+	}
+	return out, nil
+}
+
+// DescribeParameters は node のパラメータの説明を names の順に返します
+func (rc *RobotController) DescribeParameters(ctx context.Context, node string, names []string) ([]params.Descriptor, error) {
+	service, err := rc.nodeService(node, "describe_parameters")
+	if err != nil {
+		return nil, err
+	}
+	resp, err := rc.callService(ctx, service, rcl_interfaces_srv.DescribeParametersTypeSupport, &rcl_interfaces_srv.DescribeParameters_Request{Names: names})
+	if err != nil {
+		return nil, err
+	}
+	descs := resp.(*rcl_interfaces_srv.DescribeParameters_Response).Descriptors
+	if len(descs) != len(names) {
+		return nil, fmt.Errorf("describe_parameters returned %d descriptors for %d names", len(descs), len(names))
+	}
+	out := make([]params.Descriptor, len(descs))
+	for i := range descs {
+		out[i] = fromParameterDescriptor(&descs[i])
+	}
+	return out, nil
+}
+
+// Parameters は names（空なら prefix 以下の全て）の値と説明をまとめて返します
+func (rc *RobotController) Parameters(ctx context.Context, node string, names []string, prefix string) ([]params.Parameter, error) {
+	if len(names) == 0 {
+		var err error
+		if names, err = rc.ListParameters(ctx, node, prefix); err != nil {
+			return nil, err
+		}
+	}
+	out := make([]params.Parameter, len(names))
+	if len(names) == 0 {
+		return out, nil
+	}
+	values, err := rc.GetParameters(ctx, node, names)
+	if err != nil {
+		return nil, err
+	}
+	descs, err := rc.DescribeParameters(ctx, node, names)
+	if err != nil {
+		return nil, err
+	}
+	for i, name := range names {
+		out[i] = params.Parameter{Name: name, Value: values[i], Descriptor: &descs[i]}
+	}
+	return out, nil
+}
+
+// SetParameters は updates を node に設定します
+// 型を省略したものは現在の型に合わせる。atomic なら set_parameters_atomically で全て成功か全て失敗にする
+// 値が型に合わなければ送る前に params.ErrInvalidValue を返す
+func (rc *RobotController) SetParameters(ctx context.Context, node string, updates []params.Update, atomic bool) (*ParamsUpdate, error) {
+	names := make([]string, len(updates))
+	for i, u := range updates {
+		names[i] = u.Name
+	}
+	old, err := rc.GetParameters(ctx, node, names)
+	if err != nil {
+		return nil, err
+	}
+	ps := make([]rcl_interfaces_msg.Parameter, len(updates))
+	for i, u := range updates {
+		typ := u.Type
+		if typ == "" {
+			if typ = old[i].Type; typ == params.TypeNotSet {
+				return nil, fmt.Errorf("%w: %s is not set, type is required", params.ErrInvalidValue, u.Name)
+			}
+		}
+		v, err := params.Parse(typ, u.Value)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", u.Name, err)
+		}
+		ps[i] = rcl_interfaces_msg.Parameter{Name: u.Name, Value: toParameterValue(v)}
+	}
+
+	res := &ParamsUpdate{Atomic: atomic}
+	if atomic {
+		service, err := rc.nodeService(node, "set_parameters_atomically")
+		if err != nil {
+			return nil, err
+		}
+		resp, err := rc.callService(ctx, service, rcl_interfaces_srv.SetParametersAtomicallyTypeSupport, &rcl_interfaces_srv.SetParametersAtomically_Request{Parameters: ps})
+		if err != nil {
+			return nil, err
+		}
+		r := resp.(*rcl_interfaces_srv.SetParametersAtomically_Response).Result
+		res.Results = []params.SetResult{{Successful: r.Successful, Reason: r.Reason}}
+	} else {
+		service, err := rc.nodeService(node, "set_parameters")
+		if err != nil {
+			return nil, err
+		}
+		resp, err := rc.callService(ctx, service, rcl_interfaces_srv.SetParametersTypeSupport, &rcl_interfaces_srv.SetParameters_Request{Parameters: ps})
+		if err != nil {
+			return nil, err
+		}
+		for i, r := range resp.(*rcl_interfaces_srv.SetParameters_Response).Results {
+			name := ""
+			if i < len(names) {
+				name = names[i]
+			}
+			res.Results = append(res.Results, params.SetResult{Name: name, Successful: r.Successful, Reason: r.Reason})
+		}
+	}
+
+	// 設定後の値を読み直す（ノードが値を丸めることがある）
+	current, err := rc.GetParameters(ctx, node, names)
+	if err != nil {
+		rc.log(ctx, slog.LevelWarn, "failed to read back parameters", "node", node, "error", err)
+		current = nil
+	}
+	res.Changes = []params.Change{}
+	for i, name := range names {
+		ok := atomic && res.Results[0].Successful
+		if !atomic {
+			ok = i < len(res.Results) && res.Results[i].Successful
+		}
+		if !ok {
+			continue
+		}
+		next := fromParameterValue(&ps[i].Value)
+		if current != nil {
+			next = current[i]
+		}
+		res.Changes = append(res.Changes, params.Change{Name: name, Old: old[i], New: next})
+		rc.log(ctx, slog.LevelInfo, "parameter changed", "node", NormalizeNodeName(node), "name", name, "old", old[i].Value, "new", next.Value)
+	}
+	return res, nil
+}
+
+// toParameterValue は params.Value を rcl_interfaces/ParameterValue にします（params.Parse を通した値）
+func toParameterValue(v params.Value) rcl_interfaces_msg.ParameterValue {
+	var m rcl_interfaces_msg.ParameterValue
+	m.Type, _ = params.TypeID(v.Type)
+	switch x := v.Value.(type) {
+	case bool:
+		m.BoolValue = x
+	case int64:
+		m.IntegerValue = x
+	case float64:
+		m.DoubleValue = x
+	case string:
+		m.StringValue = x
+	case []byte:
+		m.ByteArrayValue = x
+	case []bool:
+		m.BoolArrayValue = x
+	case []int64:
+		m.IntegerArrayValue = x
+	case []float64:
+		m.DoubleArrayValue = x
+	case []string:
+		m.StringArrayValue = x
+	}
+	return m
+}
+
+func fromParameterValue(m *rcl_interfaces_msg.ParameterValue) params.Value {
+	v := params.Value{Type: params.TypeName(m.Type)}
+	switch m.Type {
+	case rcl_interfaces_msg.ParameterType_PARAMETER_BOOL:
+		v.Value = m.BoolValue
+	case rcl_interfaces_msg.ParameterType_PARAMETER_INTEGER:
+		v.Value = m.IntegerValue
+	case rcl_interfaces_msg.ParameterType_PARAMETER_DOUBLE:
+		v.Value = m.DoubleValue
+	case rcl_interfaces_msg.ParameterType_PARAMETER_STRING:
+		v.Value = m.StringValue
+	case rcl_interfaces_msg.ParameterType_PARAMETER_BYTE_ARRAY:
+		// JSON で base64 にならないよう数値の配列にする
+		ints := make([]int, len(m.ByteArrayValue))
+		for i, b := range m.ByteArrayValue {
+			ints[i] = int(b)
+		}
+		v.Value = ints
+	case rcl_interfaces_msg.ParameterType_PARAMETER_BOOL_ARRAY:
+		v.Value = m.BoolArrayValue
+	case rcl_interfaces_msg.ParameterType_PARAMETER_INTEGER_ARRAY:
+		v.Value = m.IntegerArrayValue
+	case rcl_interfaces_msg.ParameterType_PARAMETER_DOUBLE_ARRAY:
+		v.Value = m.DoubleArrayValue
+	case rcl_interfaces_msg.ParameterType_PARAMETER_STRING_ARRAY:
+		v.Value = m.StringArrayValue
+	}
+	return v
+}
+
+func fromParameterDescriptor(m *rcl_interfaces_msg.ParameterDescriptor) params.Descriptor {
+	d := params.Descriptor{
+		Type:                  params.TypeName(m.Type),
+		Description:           m.Description,
+		AdditionalConstraints: m.AdditionalConstraints,
+		ReadOnly:              m.ReadOnly,
+		DynamicTyping:         m.DynamicTyping,
+	}
+	if len(m.FloatingPointRange) > 0 {
+		r := m.FloatingPointRange[0]
+		d.FloatRange = &params.Range{From: r.FromValue, To: r.ToValue, Step: r.Step}
+	}
+	if len(m.IntegerRange) > 0 {
+		r := m.IntegerRange[0]
+		d.IntegerRange = &params.Range{From: float64(r.FromValue), To: float64(r.ToValue), Step: float64(r.Step)}
+	}
+	return d
+}
@@ -0,0 +1,225 @@
+// internal/robot/planner.go
+package robot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"catchrobo_app/internal/geom"
+	"catchrobo_app/internal/planner"
+	"catchrobo_app/internal/score"
+)
+
+// ErrNotArrived は計画の実行中、見積もりの時間と arrive_timeout_s を過ぎても手先が目標に着かなかった場合に返ります
+var ErrNotArrived = errors.New("arm did not arrive at goal")
+
+// arrivePollInterval は手先が着いたかを確かめる間隔です
+const arrivePollInterval = 50 * time.Millisecond
+
+// Field はフィールドのセルの座標を返します
+func (rc *RobotController) Field() planner.Field {
+	return rc.field
+}
+
+// PlanPicks は残っているワークを取る順番を決めます（Publish はしない）
+// 認識したワークは TF で指令のフレームに直してから使う。req.Start が無ければ今の目標位置から始める
+func (rc *RobotController) PlanPicks(req planner.Request) (planner.Plan, error) {
+	dets := make([]planner.Detection, len(req.Detections))
+	for i, d := range req.Detections {
+		goal, err := rc.goalToCommandFrame(PoseGoal{Position: geom.Vec3{X: d.X, Y: d.Y, Z: d.Z}, FrameId: d.FrameId})
+		if err != nil {
+			return planner.Plan{}, fmt.Errorf("detection %s: %w", d.ID, err)
+		}
+		d.X, d.Y, d.Z, d.FrameId = goal.Position.X, goal.Position.Y, goal.Position.Z, CommandFrameId
+		dets[i] = d
+	}
+	req.Detections = dets
+	if req.Start == nil {
+		if snap := rc.target.Snapshot(); snap.Initialized {
+			req.Start = &geom.Vec3{X: snap.X, Y: snap.Y, Z: snap.Z}
+		}
+	}
+	return planner.Build(rc.field, rc.plannerCfg, req)
+}
+
+// PlanExecution は計画の実行の状態を返します
+func (rc *RobotController) PlanExecution() planner.ExecStatus {
+	return rc.plan.Status()
+}
+
+// LoadPlan は計画を実行するために読み込みます。1手ずつ RunPlanStep で進める
+func (rc *RobotController) LoadPlan(ctx context.Context, plan planner.Plan) (planner.ExecStatus, error) {
+	st, err := rc.plan.Load(plan)
+	if err == nil {
+		rc.log(ctx, slog.LevelInfo, "Plan loaded", "side", plan.Side, "steps", len(plan.Steps), "total_s", plan.TotalS)
+	}
+	return st, err
+}
+
+// AbortPlan は計画の実行をやめます（実行中の手は次の Publish の前で止まる）
+func (rc *RobotController) AbortPlan(ctx context.Context) (planner.ExecStatus, error) {
+	st, err := rc.plan.Abort()
+	if err == nil {
+		rc.log(ctx, slog.LevelWarn, "Plan aborted", "completed", st.Completed)
+	}
+	return st, err
+}
+
+// RunPlanStep は計画の次の1手を実行します（ワークの上へ動いて catch、置きに行く手なら置き場所へ動いて release）
+// 記録中の試合・練習があれば試技を追加し、手を終えた時刻を試技の終わりにする。prevSuccess があれば先に前の試技の成否を付ける
+// 失敗した手は同じ手のままオペレーターの確認を待つので、原因を直してから呼び直せばやり直せる
+// つかんだ後（置き場所への移動や release）で失敗した手は、つかみ直さずに置きに行くところからやり直す
+func (rc *RobotController) RunPlanStep(ctx context.Context, prevSuccess *bool) (planner.ExecStatus, error) {
+	if prevSuccess != nil {
+		rc.markPendingAttempt(ctx, *prevSuccess)
+	}
+	plan, step, picked, err := rc.plan.Begin()
+	if err != nil {
+		return rc.plan.Status(), err
+	}
+	// やり直しで置きに行くだけなら試技は前回の分のまま
+	attempt := 0
+	if step.Item.Cell != 0 {
+		if picked {
+			attempt = rc.pendingAttempt(step.Item.Cell)
+		} else if _, ok := rc.scores.Current(); ok {
+			if a, err := rc.scores.StartAttempt(score.AttemptReq{Cell: step.Item.Cell}); err != nil {
+				rc.log(ctx, slog.LevelWarn, "plan step not recorded", "step", step.N, "error", err)
+			} else {
+				attempt = a.N
+			}
+		}
+	}
+	err = rc.runPlanStep(ctx, plan.Release, step, picked)
+	st := rc.plan.Finish(err)
+	// つかんだ後で止まった手は置きに行くところから続けるので、試技はまだ終えない
+	if attempt > 0 && (err == nil || !st.Picked) {
+		if _, err := rc.scores.EndAttempt(attempt); err != nil {
+			rc.log(ctx, slog.LevelWarn, "failed to end attempt", "attempt", attempt, "error", err)
+		}
+	}
+	if err != nil {
+		rc.log(ctx, slog.LevelWarn, "Plan step failed", "step", step.N, "item", step.Item.ID, "picked", st.Picked, "error", err)
+		return st, err
+	}
+	rc.log(ctx, slog.LevelInfo, "Plan step done", "step", step.N, "item", step.Item.ID, "place", step.Place, "resumed", picked, "state", st.State)
+	return st, nil
+}
+
+// markPendingAttempt は記録中のものの結果待ちの試技に、オペレーターが確認した成否を付けます
+func (rc *RobotController) markPendingAttempt(ctx context.Context, success bool) {
+	cur, ok := rc.scores.Current()
+	if !ok || len(cur.Attempts) == 0 || cur.Attempts[len(cur.Attempts)-1].Success != nil {
+		return
+	}
+	n := len(cur.Attempts)
+	if _, err := rc.scores.FinishAttempt(n, score.ResultReq{Success: &success, MarkedBy: score.MarkedByOperator}); err != nil {
+		rc.log(ctx, slog.LevelWarn, "failed to mark attempt", "attempt", n, "error", err)
+	}
+}
+
+// pendingAttempt は記録中のもので cell を取りに行ったまま終わっていない最後の試技の番号を返します（無ければ0）
+func (rc *RobotController) pendingAttempt(cell int) int {
+	cur, ok := rc.scores.Current()
+	if !ok || len(cur.Attempts) == 0 {
+		return 0
+	}
+	a := cur.Attempts[len(cur.Attempts)-1]
+	if a.Cell != cell || a.Success != nil || a.End != nil {
+		return 0
+	}
+	return a.N
+}
+
+// runPlanStep は1手を動かします。picked なら（前回つかみ終えていれば）置きに行くところから始める
+func (rc *RobotController) runPlanStep(ctx context.Context, release geom.Vec3, step planner.Step, picked bool) error {
+	if !picked {
+		if err := rc.movePlanned(ctx, step.Item.Position, step.TravelS); err != nil {
+			return fmt.Errorf("move to %s: %w", step.Item.ID, err)
+		}
+		if err := rc.PublishCatchMotion(ctx); err != nil {
+			return fmt.Errorf("catch %s: %w", step.Item.ID, err)
+		}
+		// catch を送った後はワークをつかんでいるものとして扱う（失敗してもセルには戻らない）
+		rc.plan.Pick()
+		if err := rc.sleepPlanned(ctx, rc.plannerCfg.PickS); err != nil {
+			return err
+		}
+	}
+	if !step.Place {
+		return nil
+	}
+	if err := rc.movePlanned(ctx, release, step.ReturnS); err != nil {
+		return fmt.Errorf("move to release: %w", err)
+	}
+	if err := rc.PublishReleaseMotion(ctx); err != nil {
+		return fmt.Errorf("release: %w", err)
+	}
+	return rc.sleepPlanned(ctx, rc.plannerCfg.PlaceS)
+}
+
+// movePlanned は目標位置を送り、手先が着くまで待ちます
+func (rc *RobotController) movePlanned(ctx context.Context, pos geom.Vec3, estimateS float64) error {
+	if rc.plan.Aborted() {
+		return planner.ErrAborted
+	}
+	if _, err := rc.PublishPosition(ctx, PoseGoal{Position: pos, FrameId: CommandFrameId}, nil); err != nil {
+		return err
+	}
+	return rc.waitArrival(ctx, pos, estimateS)
+}
+
+// waitArrival は計測姿勢が pos から arrive_tolerance_m に入るまで待ちます
+// 計測姿勢を受信していなければ見積もりの時間だけ待つ
+func (rc *RobotController) waitArrival(ctx context.Context, pos geom.Vec3, estimateS float64) error {
+	deadline := time.Now().Add(time.Duration((estimateS + rc.plannerCfg.ArriveTimeoutS) * float64(time.Second)))
+	if _, ok := rc.MeasuredPose(); !ok {
+		return rc.sleepPlanned(ctx, estimateS)
+	}
+	t := time.NewTicker(arrivePollInterval)
+	defer t.Stop()
+	for {
+		if m, ok := rc.MeasuredPose(); ok {
+			goal, err := rc.goalToCommandFrame(PoseGoal{Position: m.Position(), FrameId: m.FrameId})
+			if err == nil && goal.Position.Sub(pos).Norm() <= rc.plannerCfg.ArriveToleranceM {
+				return nil
+			}
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("%w: (%.3f, %.3f, %.3f)", ErrNotArrived, pos.X, pos.Y, pos.Z)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-rc.shutdownCh:
+			return ErrShuttingDown
+		case <-t.C:
+		}
+		if rc.plan.Aborted() {
+			return planner.ErrAborted
+		}
+	}
+}
+
+// sleepPlanned は seconds 秒待ちます（やめられたり停止処理に入ったら途中で戻る）
+func (rc *RobotController) sleepPlanned(ctx context.Context, seconds float64) error {
+	if seconds <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(time.Duration(seconds * float64(time.Second)))
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-rc.shutdownCh:
+		return ErrShuttingDown
+	case <-timer.C:
+	}
+	if rc.plan.Aborted() {
+		return planner.ErrAborted
+	}
+	return nil
+}
@@ -0,0 +1,54 @@
+// internal/robot/rosout.go
+package robot
+
+import (
+	"time"
+
+	"catchrobo_app/internal/config"
+	"catchrobo_app/internal/rosout"
+
+	rcl_interfaces_msg "msgs/rcl_interfaces/msg"
+
+	"github.com/tiiuae/rclgo/pkg/rclgo"
+)
+
+// setupRosout は /rosout（rcl_interfaces/Log）を購読してリングバッファに溜めます。spin 開始前に呼ぶ
+func (rc *RobotController) setupRosout(cfg *config.Config) {
+	rc.rosout = rosout.NewBuffer(cfg.Rosout.BufferSize)
+	if cfg.Rosout.Topic == "" {
+		return
+	}
+	// 各ノードの rosout は transient local で送るので、起動前のログも直近の分は受け取れる
+	qos := rclgo.NewDefaultQosProfile()
+	qos.Durability = rclgo.DurabilityTransientLocal
+	qos.Depth = 1000
+	opts := rclgo.NewDefaultSubscriptionOptions()
+	opts.Qos = qos
+	sub, err := rc.node.NewSubscription(cfg.Rosout.Topic, rcl_interfaces_msg.LogTypeSupport, opts, func(sub *rclgo.Subscription) {
+		var msg rcl_interfaces_msg.Log
+		if _, err := sub.TakeMessage(&msg); err != nil {
+			// rc.warn だと自分のログが /rosout に戻ってくるので JSON のログにだけ出す
+			logger.Warn("failed to take rosout", "error", err)
+			return
+		}
+		rc.rosout.Add(rosout.Entry{
+			Time:     time.Unix(int64(msg.Stamp.Sec), int64(msg.Stamp.Nanosec)),
+			Level:    int(msg.Level),
+			Node:     msg.Name,
+			Msg:      msg.Msg,
+			File:     msg.File,
+			Function: msg.Function,
+			Line:     msg.Line,
+		})
+	})
+	if err == nil {
+		rc.rosoutSub = sub
+	} else {
+		rc.warn("failed to subscribe rosout", err)
+	}
+}
+
+// Rosout は /rosout のリングバッファを返します
+func (rc *RobotController) Rosout() *rosout.Buffer {
+	return rc.rosout
+}
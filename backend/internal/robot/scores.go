@@ -0,0 +1,33 @@
+// internal/robot/scores.go
+package robot
+
+import (
+	"context"
+
+	"catchrobo_app/internal/score"
+)
+
+// Scores は試技の記録と得点を返します
+func (rc *RobotController) Scores() *score.Tracker {
+	return rc.scores
+}
+
+// followMatch は試合の状態の変化を記録に反映します（試合の開始で記録を始め、時間切れ・reset で終える）
+func (rc *RobotController) followMatch(ctx context.Context) {
+	live, cancel := rc.match.Subscribe()
+	defer cancel()
+	prev := rc.match.Status()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case st := <-live:
+			// 残り秒数だけの通知は Seq が変わらない
+			if st.Seq == prev.Seq {
+				continue
+			}
+			rc.scores.FollowMatch(prev, st)
+			prev = st
+		}
+	}
+}
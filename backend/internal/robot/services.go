@@ -0,0 +1,231 @@
+// internal/robot/services.go
+package robot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/tiiuae/rclgo/pkg/rclgo"
+	"github.com/tiiuae/rclgo/pkg/rclgo/types"
+)
+
+// 他ノードのサービス呼び出しのエラー
+var (
+	ErrNodeNotFound    = errors.New("node not found")
+	ErrServiceNotFound = errors.New("service not found")
+	ErrServiceTimeout  = errors.New("service call timed out")
+)
+
+// DefaultServiceTimeout はサービス呼び出し1回の待ち時間の既定値です
+const DefaultServiceTimeout = 10 * time.Second
+
+// serviceDiscoveryWait は作ったばかりのクライアントがサーバーと繋がるまで待つ時間です
+// rclgo にはサーバーの準備を確かめる API が無く、繋がる前に送った要求は届かないことがある
+const serviceDiscoveryWait = 300 * time.Millisecond
+
+// serviceClient はサービスごとに1つ作って使い回すクライアントです
+// node.Spin は開始時のクライアントしか待たないので、後から作ったものは専用の WaitSet で応答を受ける
+type serviceClient struct {
+	client *rclgo.Client
+	ready  time.Time // この時刻までは繋がるのを待つ
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// serviceClients はサービス名ごとのクライアントです（Close で全て閉じる）
+type serviceClients struct {
+	mu      sync.Mutex
+	clients map[string]*serviceClient
+	closed  bool
+}
+
+// NormalizeNodeName はノード名を "/namespace/name" の形にします
+func NormalizeNodeName(name string) string {
+	name = strings.TrimSuffix(strings.TrimSpace(name), "/")
+	if !strings.HasPrefix(name, "/") {
+		name = "/" + name
+	}
+	return name
+}
+
+// splitNodeName は "/ns/name" を名前と名前空間に分けます
+func splitNodeName(fqn string) (name, namespace string) {
+	i := strings.LastIndex(fqn, "/")
+	namespace = fqn[:i]
+	if namespace == "" {
+		namespace = "/"
+	}
+	return fqn[i+1:], namespace
+}
+
+// joinNodeName は名前と名前空間を "/ns/name" にします
+func joinNodeName(name, namespace string) string {
+	return strings.TrimSuffix(namespace, "/") + "/" + name
+}
+
+// NodeNames は ROS グラフ上のノードを "/ns/name" の形で返します（自分自身を含む）
+func (rc *RobotController) NodeNames() ([]string, error) {
+	names, namespaces, err := rc.node.GetNodeNames()
+	if err != nil {
+		return nil, err
+	}
+	out := make([]string, len(names))
+	for i := range names {
+		out[i] = joinNodeName(names[i], namespaces[i])
+	}
+	return out, nil
+}
+
+// nodeService は node が suffix（"get_parameters" など）のサービスを提供していればその名前を返します
+// ノードが居なければ ErrNodeNotFound、サービスが無ければ ErrServiceNotFound
+func (rc *RobotController) nodeService(node, suffix string) (string, error) {
+	node = NormalizeNodeName(node)
+	nodes, err := rc.NodeNames()
+	if err != nil {
+		return "", err
+	}
+	found := false
+	for _, n := range nodes {
+		if n == node {
+			found = true
+			break
+		}
+	}
+	if !found {
+		return "", fmt.Errorf("%w: %s", ErrNodeNotFound, node)
+	}
+	name, namespace := splitNodeName(node)
+	services, err := rc.node.GetServiceNamesAndTypesByNode(name, namespace)
+	if err != nil {
+		return "", err
+	}
+	service := node + "/" + suffix
+	if _, ok := services[service]; !ok {
+		return "", fmt.Errorf("%w: %s", ErrServiceNotFound, service)
+	}
+	return service, nil
+}
+
+// nodesWithService は suffix のサービス（型が typ のもの）を提供しているノードを返します
+func (rc *RobotController) nodesWithService(suffix, typ string) ([]string, error) {
+	nodes, err := rc.NodeNames()
+	if err != nil {
+		return nil, err
+	}
+	var out []string
+	for _, node := range nodes {
+		name, namespace := splitNodeName(node)
+		services, err := rc.node.GetServiceNamesAndTypesByNode(name, namespace)
+		if err != nil {
+			continue // 問い合わせの間にノードが終了した
+		}
+		for _, t := range services[node+"/"+suffix] {
+			if t == typ {
+				out = append(out, node)
+				break
+			}
+		}
+	}
+	sort.Strings(out)
+	return out, nil
+}
+
+// callService は service を呼び出して応答を返します。待ち時間は ctx と設定の services.timeout_s の短い方
+func (rc *RobotController) callService(ctx context.Context, service string, ts types.ServiceTypeSupport, req types.Message) (types.Message, error) {
+	return rc.callServiceTimeout(ctx, service, ts, req, rc.serviceTimeout)
+}
+
+// callServiceTimeout は待ち時間を指定して service を呼び出します（0以下なら DefaultServiceTimeout）
+func (rc *RobotController) callServiceTimeout(ctx context.Context, service string, ts types.ServiceTypeSupport, req types.Message, timeout time.Duration) (types.Message, error) {
+	sc, err := rc.serviceClient(service, ts)
+	if err != nil {
+		return nil, err
+	}
+	if wait := time.Until(sc.ready); wait > 0 {
+		select {
+		case <-time.After(wait):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	if timeout <= 0 {
+		timeout = DefaultServiceTimeout
+	}
+	callCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	start := time.Now()
+	resp, _, err := sc.client.Send(callCtx, req)
+	if err != nil {
+		if errors.Is(err, context.DeadlineExceeded) && ctx.Err() == nil {
+			err = fmt.Errorf("%w: %s (%s)", ErrServiceTimeout, service, timeout)
+		}
+		rc.log(ctx, slog.LevelWarn, "service call failed", "service", service, "error", err)
+		return nil, err
+	}
+	rc.log(ctx, slog.LevelDebug, "service call", "service", service, "ms", time.Since(start).Milliseconds())
+	return resp, nil
+}
+
+// serviceClient は service のクライアントを返します（無ければ作って応答待ちの WaitSet を動かす）
+func (rc *RobotController) serviceClient(service string, ts types.ServiceTypeSupport) (*serviceClient, error) {
+	s := &rc.services
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return nil, ErrShuttingDown
+	}
+	if sc, ok := s.clients[service]; ok {
+		return sc, nil
+	}
+	client, err := rc.node.NewClient(service, ts, nil)
+	if err != nil {
+		return nil, fmt.Errorf("create client %s: %w", service, err)
+	}
+	ws, err := rc.node.Context().NewWaitSet()
+	if err != nil {
+		_ = client.Close()
+		return nil, fmt.Errorf("create wait set for %s: %w", service, err)
+	}
+	ws.AddClients(client)
+	ctx, cancel := context.WithCancel(context.Background())
+	sc := &serviceClient{client: client, ready: time.Now().Add(serviceDiscoveryWait), cancel: cancel, done: make(chan struct{})}
+	go func() {
+		defer close(sc.done)
+		defer ws.Close()
+		if err := ws.Run(ctx); err != nil && ctx.Err() == nil {
+			rc.warn("service client wait set stopped", err)
+		}
+	}()
+	if s.clients == nil {
+		s.clients = map[string]*serviceClient{}
+	}
+	s.clients[service] = sc
+	return sc, nil
+}
+
+// closeServiceClients は全てのサービスクライアントを閉じます。ノードを閉じる前に呼ぶ
+func (rc *RobotController) closeServiceClients() {
+	s := &rc.services
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closed = true
+	for service, sc := range s.clients {
+		sc.cancel()
+		select {
+		case <-sc.done:
+		case <-time.After(spinStopTimeout):
+			rc.log(context.Background(), slog.LevelWarn, "service client did not stop in time", "service", service)
+			continue // 動いている WaitSet が触るので閉じない
+		}
+		if err := sc.client.Close(); err != nil {
+			rc.warn("failed to close service client", err)
+		}
+	}
+	s.clients = nil
+}
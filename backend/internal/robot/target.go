@@ -0,0 +1,244 @@
+// internal/robot/target.go
+package robot
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"catchrobo_app/internal/geom"
+)
+
+var (
+	// ErrTargetVersionMismatch は If-Match で指定された版数が現在の版数と一致しない場合に返ります
+	ErrTargetVersionMismatch = errors.New("target version mismatch")
+	// ErrTargetUninitialized は目標位置が未確定のまま相対移動しようとした場合に返ります
+	ErrTargetUninitialized = errors.New("target not initialized")
+	// ErrFrameMismatch は目標位置と異なるフレームで相対移動しようとした場合に返ります
+	ErrFrameMismatch = errors.New("frame does not match current target frame")
+	// ErrInvalidPose は NaN / Inf を含む姿勢や正規化できないクォータニオンが指定された場合に返ります
+	ErrInvalidPose = errors.New("invalid pose")
+	// ErrTransformUnavailable は指定フレームから指令フレームへの変換が TF から得られない場合に返ります
+	ErrTransformUnavailable = errors.New("transform unavailable")
+)
+
+// CommandFrameId はアームへ指令を送るフレームです。他のフレームで来た目標は TF でこのフレームへ変換します
+const CommandFrameId = "base_link"
+
+// PoseGoal は絶対値での目標姿勢です
+type PoseGoal struct {
+	Position    geom.Vec3
+	Orientation *geom.Quaternion // nil なら現在の目標姿勢を維持
+	FrameId     string           // 空なら現在の目標のフレーム
+}
+
+// PoseDelta はジョグ用の相対変位です
+type PoseDelta struct {
+	Translation geom.Vec3
+	Rotation    geom.Quaternion // 固定軸まわりの回転増分（ゼロ値なら回転しない）
+	FrameId     string          // 空なら現在の目標のフレーム
+}
+
+// 目標位置がどこから来たか
+const (
+	TargetSourceNone      = "none"
+	TargetSourcePersisted = "persisted"
+	TargetSourceMeasured  = "measured"
+	TargetSourceCommand   = "command"
+)
+
+// TargetSnapshot は目標位置のある時点でのコピーです
+type TargetSnapshot struct {
+	X           float64         `json:"x"`
+	Y           float64         `json:"y"`
+	Z           float64         `json:"z"`
+	Orientation geom.Quaternion `json:"orientation"`
+	FrameId     string          `json:"frame_id"`
+	Version     uint64          `json:"version"`
+	Initialized bool            `json:"initialized"`
+	Source      string          `json:"source"`
+	UpdatedAt   time.Time       `json:"updated_at"`
+}
+
+// TargetState は累積目標位置を排他制御・版管理・永続化付きで保持します
+type TargetState struct {
+	mu   sync.Mutex
+	snap TargetSnapshot
+
+	path string      // 永続化先（空なら永続化しない）
+	warn func(error) // 永続化失敗時の通知先
+}
+
+// NewTargetState は path から前回の目標位置を読み込みます。ファイルが無ければ未初期化で始まります
+func NewTargetState(path string, warn func(error)) *TargetState {
+	s := &TargetState{
+		snap: TargetSnapshot{Orientation: geom.Identity(), FrameId: CommandFrameId, Source: TargetSourceNone},
+		path: path,
+		warn: warn,
+	}
+	if path == "" {
+		return s
+	}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			s.notify(fmt.Errorf("read target state: %w", err))
+		}
+		return s
+	}
+	var snap TargetSnapshot
+	if err := json.Unmarshal(b, &snap); err != nil {
+		s.notify(fmt.Errorf("decode target state: %w", err))
+		return s
+	}
+	if snap.Initialized {
+		// 姿勢・フレームを持たない古い保存形式は単位姿勢 / base_link とみなす
+		if snap.Orientation.IsZero() {
+			snap.Orientation = geom.Identity()
+		}
+		if snap.FrameId == "" {
+			snap.FrameId = CommandFrameId
+		}
+		snap.Source = TargetSourcePersisted
+		s.snap = snap
+	}
+	return s
+}
+
+// Snapshot は現在の目標位置を返します
+func (s *TargetState) Snapshot() TargetSnapshot {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.snap
+}
+
+// Position は目標位置をベクトルで返します
+func (t TargetSnapshot) Position() geom.Vec3 { return geom.Vec3{X: t.X, Y: t.Y, Z: t.Z} }
+
+// Set は目標姿勢を絶対値で更新します。ifMatch が nil でなければ版数が一致した場合のみ更新します。
+// publish はロックを保持したまま呼ばれ、成功した場合のみ新しい目標姿勢が確定します
+func (s *TargetState) Set(goal PoseGoal, ifMatch *uint64, publish func(TargetSnapshot) error) (TargetSnapshot, error) {
+	return s.update(ifMatch, false, func(snap *TargetSnapshot) error {
+		snap.X, snap.Y, snap.Z = goal.Position.X, goal.Position.Y, goal.Position.Z
+		if goal.Orientation != nil {
+			snap.Orientation = *goal.Orientation
+		}
+		if goal.FrameId != "" {
+			snap.FrameId = goal.FrameId
+		}
+		return nil
+	}, publish)
+}
+
+// Add は目標姿勢に相対変位を加えます。目標位置が未初期化の場合は ErrTargetUninitialized を返します
+func (s *TargetState) Add(delta PoseDelta, ifMatch *uint64, publish func(TargetSnapshot) error) (TargetSnapshot, error) {
+	return s.update(ifMatch, true, func(snap *TargetSnapshot) error {
+		if delta.FrameId != "" && delta.FrameId != snap.FrameId {
+			return fmt.Errorf("%w: %q (target is %q)", ErrFrameMismatch, delta.FrameId, snap.FrameId)
+		}
+		snap.X += delta.Translation.X
+		snap.Y += delta.Translation.Y
+		snap.Z += delta.Translation.Z
+		if !delta.Rotation.IsZero() {
+			snap.Orientation = delta.Rotation.Mul(snap.Orientation)
+		}
+		return nil
+	}, publish)
+}
+
+func (s *TargetState) update(ifMatch *uint64, needInit bool, apply func(*TargetSnapshot) error, publish func(TargetSnapshot) error) (TargetSnapshot, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if ifMatch != nil && *ifMatch != s.snap.Version {
+		return s.snap, ErrTargetVersionMismatch
+	}
+	if needInit && !s.snap.Initialized {
+		return s.snap, ErrTargetUninitialized
+	}
+
+	next := s.snap
+	if err := apply(&next); err != nil {
+		return s.snap, err
+	}
+	if !next.Position().IsFinite() {
+		return s.snap, fmt.Errorf("%w: position is not finite", ErrInvalidPose)
+	}
+	q, err := next.Orientation.Normalize()
+	if err != nil {
+		return s.snap, fmt.Errorf("%w: orientation: %v", ErrInvalidPose, err)
+	}
+	next.Orientation = q
+	next.Version++
+	next.Initialized = true
+	next.Source = TargetSourceCommand
+	next.UpdatedAt = time.Now()
+
+	if publish != nil {
+		if err := publish(next); err != nil {
+			return s.snap, err
+		}
+	}
+	s.snap = next
+	s.save()
+	return next, nil
+}
+
+// InitFromMeasured は目標位置が未初期化の場合に限り、計測姿勢で初期化します
+func (s *TargetState) InitFromMeasured(m MeasuredPose) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.snap.Initialized {
+		return false
+	}
+	q, err := m.Orientation.Normalize()
+	if err != nil || !m.Position().IsFinite() {
+		return false
+	}
+	s.snap.X, s.snap.Y, s.snap.Z = m.X, m.Y, m.Z
+	s.snap.Orientation = q
+	if m.FrameId != "" {
+		s.snap.FrameId = m.FrameId
+	}
+	s.snap.Version++
+	s.snap.Initialized = true
+	s.snap.Source = TargetSourceMeasured
+	s.snap.UpdatedAt = time.Now()
+	s.save()
+	return true
+}
+
+// save は一時ファイルに書いてから rename する（書き込み途中で落ちても壊れないように）
+// 呼び出し側で mu を保持していること
+func (s *TargetState) save() {
+	if s.path == "" {
+		return
+	}
+	b, err := json.MarshalIndent(s.snap, "", "  ")
+	if err != nil {
+		s.notify(fmt.Errorf("encode target state: %w", err))
+		return
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0o755); err != nil {
+		s.notify(fmt.Errorf("create target state dir: %w", err))
+		return
+	}
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, b, 0o644); err != nil {
+		s.notify(fmt.Errorf("write target state: %w", err))
+		return
+	}
+	if err := os.Rename(tmp, s.path); err != nil {
+		s.notify(fmt.Errorf("rename target state: %w", err))
+	}
+}
+
+func (s *TargetState) notify(err error) {
+	if s.warn != nil {
+		s.warn(err)
+	}
+}
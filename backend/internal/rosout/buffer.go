@@ -0,0 +1,194 @@
+// internal/rosout/buffer.go
+package rosout
+
+// rosoutはROSにもginにも依存しないように書く（/rosout の購読は robot、HTTP と SSE は api パッケージ側）
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ROS のログレベル（rcl_interfaces/msg/Log の定数と同じ値）
+const (
+	LevelDebug = 10
+	LevelInfo  = 20
+	LevelWarn  = 30
+	LevelError = 40
+	LevelFatal = 50
+)
+
+// DefaultCapacity はリングバッファに残す件数の既定値です
+const DefaultCapacity = 2000
+
+// subscriberBuffer は SSE の購読者ごとのチャネルの長さです。溢れた分は捨てる（Seq が飛ぶ）
+const subscriberBuffer = 256
+
+// Entry は /rosout の1行です
+type Entry struct {
+	Seq      uint64    `json:"seq"` // 受信順の通し番号（SSE の id にも使う）
+	Time     time.Time `json:"time"`
+	Level    int       `json:"level"`
+	Severity string    `json:"severity"`
+	Node     string    `json:"node"` // ロガー名（ノード名）
+	Msg      string    `json:"msg"`
+	File     string    `json:"file,omitempty"`
+	Function string    `json:"function,omitempty"`
+	Line     uint32    `json:"line,omitempty"`
+}
+
+// SeverityName はログレベルの名前を返します
+func SeverityName(level int) string {
+	switch {
+	case level >= LevelFatal:
+		return "fatal"
+	case level >= LevelError:
+		return "error"
+	case level >= LevelWarn:
+		return "warn"
+	case level >= LevelInfo:
+		return "info"
+	}
+	return "debug"
+}
+
+// ParseLevel は debug / info / warn / error / fatal を数値にします
+func ParseLevel(s string) (int, error) {
+	switch strings.ToLower(s) {
+	case "", "debug":
+		return LevelDebug, nil
+	case "info":
+		return LevelInfo, nil
+	case "warn", "warning":
+		return LevelWarn, nil
+	case "error":
+		return LevelError, nil
+	case "fatal":
+		return LevelFatal, nil
+	}
+	return 0, fmt.Errorf("unknown severity %q (debug / info / warn / error / fatal)", s)
+}
+
+// Filter は取得するログの条件です。空のフィールドは条件にしない
+type Filter struct {
+	Node     string // ノード名の部分一致（先頭の / は無視）
+	MinLevel int    // この値以上のレベルだけ
+	Text     string // メッセージの部分一致（大文字小文字を区別しない）
+	AfterSeq uint64 // この番号より後だけ
+}
+
+// Match は e が条件に合えば true を返します
+func (f Filter) Match(e Entry) bool {
+	if e.Seq <= f.AfterSeq || e.Level < f.MinLevel {
+		return false
+	}
+	if f.Node != "" && !strings.Contains(strings.TrimPrefix(e.Node, "/"), strings.TrimPrefix(f.Node, "/")) {
+		return false
+	}
+	if f.Text != "" && !strings.Contains(strings.ToLower(e.Msg), strings.ToLower(f.Text)) {
+		return false
+	}
+	return true
+}
+
+// Buffer は直近のログを capacity 件だけ残すリングバッファです
+type Buffer struct {
+	mu      sync.Mutex
+	entries []Entry // 長さ capacity のリング
+	next    int     // 次に書く位置
+	full    bool
+	seq     uint64
+	subs    map[chan Entry]struct{}
+	dropped uint64 // 購読者が遅くて捨てた件数
+}
+
+// NewBuffer はリングバッファを作ります（capacity が0以下なら DefaultCapacity）
+func NewBuffer(capacity int) *Buffer {
+	if capacity <= 0 {
+		capacity = DefaultCapacity
+	}
+	return &Buffer{entries: make([]Entry, capacity), subs: map[chan Entry]struct{}{}}
+}
+
+// Add は1行追加して購読者に配ります。Seq と Severity はここで付ける
+func (b *Buffer) Add(e Entry) Entry {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.seq++
+	e.Seq = b.seq
+	e.Severity = SeverityName(e.Level)
+	b.entries[b.next] = e
+	b.next = (b.next + 1) % len(b.entries)
+	if b.next == 0 {
+		b.full = true
+	}
+	for ch := range b.subs {
+		select {
+		case ch <- e:
+		default:
+			b.dropped++
+		}
+	}
+	return e
+}
+
+// Query は条件に合うログを古い順に返します。limit が正なら新しい方から limit 件
+func (b *Buffer) Query(f Filter, limit int) []Entry {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	out := []Entry{}
+	for _, e := range b.ordered() {
+		if f.Match(e) {
+			out = append(out, e)
+		}
+	}
+	if limit > 0 && len(out) > limit {
+		out = out[len(out)-limit:]
+	}
+	return out
+}
+
+// ordered は古い順に並べたログです（呼び出し側で mu を保持）
+func (b *Buffer) ordered() []Entry {
+	if !b.full {
+		return b.entries[:b.next]
+	}
+	return append(append([]Entry(nil), b.entries[b.next:]...), b.entries[:b.next]...)
+}
+
+// Subscribe は新しいログを受け取るチャネルと、購読をやめる関数を返します
+// 受け取りが遅れてチャネルが溢れた分は捨てられる（Seq が飛ぶので分かる）
+func (b *Buffer) Subscribe() (<-chan Entry, func()) {
+	ch := make(chan Entry, subscriberBuffer)
+	b.mu.Lock()
+	b.subs[ch] = struct{}{}
+	b.mu.Unlock()
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() {
+			b.mu.Lock()
+			delete(b.subs, ch)
+			b.mu.Unlock()
+		})
+	}
+}
+
+// Stats はバッファの状態です
+type Stats struct {
+	Capacity    int    `json:"capacity"`
+	Buffered    int    `json:"buffered"`
+	LastSeq     uint64 `json:"last_seq"`
+	Subscribers int    `json:"subscribers"`
+	Dropped     uint64 `json:"dropped"`
+}
+
+// Stats はバッファの状態を返します
+func (b *Buffer) Stats() Stats {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	n := b.next
+	if b.full {
+		n = len(b.entries)
+	}
+	return Stats{Capacity: len(b.entries), Buffered: n, LastSeq: b.seq, Subscribers: len(b.subs), Dropped: b.dropped}
+}
@@ -0,0 +1,182 @@
+// internal/score/record.go
+package score
+
+import (
+	"encoding/csv"
+	"io"
+	"strconv"
+	"time"
+)
+
+// 記録の種類
+const (
+	KindMatch    = "match"    // 試合（/api/match の時計と連動する）
+	KindPractice = "practice" // 練習
+)
+
+// サイド
+const (
+	SideBlue = "blue"
+	SideRed  = "red"
+)
+
+// 成否を付けたもの
+const (
+	MarkedByOperator = "operator" // オペレーターが付けた
+	MarkedByDetected = "detected" // 認識などで自動で付けた
+)
+
+// 記録に残る出来事の種類
+const (
+	EventBegin        = "begin"         // 記録を始めた
+	EventAttemptStart = "attempt_start" // 取りに行った
+	EventAttemptEnd   = "attempt_end"   // 成否が付いた（付け直しも含む）
+	EventMatchState   = "match_state"   // 試合の状態が変わった
+	EventEnd          = "end"           // 記録を終えた
+)
+
+// Attempt は1回の試技（ワークを取って置くまで）です
+type Attempt struct {
+	N         int        `json:"n"` // 記録の中での番号（1始まり）
+	Cell      int        `json:"cell"`
+	Slot      string     `json:"slot,omitempty"`
+	Workpiece string     `json:"workpiece,omitempty"`
+	Start     time.Time  `json:"start"`
+	End       *time.Time `json:"end,omitempty"`
+	Success   *bool      `json:"success"` // nil なら結果待ち
+	MarkedBy  string     `json:"marked_by,omitempty"`
+	Points    int        `json:"points"` // 成功したときのワークの点数
+	Bonus     int        `json:"bonus"`  // 成功したときの加点
+	Zones     []string   `json:"zones,omitempty"`
+}
+
+// Duration は試技にかかった時間です（終わった時刻が分からなければ0）
+func (a Attempt) Duration() time.Duration {
+	if a.End == nil {
+		return 0
+	}
+	return a.End.Sub(a.Start)
+}
+
+// Succeeded は成功した試技かを返します
+func (a Attempt) Succeeded() bool {
+	return a.Success != nil && *a.Success
+}
+
+// Event は記録に残る出来事です。試技の欄はその時点の値
+type Event struct {
+	Seq       int       `json:"seq"`
+	Time      time.Time `json:"time"`
+	Type      string    `json:"type"`
+	Attempt   int       `json:"attempt,omitempty"`
+	Cell      int       `json:"cell,omitempty"`
+	Slot      string    `json:"slot,omitempty"`
+	Workpiece string    `json:"workpiece,omitempty"`
+	Success   *bool     `json:"success,omitempty"`
+	MarkedBy  string    `json:"marked_by,omitempty"`
+	Points    int       `json:"points,omitempty"` // この出来事で増減した点数
+	Score     int       `json:"score"`            // この出来事の後の合計
+	Detail    string    `json:"detail,omitempty"`
+}
+
+// Record は1回の試合・練習の記録です
+type Record struct {
+	ID       string     `json:"id"`
+	Kind     string     `json:"kind"`
+	Side     string     `json:"side"`
+	Start    time.Time  `json:"start"`
+	End      *time.Time `json:"end,omitempty"`
+	Rules    Rules      `json:"rules"` // 始めたときのルール（後から設定を変えても点数は変わらない）
+	Score    int        `json:"score"`
+	Attempts []Attempt  `json:"attempts"`
+	Events   []Event    `json:"events"`
+}
+
+// Summary は一覧に出す記録の要約です
+type Summary struct {
+	ID        string     `json:"id"`
+	Kind      string     `json:"kind"`
+	Side      string     `json:"side"`
+	Start     time.Time  `json:"start"`
+	End       *time.Time `json:"end,omitempty"`
+	Score     int        `json:"score"`
+	Attempts  int        `json:"attempts"`
+	Successes int        `json:"successes"`
+}
+
+// Summary は記録の要約を返します
+func (r *Record) Summary() Summary {
+	s := Summary{ID: r.ID, Kind: r.Kind, Side: r.Side, Start: r.Start, End: r.End, Score: r.Score, Attempts: len(r.Attempts)}
+	for _, a := range r.Attempts {
+		if a.Succeeded() {
+			s.Successes++
+		}
+	}
+	return s
+}
+
+// clone は呼び出し側が書き換えても記録に影響しない複製を返します
+func (r *Record) clone() Record {
+	c := *r
+	c.Attempts = make([]Attempt, len(r.Attempts))
+	for i, a := range r.Attempts {
+		a.Zones = append([]string(nil), a.Zones...)
+		c.Attempts[i] = a
+	}
+	c.Events = append([]Event(nil), r.Events...)
+	if c.Events == nil {
+		c.Events = []Event{}
+	}
+	return c
+}
+
+// addEvent は出来事を追加します（Seq と Score を埋める）
+func (r *Record) addEvent(ev Event) Event {
+	ev.Seq = len(r.Events) + 1
+	ev.Score = r.Score
+	r.Events = append(r.Events, ev)
+	return ev
+}
+
+// csvHeader は WriteCSV の列です
+var csvHeader = []string{"seq", "time", "elapsed_s", "type", "attempt", "cell", "slot", "workpiece", "success", "marked_by", "points", "score", "detail"}
+
+// WriteCSV は出来事を CSV にします（公式の得点表と突き合わせるため）
+func (r *Record) WriteCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(csvHeader); err != nil {
+		return err
+	}
+	for _, ev := range r.Events {
+		row := []string{
+			strconv.Itoa(ev.Seq),
+			ev.Time.Format(time.RFC3339Nano),
+			strconv.FormatFloat(ev.Time.Sub(r.Start).Seconds(), 'f', 3, 64),
+			ev.Type,
+			optInt(ev.Attempt),
+			optInt(ev.Cell),
+			ev.Slot,
+			ev.Workpiece,
+			"",
+			ev.MarkedBy,
+			strconv.Itoa(ev.Points),
+			strconv.Itoa(ev.Score),
+			ev.Detail,
+		}
+		if ev.Success != nil {
+			row[8] = strconv.FormatBool(*ev.Success)
+		}
+		if err := cw.Write(row); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func optInt(n int) string {
+	if n == 0 {
+		return ""
+	}
+	return strconv.Itoa(n)
+}
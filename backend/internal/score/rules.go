@@ -0,0 +1,43 @@
+// internal/score/rules.go
+package score
+
+// scoreはROSにもginにも依存しないように書く（試合の状態の受け取りは robot、HTTP と CSV の配信は api パッケージ側）
+import (
+	"slices"
+)
+
+// BonusZone は取った場所（フィールドのセル）か置いた場所（スロット）で加点されるゾーンです
+type BonusZone struct {
+	Name   string   `yaml:"name" json:"name"`
+	Cells  []int    `yaml:"cells" json:"cells,omitempty"` // フィールドのセル番号（1始まり。共通エリアなど）
+	Slots  []string `yaml:"slots" json:"slots,omitempty"` // 置き場所の名前（シューティングボックスの段など）
+	Points int      `yaml:"points" json:"points"`         // 1個あたりの加点
+}
+
+// Rules はキャチロボの得点のルールです（年ごとに変わるので設定で書く）
+type Rules struct {
+	Points     int            `yaml:"points" json:"points"`                   // ワーク1個あたりの点数
+	Workpieces map[string]int `yaml:"workpieces" json:"workpieces,omitempty"` // ワークの種類ごとの点数（points の代わり）
+	BonusZones []BonusZone    `yaml:"bonus_zones" json:"bonus_zones,omitempty"`
+}
+
+// DefaultRules は既定のルールです（1個1点、ボーナスなし）
+func DefaultRules() Rules {
+	return Rules{Points: 1}
+}
+
+// Score は成功した試技の点数（ワークの点数）と加点、当てはまったボーナスゾーンを返します
+// cell と slot の両方が同じゾーンに当てはまっても加点は1回
+func (r Rules) Score(cell int, slot, workpiece string) (points, bonus int, zones []string) {
+	points = r.Points
+	if p, ok := r.Workpieces[workpiece]; ok && workpiece != "" {
+		points = p
+	}
+	for _, z := range r.BonusZones {
+		if slices.Contains(z.Cells, cell) || (slot != "" && slices.Contains(z.Slots, slot)) {
+			bonus += z.Points
+			zones = append(zones, z.Name)
+		}
+	}
+	return points, bonus, zones
+}
@@ -0,0 +1,430 @@
+// internal/score/tracker.go
+package score
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"catchrobo_app/internal/match"
+)
+
+// エラー
+var (
+	// ErrNotFound は指定した ID の記録が無い場合に返ります
+	ErrNotFound = errors.New("record not found")
+	// ErrNoRecord は記録を始めていないのに試技を記録しようとした場合に返ります
+	ErrNoRecord = errors.New("no record in progress")
+	// ErrAttemptPending は前の試技の成否が付いていないのに次の試技を始めようとした場合に返ります
+	ErrAttemptPending = errors.New("previous attempt has no result yet")
+	// ErrInvalid は種類・サイド・セル番号などが正しくない場合に返ります
+	ErrInvalid = errors.New("invalid record request")
+)
+
+// CurrentID は記録中のものを指す ID です（/api/matches/current）
+const CurrentID = "current"
+
+// AttemptReq は試技を始めるときの内容です。success を付けるとその場で成否まで記録する
+// 後から記録するときは start / end に実際の時刻を付ける（start を省けば今、end を省けば終わった時刻は分からないまま）
+type AttemptReq struct {
+	Cell      int        `json:"cell"` // フィールドのセル番号（1始まり）
+	Slot      string     `json:"slot"`
+	Workpiece string     `json:"workpiece"`
+	Success   *bool      `json:"success"`
+	MarkedBy  string     `json:"marked_by"` // operator（既定）/ detected
+	Start     *time.Time `json:"start"`
+	End       *time.Time `json:"end"`
+}
+
+// ResultReq は試技の成否を付けるときの内容です（付け直しもできる）。slot / workpiece は空なら変えない
+type ResultReq struct {
+	Success   *bool  `json:"success" binding:"required"`
+	Slot      string `json:"slot"`
+	Workpiece string `json:"workpiece"`
+	MarkedBy  string `json:"marked_by"`
+}
+
+// Tracker は記録中の試合・練習に試技を記録して点数を数え、記録ごとに dir へ JSON で保存します
+type Tracker struct {
+	dir  string
+	warn func(error)
+
+	mu      sync.Mutex
+	rules   Rules
+	side    string // 次に自動で始める記録のサイド（最後に使ったもの）
+	records map[string]*Record
+	current *Record
+	now     func() time.Time
+}
+
+// NewTracker は dir の記録を読み込んで Tracker を作ります。dir が空なら保存しない
+// 読み込めないファイルは warn に渡して飛ばす。終わっていない記録（途中で再起動した場合）は最後の出来事の時刻で終える
+func NewTracker(dir string, rules Rules, side string, warn func(error)) *Tracker {
+	if side == "" {
+		side = SideBlue
+	}
+	t := &Tracker{
+		dir:     dir,
+		warn:    warn,
+		rules:   rules,
+		side:    side,
+		records: map[string]*Record{},
+		now:     time.Now,
+	}
+	t.load()
+	return t
+}
+
+func (t *Tracker) load() {
+	if t.dir == "" {
+		return
+	}
+	files, err := filepath.Glob(filepath.Join(t.dir, "*.json"))
+	if err != nil {
+		t.notify(fmt.Errorf("list match records: %w", err))
+		return
+	}
+	for _, f := range files {
+		b, err := os.ReadFile(f)
+		if err != nil {
+			t.notify(fmt.Errorf("read match record: %w", err))
+			continue
+		}
+		var rec Record
+		if err := json.Unmarshal(b, &rec); err != nil || rec.ID == "" {
+			t.notify(fmt.Errorf("parse match record %s: %v", f, err))
+			continue
+		}
+		if rec.End == nil {
+			end := rec.Start
+			if n := len(rec.Events); n > 0 {
+				end = rec.Events[n-1].Time
+			}
+			rec.End = &end
+			rec.addEvent(Event{Time: end, Type: EventEnd, Detail: "interrupted"})
+			t.save(&rec)
+		}
+		t.records[rec.ID] = &rec
+	}
+}
+
+// Rules は新しく始める記録に使うルールを返します
+func (t *Tracker) Rules() Rules {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.rules
+}
+
+// Begin は記録を始めます。記録中のものがあれば終えてから始める。side が空なら最後に使ったサイド
+func (t *Tracker) Begin(kind, side string) (Record, error) {
+	if kind != KindMatch && kind != KindPractice {
+		return Record{}, fmt.Errorf("%w: kind must be %s or %s", ErrInvalid, KindMatch, KindPractice)
+	}
+	if side != "" && side != SideBlue && side != SideRed {
+		return Record{}, fmt.Errorf("%w: side must be %s or %s", ErrInvalid, SideBlue, SideRed)
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.beginLocked(kind, side).clone(), nil
+}
+
+func (t *Tracker) beginLocked(kind, side string) *Record {
+	now := t.now()
+	if t.current != nil {
+		t.endLocked("replaced", now)
+	}
+	if side == "" {
+		side = t.side
+	}
+	t.side = side
+	id := now.Format("20060102-150405")
+	for n := 2; t.records[id] != nil; n++ {
+		id = fmt.Sprintf("%s-%d", now.Format("20060102-150405"), n)
+	}
+	rec := &Record{ID: id, Kind: kind, Side: side, Start: now, Rules: t.rules, Attempts: []Attempt{}}
+	rec.addEvent(Event{Time: now, Type: EventBegin, Detail: kind + " " + side})
+	t.records[id] = rec
+	t.current = rec
+	t.save(rec)
+	return rec
+}
+
+// End は記録中のものを終えます。結果待ちの試技は成否を付けずに残す
+func (t *Tracker) End(detail string) (Record, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.current == nil {
+		return Record{}, ErrNoRecord
+	}
+	return t.endLocked(detail, t.now()).clone(), nil
+}
+
+func (t *Tracker) endLocked(detail string, now time.Time) *Record {
+	rec := t.current
+	rec.End = &now
+	rec.addEvent(Event{Time: now, Type: EventEnd, Detail: detail})
+	t.current = nil
+	t.save(rec)
+	return rec
+}
+
+// Current は記録中のものを返します
+func (t *Tracker) Current() (Record, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.current == nil {
+		return Record{}, false
+	}
+	return t.current.clone(), true
+}
+
+// Get は ID の記録を返します。CurrentID なら記録中のもの
+func (t *Tracker) Get(id string) (Record, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if id == CurrentID {
+		if t.current == nil {
+			return Record{}, ErrNoRecord
+		}
+		return t.current.clone(), nil
+	}
+	rec := t.records[id]
+	if rec == nil {
+		return Record{}, fmt.Errorf("%w: %s", ErrNotFound, id)
+	}
+	return rec.clone(), nil
+}
+
+// List は記録の要約を新しい順に返します
+func (t *Tracker) List() []Summary {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	out := make([]Summary, 0, len(t.records))
+	for _, rec := range t.records {
+		out = append(out, rec.Summary())
+	}
+	slices.SortFunc(out, func(a, b Summary) int { return b.Start.Compare(a.Start) })
+	return out
+}
+
+// Records は記録中のものも含めた全ての記録を古い順に返します（集計用）
+func (t *Tracker) Records() []Record {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	out := make([]Record, 0, len(t.records))
+	for _, rec := range t.records {
+		out = append(out, rec.clone())
+	}
+	slices.SortFunc(out, func(a, b Record) int { return a.Start.Compare(b.Start) })
+	return out
+}
+
+// StartAttempt は記録中のものに試技を追加します。req.Success があればその場で成否も付ける
+func (t *Tracker) StartAttempt(req AttemptReq) (Attempt, error) {
+	if req.Cell < 1 {
+		return Attempt{}, fmt.Errorf("%w: cell must be 1 or more", ErrInvalid)
+	}
+	if err := checkMarkedBy(req.MarkedBy); err != nil {
+		return Attempt{}, err
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	rec := t.current
+	if rec == nil {
+		return Attempt{}, ErrNoRecord
+	}
+	if n := len(rec.Attempts); n > 0 && rec.Attempts[n-1].Success == nil {
+		return Attempt{}, fmt.Errorf("%w: attempt %d", ErrAttemptPending, n)
+	}
+	now := t.now()
+	start := now
+	if req.Start != nil {
+		start = *req.Start
+	}
+	if req.End != nil && req.End.Before(start) {
+		return Attempt{}, fmt.Errorf("%w: end is before start", ErrInvalid)
+	}
+	rec.Attempts = append(rec.Attempts, Attempt{
+		N:         len(rec.Attempts) + 1,
+		Cell:      req.Cell,
+		Slot:      req.Slot,
+		Workpiece: req.Workpiece,
+		Start:     start,
+		End:       req.End,
+	})
+	a := &rec.Attempts[len(rec.Attempts)-1]
+	rec.addEvent(Event{Time: start, Type: EventAttemptStart, Attempt: a.N, Cell: a.Cell, Slot: a.Slot, Workpiece: a.Workpiece})
+	if req.Success != nil {
+		t.resultLocked(rec, a, ResultReq{Success: req.Success, MarkedBy: req.MarkedBy}, now)
+	}
+	t.save(rec)
+	return *a, nil
+}
+
+// EndAttempt は記録中のものの n 番目の試技が終わった時刻を今にします（成否は付けない）
+// 既に終わった時刻があれば変えない。計画の手のように、動き終えた時刻が成否の確認より先に分かる場合に使う
+func (t *Tracker) EndAttempt(n int) (Attempt, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	rec := t.current
+	if rec == nil {
+		return Attempt{}, ErrNoRecord
+	}
+	if n < 1 || n > len(rec.Attempts) {
+		return Attempt{}, fmt.Errorf("%w: attempt %d", ErrNotFound, n)
+	}
+	a := &rec.Attempts[n-1]
+	if a.End == nil {
+		now := t.now()
+		a.End = &now
+		t.save(rec)
+	}
+	return *a, nil
+}
+
+// FinishAttempt は記録中のものの n 番目の試技に成否を付けます。既に付いていれば付け直して点数も直す
+// 終わった時刻が無ければ成否を付けた時刻を終わった時刻にする
+func (t *Tracker) FinishAttempt(n int, req ResultReq) (Attempt, error) {
+	if req.Success == nil {
+		return Attempt{}, fmt.Errorf("%w: success is required", ErrInvalid)
+	}
+	if err := checkMarkedBy(req.MarkedBy); err != nil {
+		return Attempt{}, err
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	rec := t.current
+	if rec == nil {
+		return Attempt{}, ErrNoRecord
+	}
+	if n < 1 || n > len(rec.Attempts) {
+		return Attempt{}, fmt.Errorf("%w: attempt %d", ErrNotFound, n)
+	}
+	a := &rec.Attempts[n-1]
+	now := t.now()
+	if a.End == nil {
+		a.End = &now
+	}
+	t.resultLocked(rec, a, req, now)
+	t.save(rec)
+	return *a, nil
+}
+
+// resultLocked は試技に成否を付けて点数を数え直します（呼び出し側で mu を保持）
+func (t *Tracker) resultLocked(rec *Record, a *Attempt, req ResultReq, now time.Time) {
+	delta := 0
+	if a.Succeeded() {
+		delta -= a.Points + a.Bonus
+	}
+	corrected := a.Success != nil
+	if req.Slot != "" {
+		a.Slot = req.Slot
+	}
+	if req.Workpiece != "" {
+		a.Workpiece = req.Workpiece
+	}
+	a.MarkedBy = req.MarkedBy
+	if a.MarkedBy == "" {
+		a.MarkedBy = MarkedByOperator
+	}
+	success := *req.Success
+	a.Success = &success
+	a.Points, a.Bonus, a.Zones = rec.Rules.Score(a.Cell, a.Slot, a.Workpiece)
+	if success {
+		delta += a.Points + a.Bonus
+	}
+	rec.Score += delta
+	ev := Event{
+		Time:      now,
+		Type:      EventAttemptEnd,
+		Attempt:   a.N,
+		Cell:      a.Cell,
+		Slot:      a.Slot,
+		Workpiece: a.Workpiece,
+		Success:   a.Success,
+		MarkedBy:  a.MarkedBy,
+		Points:    delta,
+		Detail:    strings.Join(a.Zones, ","),
+	}
+	if corrected {
+		ev.Detail = strings.TrimSuffix("corrected "+ev.Detail, " ")
+	}
+	rec.addEvent(ev)
+}
+
+// FollowMatch は試合の状態の変化を記録に反映します
+// idle から始まったら試合の記録を始め（まだ試技の無い試合の記録があればそれを使う）、時間切れ・reset で終える
+func (t *Tracker) FollowMatch(prev, next match.Status) {
+	if prev.State == next.State && prev.Paused == next.Paused {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	now := t.now()
+	if prev.State == match.StateIdle && next.State != match.StateIdle {
+		if rec := t.current; rec == nil || rec.Kind != KindMatch || len(rec.Attempts) > 0 {
+			t.beginLocked(KindMatch, "")
+		}
+	}
+	rec := t.current
+	if rec == nil || rec.Kind != KindMatch {
+		return
+	}
+	detail := next.State
+	if next.Paused {
+		detail += " (paused)"
+	}
+	rec.addEvent(Event{Time: now, Type: EventMatchState, Detail: detail})
+	switch next.State {
+	case match.StateFinished:
+		t.endLocked("time is up", now)
+	case match.StateIdle:
+		t.endLocked("match reset", now)
+	default:
+		t.save(rec)
+	}
+}
+
+func checkMarkedBy(by string) error {
+	if by != "" && by != MarkedByOperator && by != MarkedByDetected {
+		return fmt.Errorf("%w: marked_by must be %s or %s", ErrInvalid, MarkedByOperator, MarkedByDetected)
+	}
+	return nil
+}
+
+// save は記録を <dir>/<id>.json に書きます（一時ファイルに書いてから置き換える）
+func (t *Tracker) save(rec *Record) {
+	if t.dir == "" {
+		return
+	}
+	b, err := json.MarshalIndent(rec, "", "  ")
+	if err != nil {
+		t.notify(fmt.Errorf("encode match record: %w", err))
+		return
+	}
+	if err := os.MkdirAll(t.dir, 0o755); err != nil {
+		t.notify(fmt.Errorf("create match record dir: %w", err))
+		return
+	}
+	path := filepath.Join(t.dir, rec.ID+".json")
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, b, 0o644); err != nil {
+		t.notify(fmt.Errorf("write match record: %w", err))
+		return
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		t.notify(fmt.Errorf("rename match record: %w", err))
+	}
+}
+
+func (t *Tracker) notify(err error) {
+	if t.warn != nil {
+		t.warn(err)
+	}
+}
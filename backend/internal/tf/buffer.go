@@ -0,0 +1,285 @@
+// internal/tf/buffer.go
+package tf
+
+// tfはROSに依存しないように書く（/tf の購読は robot パッケージ側で行う）
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"catchrobo_app/internal/geom"
+)
+
+var (
+	// ErrUnknownFrame はバッファに存在しないフレームを指定した場合に返ります
+	ErrUnknownFrame = errors.New("unknown frame")
+	// ErrNotConnected は2つのフレームが同じ木に属していない場合に返ります
+	ErrNotConnected = errors.New("frames are not connected")
+	// ErrExtrapolation は保持している時刻範囲の外を参照した場合に返ります
+	ErrExtrapolation = errors.New("lookup would require extrapolation")
+	// ErrStale は最新値を求めたとき、経路の動的な変換が cacheTime より長く届いていない場合に返ります
+	ErrStale = errors.New("transform is stale")
+)
+
+// DefaultCacheTime は動的な変換を保持する時間です（tf2 と同じ10秒）
+const DefaultCacheTime = 10 * time.Second
+
+// maxDepth は木をたどる深さの上限（親子関係がループしていても止まるように）
+const maxDepth = 100
+
+// Stamped は時刻付きの親子間変換です（親フレームから見た子フレームの姿勢）
+type Stamped struct {
+	Parent    string         `json:"parent"`
+	Child     string         `json:"child"`
+	Stamp     time.Time      `json:"stamp"`
+	Transform geom.Transform `json:"transform"`
+}
+
+// FrameInfo はフレーム一覧用の情報です
+type FrameInfo struct {
+	Parent string    `json:"parent"`
+	Static bool      `json:"static"`
+	Oldest time.Time `json:"oldest"`
+	Latest time.Time `json:"latest"`
+}
+
+type sample struct {
+	stamp time.Time
+	tf    geom.Transform
+}
+
+// frame は子フレームごとの親と時系列（時刻順）です
+type frame struct {
+	parent   string
+	static   bool
+	samples  []sample
+	received time.Time // 最後に Set した時刻（壁時計。スタンプの時計とは比べない）
+}
+
+// Buffer は /tf と /tf_static から作る時刻付きの変換木です
+type Buffer struct {
+	mu        sync.RWMutex
+	frames    map[string]*frame // 子フレーム名 → 親と時系列
+	cacheTime time.Duration
+	now       func() time.Time
+}
+
+// NewBuffer は cacheTime 分の履歴を保持するバッファを作ります（0以下なら DefaultCacheTime）
+func NewBuffer(cacheTime time.Duration) *Buffer {
+	if cacheTime <= 0 {
+		cacheTime = DefaultCacheTime
+	}
+	return &Buffer{frames: map[string]*frame{}, cacheTime: cacheTime, now: time.Now}
+}
+
+// Set は変換を1つ追加します。static な変換は時刻によらず常に有効です
+func (b *Buffer) Set(st Stamped, static bool) error {
+	if st.Parent == "" || st.Child == "" {
+		return fmt.Errorf("empty frame id (parent=%q child=%q)", st.Parent, st.Child)
+	}
+	if st.Parent == st.Child {
+		return fmt.Errorf("frame %q cannot be its own parent", st.Child)
+	}
+	q, err := st.Transform.Rotation.Normalize()
+	if err != nil || !st.Transform.Translation.IsFinite() {
+		return fmt.Errorf("invalid transform %s -> %s", st.Parent, st.Child)
+	}
+	st.Transform.Rotation = q
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	f := b.frames[st.Child]
+	if f == nil || f.parent != st.Parent || f.static != static {
+		// 親が変わったら過去の履歴は意味を持たないので捨てる
+		f = &frame{parent: st.Parent, static: static}
+		b.frames[st.Child] = f
+	}
+	f.received = b.now()
+	if static {
+		f.samples = []sample{{stamp: st.Stamp, tf: st.Transform}}
+		return nil
+	}
+
+	// 時刻順に挿入（通常は末尾への追加）
+	i := sort.Search(len(f.samples), func(i int) bool { return !f.samples[i].stamp.Before(st.Stamp) })
+	if i < len(f.samples) && f.samples[i].stamp.Equal(st.Stamp) {
+		f.samples[i].tf = st.Transform
+	} else {
+		f.samples = append(f.samples, sample{})
+		copy(f.samples[i+1:], f.samples[i:])
+		f.samples[i] = sample{stamp: st.Stamp, tf: st.Transform}
+	}
+
+	// 最新から cacheTime より古いものを捨てる
+	cutoff := f.samples[len(f.samples)-1].stamp.Add(-b.cacheTime)
+	drop := 0
+	for drop < len(f.samples)-1 && f.samples[drop].stamp.Before(cutoff) {
+		drop++
+	}
+	f.samples = f.samples[drop:]
+	return nil
+}
+
+// Lookup は source フレームの点を target フレームへ写す変換を返します。
+// at がゼロ値なら経路上の動的な変換がすべて持っている最新の時刻を使います（tf2 と同じ）。
+// その場合、経路の動的な変換が cacheTime より長く届いていなければ ErrStale を返します
+func (b *Buffer) Lookup(target, source string, at time.Time) (geom.Transform, error) {
+	if target == source {
+		return geom.IdentityTransform(), nil
+	}
+
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	if !b.known(target) {
+		return geom.Transform{}, fmt.Errorf("%w: %q", ErrUnknownFrame, target)
+	}
+	if !b.known(source) {
+		return geom.Transform{}, fmt.Errorf("%w: %q", ErrUnknownFrame, source)
+	}
+	up, down, err := b.path(target, source)
+	if err != nil {
+		return geom.Transform{}, err
+	}
+	if at.IsZero() {
+		if at, err = b.latestCommon(up, down); err != nil {
+			return geom.Transform{}, err
+		}
+	}
+
+	// s: 共通祖先から見た source、t: 共通祖先から見た target
+	s, err := b.compose(up, at)
+	if err != nil {
+		return geom.Transform{}, err
+	}
+	t, err := b.compose(down, at)
+	if err != nil {
+		return geom.Transform{}, err
+	}
+	return t.Inverse().Mul(s), nil
+}
+
+// path は source と target から共通祖先までにたどる子フレームを、それぞれ根に向かう順で返します（呼び出し側で mu を保持）
+func (b *Buffer) path(target, source string) (up, down []string, err error) {
+	// source から根までの各フレームが up の何番目まででたどれるか
+	depthOf := map[string]int{}
+	cur := source
+	for depth := 0; depth < maxDepth; depth++ {
+		if _, ok := depthOf[cur]; ok {
+			break
+		}
+		depthOf[cur] = len(up)
+		f := b.frames[cur]
+		if f == nil {
+			break
+		}
+		up = append(up, cur)
+		cur = f.parent
+	}
+
+	// target 側から根へたどり、最初に出会った共通祖先で止める
+	cur = target
+	for depth := 0; depth < maxDepth; depth++ {
+		if i, ok := depthOf[cur]; ok {
+			return up[:i], down, nil
+		}
+		f := b.frames[cur]
+		if f == nil {
+			break
+		}
+		down = append(down, cur)
+		cur = f.parent
+	}
+	return nil, nil, fmt.Errorf("%w: %q and %q", ErrNotConnected, target, source)
+}
+
+// latestCommon は経路の動的な変換がすべて持っている最新の時刻を返します（動的な変換が無ければゼロ値）
+func (b *Buffer) latestCommon(paths ...[]string) (time.Time, error) {
+	var common time.Time
+	now := b.now()
+	for _, names := range paths {
+		for _, name := range names {
+			f := b.frames[name]
+			if f.static || len(f.samples) == 0 {
+				continue
+			}
+			if now.Sub(f.received) > b.cacheTime {
+				return time.Time{}, fmt.Errorf("%w: %q -> %q not received for %s",
+					ErrStale, f.parent, name, now.Sub(f.received).Round(time.Millisecond))
+			}
+			if last := f.samples[len(f.samples)-1].stamp; common.IsZero() || last.Before(common) {
+				common = last
+			}
+		}
+	}
+	return common, nil
+}
+
+// compose は names の順に変換をかけ合わせ、最後のフレームの親から見た names[0] を返します
+func (b *Buffer) compose(names []string, at time.Time) (geom.Transform, error) {
+	acc := geom.IdentityTransform()
+	for _, name := range names {
+		t, err := b.frames[name].at(name, at)
+		if err != nil {
+			return geom.Transform{}, err
+		}
+		acc = t.Mul(acc)
+	}
+	return acc, nil
+}
+
+// Frames は子フレーム名ごとの親と保持している時刻範囲を返します
+func (b *Buffer) Frames() map[string]FrameInfo {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	out := make(map[string]FrameInfo, len(b.frames))
+	for child, f := range b.frames {
+		info := FrameInfo{Parent: f.parent, Static: f.static}
+		if len(f.samples) > 0 {
+			info.Oldest = f.samples[0].stamp
+			info.Latest = f.samples[len(f.samples)-1].stamp
+		}
+		out[child] = info
+	}
+	return out
+}
+
+// known は子としても親としても登場しないフレームを弾くために使う（呼び出し側で mu を保持）
+func (b *Buffer) known(name string) bool {
+	if _, ok := b.frames[name]; ok {
+		return true
+	}
+	for _, f := range b.frames {
+		if f.parent == name {
+			return true
+		}
+	}
+	return false
+}
+
+// at は時刻 t における親から見た子の変換を補間して返します（t がゼロ値なら最新値）
+func (f *frame) at(child string, t time.Time) (geom.Transform, error) {
+	n := len(f.samples)
+	if n == 0 {
+		return geom.Transform{}, fmt.Errorf("%w: no data for %q", ErrUnknownFrame, child)
+	}
+	if f.static || t.IsZero() {
+		return f.samples[n-1].tf, nil
+	}
+	first, last := f.samples[0], f.samples[n-1]
+	if t.Before(first.stamp) || t.After(last.stamp) {
+		return geom.Transform{}, fmt.Errorf("%w: %q -> %q at %s (have %s .. %s)",
+			ErrExtrapolation, f.parent, child, t.Format(time.RFC3339Nano),
+			first.stamp.Format(time.RFC3339Nano), last.stamp.Format(time.RFC3339Nano))
+	}
+	i := sort.Search(n, func(i int) bool { return !f.samples[i].stamp.Before(t) })
+	if f.samples[i].stamp.Equal(t) || i == 0 {
+		return f.samples[i].tf, nil
+	}
+	a, b := f.samples[i-1], f.samples[i]
+	ratio := float64(t.Sub(a.stamp)) / float64(b.stamp.Sub(a.stamp))
+	return geom.Interpolate(a.tf, b.tf, ratio), nil
+}
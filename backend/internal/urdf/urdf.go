@@ -0,0 +1,247 @@
+// internal/urdf/urdf.go
+package urdf
+
+// urdfは運動学に必要な要素（link / joint / origin / axis / limit）だけを読む
+import (
+	"encoding/xml"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"catchrobo_app/internal/geom"
+)
+
+// Robot は URDF の robot 要素です
+type Robot struct {
+	Name   string  `xml:"name,attr" json:"name"`
+	Links  []Link  `xml:"link" json:"links"`
+	Joints []Joint `xml:"joint" json:"joints"`
+}
+
+// Link は URDF の link 要素です（形状や慣性は使わないので読まない）
+type Link struct {
+	Name string `xml:"name,attr" json:"name"`
+}
+
+// Joint は URDF の joint 要素です
+type Joint struct {
+	Name   string  `xml:"name,attr" json:"name"`
+	Type   string  `xml:"type,attr" json:"type"`
+	Parent Ref     `xml:"parent" json:"parent"`
+	Child  Ref     `xml:"child" json:"child"`
+	Origin *Origin `xml:"origin" json:"origin,omitempty"`
+	Axis   *Axis   `xml:"axis" json:"axis,omitempty"`
+	Limit  *Limit  `xml:"limit" json:"limit,omitempty"`
+}
+
+// Ref は parent / child 要素です
+type Ref struct {
+	Link string `xml:"link,attr" json:"link"`
+}
+
+// Origin は joint の原点（親リンクから見た位置と roll/pitch/yaw）です
+type Origin struct {
+	XYZ string `xml:"xyz,attr" json:"xyz"`
+	RPY string `xml:"rpy,attr" json:"rpy"`
+}
+
+// Axis は回転軸・直動軸です
+type Axis struct {
+	XYZ string `xml:"xyz,attr" json:"xyz"`
+}
+
+// Limit は joint の可動範囲です
+type Limit struct {
+	Lower    float64 `xml:"lower,attr" json:"lower"`
+	Upper    float64 `xml:"upper,attr" json:"upper"`
+	Effort   float64 `xml:"effort,attr" json:"effort"`
+	Velocity float64 `xml:"velocity,attr" json:"velocity"`
+}
+
+// TreeNode はリンク木の1ノードです（API でそのまま返す）
+type TreeNode struct {
+	Link     string      `json:"link"`
+	Joint    string      `json:"joint,omitempty"` // 親からこのリンクへつながる joint
+	Type     string      `json:"type,omitempty"`
+	Children []*TreeNode `json:"children,omitempty"`
+}
+
+// Parse は URDF の XML を読み込み、リンク木として矛盾が無いか（親が1つ・閉路が無い）確かめます
+func Parse(data []byte) (*Robot, error) {
+	var r Robot
+	if err := xml.Unmarshal(data, &r); err != nil {
+		return nil, fmt.Errorf("parse urdf: %w", err)
+	}
+	if len(r.Links) == 0 {
+		return nil, fmt.Errorf("parse urdf: no links")
+	}
+	links := map[string]bool{}
+	for _, l := range r.Links {
+		links[l.Name] = true
+	}
+	parentOf := map[string]string{}
+	for _, j := range r.Joints {
+		if !links[j.Parent.Link] || !links[j.Child.Link] {
+			return nil, fmt.Errorf("parse urdf: joint %q refers to unknown link", j.Name)
+		}
+		if _, dup := parentOf[j.Child.Link]; dup {
+			return nil, fmt.Errorf("parse urdf: link %q has multiple parents", j.Child.Link)
+		}
+		parentOf[j.Child.Link] = j.Parent.Link
+	}
+	root, err := r.Root()
+	if err != nil {
+		return nil, err
+	}
+	// ルートにつながらない閉路があると Path や DeepestLeaf が終わらないので、
+	// 全てのリンクが親をたどってリンクの数以内にルートへ着くことを確かめる
+	for _, l := range r.Links {
+		cur := l.Name
+		for steps := 0; cur != root; steps++ {
+			if steps > len(r.Links) {
+				return nil, fmt.Errorf("parse urdf: link %q is on a joint cycle", l.Name)
+			}
+			cur = parentOf[cur]
+		}
+	}
+	return &r, nil
+}
+
+// Root は親を持たないリンクを返します（URDF では1つだけのはず）
+func (r *Robot) Root() (string, error) {
+	hasParent := map[string]bool{}
+	for _, j := range r.Joints {
+		hasParent[j.Child.Link] = true
+	}
+	var roots []string
+	for _, l := range r.Links {
+		if !hasParent[l.Name] {
+			roots = append(roots, l.Name)
+		}
+	}
+	if len(roots) != 1 {
+		return "", fmt.Errorf("parse urdf: expected exactly one root link, got %v", roots)
+	}
+	return roots[0], nil
+}
+
+// Tree はルートからのリンク木を返します
+func (r *Robot) Tree() *TreeNode {
+	root, err := r.Root()
+	if err != nil {
+		return nil
+	}
+	children := map[string][]Joint{}
+	for _, j := range r.Joints {
+		children[j.Parent.Link] = append(children[j.Parent.Link], j)
+	}
+	var build func(link string, via *Joint) *TreeNode
+	build = func(link string, via *Joint) *TreeNode {
+		n := &TreeNode{Link: link}
+		if via != nil {
+			n.Joint, n.Type = via.Name, via.Type
+		}
+		js := children[link]
+		sort.Slice(js, func(a, b int) bool { return js[a].Name < js[b].Name })
+		for i := range js {
+			n.Children = append(n.Children, build(js[i].Child.Link, &js[i]))
+		}
+		return n
+	}
+	return build(root, nil)
+}
+
+// Path は base リンクから tip リンクまでの joint を順に返します
+func (r *Robot) Path(base, tip string) ([]Joint, error) {
+	byChild := map[string]Joint{}
+	for _, j := range r.Joints {
+		byChild[j.Child.Link] = j
+	}
+	var rev []Joint
+	cur := tip
+	for cur != base {
+		j, ok := byChild[cur]
+		if !ok {
+			return nil, fmt.Errorf("link %q is not a descendant of %q", tip, base)
+		}
+		rev = append(rev, j)
+		cur = j.Parent.Link
+	}
+	path := make([]Joint, len(rev))
+	for i, j := range rev {
+		path[len(rev)-1-i] = j
+	}
+	return path, nil
+}
+
+// DeepestLeaf は base から最も多くの可動 joint を経由する末端リンクを返します（tip 未指定時に使う）
+func (r *Robot) DeepestLeaf(base string) string {
+	children := map[string][]Joint{}
+	for _, j := range r.Joints {
+		children[j.Parent.Link] = append(children[j.Parent.Link], j)
+	}
+	best, bestDepth := base, -1
+	var walk func(link string, depth int)
+	walk = func(link string, depth int) {
+		if len(children[link]) == 0 && (depth > bestDepth || (depth == bestDepth && link < best)) {
+			best, bestDepth = link, depth
+		}
+		for _, j := range children[link] {
+			d := depth
+			if j.Type != "fixed" {
+				d++
+			}
+			walk(j.Child.Link, d)
+		}
+	}
+	walk(base, 0)
+	return best
+}
+
+// OriginTransform は joint の origin を変換として返します（省略時は恒等変換）
+func (j Joint) OriginTransform() (geom.Transform, error) {
+	if j.Origin == nil {
+		return geom.IdentityTransform(), nil
+	}
+	xyz, err := parseVec3(j.Origin.XYZ, geom.Vec3{})
+	if err != nil {
+		return geom.Transform{}, fmt.Errorf("joint %q origin xyz: %w", j.Name, err)
+	}
+	rpy, err := parseVec3(j.Origin.RPY, geom.Vec3{})
+	if err != nil {
+		return geom.Transform{}, fmt.Errorf("joint %q origin rpy: %w", j.Name, err)
+	}
+	return geom.Transform{Translation: xyz, Rotation: geom.FromRPY(rpy.X, rpy.Y, rpy.Z)}, nil
+}
+
+// AxisVec は joint の軸を返します（URDF の既定は x 軸）
+func (j Joint) AxisVec() (geom.Vec3, error) {
+	if j.Axis == nil {
+		return geom.Vec3{X: 1}, nil
+	}
+	v, err := parseVec3(j.Axis.XYZ, geom.Vec3{X: 1})
+	if err != nil {
+		return geom.Vec3{}, fmt.Errorf("joint %q axis: %w", j.Name, err)
+	}
+	return v, nil
+}
+
+func parseVec3(s string, def geom.Vec3) (geom.Vec3, error) {
+	f := strings.Fields(s)
+	if len(f) == 0 {
+		return def, nil
+	}
+	if len(f) != 3 {
+		return geom.Vec3{}, fmt.Errorf("expected 3 values, got %q", s)
+	}
+	var v [3]float64
+	for i := range f {
+		x, err := strconv.ParseFloat(f[i], 64)
+		if err != nil {
+			return geom.Vec3{}, err
+		}
+		v[i] = x
+	}
+	return geom.Vec3{X: v[0], Y: v[1], Z: v[2]}, nil
+}
@@ -5,6 +5,82 @@ import { Position, Displacement } from '../types';
 
 const API_BASE_URL = '/api';
 
+/**
+ * 操作権（POST /api/control/take）
+ * 指令系のエンドポイントは操作権のトークンが無いと 423 になるので、
+ * 指令の前に take してトークンを X-Control-Token で付ける。
+ * 自動解放（無操作）や他のタブに奪われて 423 になった場合は1度だけ取り直して送り直す。
+ */
+const CLIENT_ID_KEY = 'catchrobo.clientId';
+let controlToken: string | null = null;
+
+/** タブごとのクライアントID（X-Client-Id）。リロードしても同じタブなら同じ ID を使う */
+const clientId = (): string => {
+  let id = sessionStorage.getItem(CLIENT_ID_KEY);
+  if (!id) {
+    id = `ui-${Date.now().toString(36)}-${Math.random().toString(36).slice(2, 10)}`;
+    sessionStorage.setItem(CLIENT_ID_KEY, id);
+  }
+  return id;
+};
+
+/** エラー応答の detail（無ければ error）を取り出す */
+const errorDetail = async (response: Response): Promise<string> => {
+  const body = await response.json().catch(() => ({}));
+  return body.detail || body.error || `HTTP error! status: ${response.status}`;
+};
+
+/**
+ * 操作権を取ってトークンを覚える。他のクライアントが持っていれば（409）取れない
+ */
+export const takeControl = async (name = 'web-ui'): Promise<any> => {
+  const response = await fetch(`${API_BASE_URL}/control/take`, {
+    method: 'POST',
+    headers: { 'Content-Type': 'application/json', 'X-Client-Id': clientId() },
+    body: JSON.stringify({ name }),
+  });
+  if (!response.ok) {
+    controlToken = null;
+    throw new Error(`Failed to take control: ${await errorDetail(response)}`);
+  }
+  const data = await response.json();
+  controlToken = data.token;
+  return data;
+};
+
+/** 操作権を手放す */
+export const releaseControl = async (): Promise<any> => {
+  const response = await fetch(`${API_BASE_URL}/control/release`, {
+    method: 'POST',
+    headers: { 'X-Client-Id': clientId(), 'X-Control-Token': controlToken ?? '' },
+  });
+  controlToken = null;
+  if (!response.ok) throw new Error(`Failed to release control: ${await errorDetail(response)}`);
+  return response.json();
+};
+
+/**
+ * 操作権のトークンを付けて指令を送る。423（操作権が無い・切れた）なら取り直して1度だけ送り直す
+ */
+const commandFetch = async (path: string, init: RequestInit = {}): Promise<Response> => {
+  const send = () =>
+    fetch(`${API_BASE_URL}${path}`, {
+      ...init,
+      headers: {
+        ...(init.headers as Record<string, string>),
+        'X-Client-Id': clientId(),
+        'X-Control-Token': controlToken ?? '',
+      },
+    });
+  if (!controlToken) await takeControl();
+  let response = await send();
+  if (response.status === 423) {
+    await takeControl();
+    response = await send();
+  }
+  return response;
+};
+
 /**
  * 指定した座標をバックエンドに送信する
  * @param position 送信する座標データ
@@ -13,7 +89,7 @@ export const sendPosition = async (position: Position): Promise<any> => {
   // position.z が未指定なら 0.5 を補完
   const payload = { x: position.x, y: position.y, z: position.z ?? 0.5 };
   try {
-    const response = await fetch(`${API_BASE_URL}/position`, {
+    const response = await commandFetch('/position', {
       method: 'POST',
       headers: { 'Content-Type': 'application/json' },
       body: JSON.stringify(payload),
@@ -31,7 +107,7 @@ export const sendPosition = async (position: Position): Promise<any> => {
  */
 export const sendDisplacement = async (displacement: Displacement): Promise<any> => {
   try {
-    const response = await fetch(`${API_BASE_URL}/move`, {
+    const response = await commandFetch('/move', {
       method: 'POST',
       headers: { 'Content-Type': 'application/json' },
       body: JSON.stringify({
@@ -52,48 +128,48 @@ export const sendDisplacement = async (displacement: Displacement): Promise<any>
  * モーション各種
  */
 export const startMotion = async (): Promise<any> => {
-  const response = await fetch(`${API_BASE_URL}/start_motion`, { method: 'POST' });
+  const response = await commandFetch('/start_motion', { method: 'POST' });
   if (!response.ok) throw new Error('Failed start motion');
   return response.json();
 };
 
 export const downMotion = async (): Promise<any> => {
-  const response = await fetch(`${API_BASE_URL}/down_motion`, { method: 'POST' });
+  const response = await commandFetch('/down_motion', { method: 'POST' });
   if (!response.ok) throw new Error('Failed down motion');
   return response.json();
 };
 
 export const upMotion = async (): Promise<any> => {
-  const response = await fetch(`${API_BASE_URL}/up_motion`, { method: 'POST' });
+  const response = await commandFetch('/up_motion', { method: 'POST' });
   if (!response.ok) throw new Error('Failed up motion');
   return response.json();
 };
 
 export const catchMotion = async (): Promise<any> => {
-  const response = await fetch(`${API_BASE_URL}/catch_motion`, { method: 'POST' });
+  const response = await commandFetch('/catch_motion', { method: 'POST' });
   if (!response.ok) throw new Error('Failed catch motion');
   return response.json();
 };
 export const releaseMotion = async (): Promise<any> => {
-  const response = await fetch(`${API_BASE_URL}/release_motion`, { method: 'POST' });
+  const response = await commandFetch('/release_motion', { method: 'POST' });
   if (!response.ok) throw new Error('Failed release motion');
   return response.json();
 }
 
 export const addDownMotion = async (): Promise<any> => {
-  const response = await fetch(`${API_BASE_URL}/add_down_motion`, { method: 'POST' });
+  const response = await commandFetch('/add_down_motion', { method: 'POST' });
   if (!response.ok) throw new Error('Failed add down motion');
   return response.json();
 }
 
 export const addUpMotion = async (): Promise<any> => {
-  const response = await fetch(`${API_BASE_URL}/add_up_motion`, { method: 'POST' });
+  const response = await commandFetch('/add_up_motion', { method: 'POST' });
   if (!response.ok) throw new Error('Failed add up motion');
   return response.json();
 }
 
 export const middleMotion = async (): Promise<any> => {
-  const response = await fetch(`${API_BASE_URL}/middle_motion`, { method: 'POST' });
+  const response = await commandFetch('/middle_motion', { method: 'POST' });
   if (!response.ok) throw new Error('Failed middle down motion');
   return response.json();
 }
@@ -101,7 +177,7 @@ export const middleMotion = async (): Promise<any> => {
 
 
 export const resetMotion = async (): Promise<any> => {
-  const response = await fetch(`${API_BASE_URL}/reset_motion`, { method: 'POST' });
+  const response = await commandFetch('/reset_motion', { method: 'POST' });
   if (!response.ok) throw new Error('Failed reset motion');
   return response.json();
 };
@@ -110,7 +186,7 @@ export const resetMotion = async (): Promise<any> => {
  * ジョイント角度をバックエンドに送信する
  */
 export const sendJointAngles = async (angles: number[]): Promise<any> => {
-  const response = await fetch(`${API_BASE_URL}/joint_angles`, {
+  const response = await commandFetch('/joint_angles', {
     method: 'POST',
     headers: { 'Content-Type': 'application/json' },
     body: JSON.stringify({ angles }),